@@ -0,0 +1,40 @@
+// Command hello greets from the command line with the testgopkg library.
+//
+//	hello [-name name] [-locale locale] [-log-format text|json|none]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	testgopkg "github.com/raducristianpopa/test-go-pkg/v4"
+)
+
+func main() {
+	name := flag.String("name", "", "Who to greet (default: \"World\" in the locale)")
+	locale := flag.String("locale", testgopkg.DefaultLocale, "Locale of the greeting: "+strings.Join(testgopkg.Locales(), ", "))
+	logFormat := flag.String("log-format", "text", "Format of the log on stderr: text, json, or none")
+	flag.Parse()
+	if flag.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "hello: unexpected arguments: %s\n", strings.Join(flag.Args(), " "))
+		os.Exit(2)
+	}
+
+	opts := []testgopkg.Option{testgopkg.WithName(*name), testgopkg.WithLocale(*locale)}
+	switch *logFormat {
+	case "text", "json":
+		opts = append(opts, testgopkg.WithLogFormat(testgopkg.LogFormat(*logFormat)))
+	case "none":
+		opts = append(opts, testgopkg.WithoutLogging())
+	default:
+		fmt.Fprintf(os.Stderr, "hello: invalid -log-format %q, must be text, json, or none\n", *logFormat)
+		os.Exit(2)
+	}
+
+	if err := testgopkg.New(opts...).Greet(); err != nil {
+		fmt.Fprintf(os.Stderr, "hello: %v\n", err)
+		os.Exit(1)
+	}
+}
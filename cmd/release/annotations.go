@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// inGroup is whether a GitHub Actions log group is open.
+var inGroup bool
+
+// annotate reports whether the text log format is written as GitHub
+// Actions workflow commands: errors and warnings as annotations, which
+// the Actions UI shows inline, and steps as collapsible groups.
+func annotate() bool {
+	return logFormat == "text" && os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// startGroup opens a log group titled title in GitHub Actions, closing the
+// open one. Outside Actions it does nothing.
+func startGroup(title string) {
+	if !annotate() {
+		return
+	}
+	endGroup()
+	writeWorkflowCommand("group", title)
+	inGroup = true
+}
+
+// endGroup closes the open log group, if any.
+func endGroup() {
+	if !inGroup {
+		return
+	}
+	writeWorkflowCommand("endgroup", "")
+	inGroup = false
+}
+
+func writeWorkflowCommand(name, data string) {
+	activeLine.Lock()
+	defer activeLine.Unlock()
+	clearActiveLine()
+	fmt.Fprintf(messages, "::%s::%s\n", name, escapeWorkflowData(data))
+}
+
+// escapeWorkflowData escapes the data of a workflow command, which ends at
+// a newline.
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
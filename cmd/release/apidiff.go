@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// apidiffInstall is how to install apidiff, which -semver-gate runs.
+const apidiffInstall = "go install golang.org/x/exp/cmd/apidiff@latest"
+
+// incompatibleChanges lists the changes of the module's API since
+// previousTag that break its users, as reported by apidiff: the API at
+// previousTag, checked out in a worktree, is compared with the one of the
+// working tree.
+func incompatibleChanges(previousTag string) ([]string, error) {
+	if _, err := exec.LookPath("apidiff"); err != nil {
+		return nil, fmt.Errorf("apidiff is not installed; install it with '%s'", apidiffInstall)
+	}
+	out, err := newCommand("go", "list", "-m").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the module path: %w", err)
+	}
+	module := strings.TrimSpace(string(out))
+
+	dir, err := os.MkdirTemp("", "release-apidiff-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	exportData := filepath.Join(dir, "v"+versionNumber(previousTag)+".export")
+
+	err = withModuleWorktree(previousTag, func(worktree string) error {
+		out, err := commandIn(worktree, "apidiff", "-m", "-w", exportData, module).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to read the API of %s: %v: %s", previousTag, err, out)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err = newCommand("apidiff", "-m", "-incompatible", exportData, module).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare the API with %s: %v: %s", previousTag, err, out)
+	}
+	var changes []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if change, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// semverGate verifies that a bump release after previous is allowed by
+// Semantic Versioning: one with incompatible API changes must be a major
+// release. Until v1, as in Go's module conventions, minor releases may
+// break the API as well. There is nothing to compare the first release to.
+func semverGate(bump BumpType, previous version) error {
+	if bump == major || previous == (version{}) {
+		return nil
+	}
+	if previous.Major == 0 && bump == minor {
+		return nil
+	}
+	previousTag := previous.String()
+	changes, err := incompatibleChanges(previousTag)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		success("The API changes are compatible", "since", previousTag, "type", bump)
+		return nil
+	}
+	for _, c := range changes {
+		slog.Error("Incompatible API change", "change", c)
+	}
+	return fmt.Errorf("%w since %s (%d) require a major release, not a %s release", ErrSemverViolation, previousTag, len(changes), bump)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Modes of -approval, the gate waiting for a person to approve a release
+// after its plan is shown and before any change.
+const (
+	// approvalPrompt asks on the terminal for the tag to be typed.
+	approvalPrompt = "prompt"
+	// approvalFile waits for the file after the prefix to exist, e.g.
+	// created by an approver on a shared runner.
+	approvalFile = "file:"
+	// approvalDeployment creates a GitHub deployment of the released commit
+	// to the environment after the prefix, and waits for its status to be
+	// set to success (approved) or failure (rejected).
+	approvalDeployment = "github-deployment:"
+)
+
+// approvalPollInterval is how often a pending approval is checked.
+const approvalPollInterval = 10 * time.Second
+
+// deploymentApprover is implemented by forges whose deployments can gate
+// a release.
+type deploymentApprover interface {
+	forge
+	// createDeployment creates a deployment of commit to environment,
+	// returning its ID.
+	createDeployment(commit, environment, description string) (int64, error)
+	// deploymentState returns the state of the latest status of deployment
+	// id, or "" if it has none.
+	deploymentState(id int64) (string, error)
+}
+
+// validApproval reports whether mode is an -approval mode.
+func validApproval(mode string) bool {
+	switch {
+	case mode == approvalPrompt:
+		return true
+	case strings.HasPrefix(mode, approvalFile):
+		return len(mode) > len(approvalFile)
+	case strings.HasPrefix(mode, approvalDeployment):
+		return len(mode) > len(approvalDeployment)
+	}
+	return false
+}
+
+// waitForApproval waits up to timeout for the release of tag at commit to
+// be approved in mode.
+func waitForApproval(mode string, fc forgeConfig, commit, tag string, timeout time.Duration) error {
+	switch {
+	case mode == approvalPrompt:
+		return promptApproval(tag)
+	case strings.HasPrefix(mode, approvalFile):
+		return waitForApprovalFile(strings.TrimPrefix(mode, approvalFile), timeout)
+	default:
+		return waitForDeployment(fc, strings.TrimPrefix(mode, approvalDeployment), commit, tag, timeout)
+	}
+}
+
+// promptApproval asks for tag to be typed, so that the approval is not a
+// reflex like the confirmation, which -yes skips.
+func promptApproval(tag string) error {
+	if !isTerminal(os.Stdin) {
+		return errors.New("approval prompt requires a terminal; use another -approval mode or -approved-by")
+	}
+	fmt.Fprintf(messages, "Approval required. Type %s to approve the release: ", tag)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != tag {
+		return errors.New("rejected at the prompt")
+	}
+	return nil
+}
+
+// waitForApprovalFile waits for path to exist. Its content, if any, names
+// the approver.
+func waitForApprovalFile(path string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	p := startProgress("Waiting for approval", "file", path)
+	defer p.stop()
+	for {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			slog.Info("Release approved", "file", path, "approver", strings.TrimSpace(string(data)))
+			return nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if !time.Now().Add(approvalPollInterval).Before(deadline) {
+			return fmt.Errorf("no approval file %s after %s", path, timeout)
+		}
+		if err := sleep(approvalPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// waitForDeployment creates a deployment of commit to environment and waits
+// for it to be marked successful.
+func waitForDeployment(fc forgeConfig, environment, commit, tag string, timeout time.Duration) error {
+	f, err := newForge(fc)
+	if err != nil {
+		return err
+	}
+	d, ok := f.(deploymentApprover)
+	if !ok {
+		return fmt.Errorf("deployment approvals are not supported on %s", f.name())
+	}
+
+	id, err := d.createDeployment(commit, environment, "Approve the release of "+tag)
+	if err != nil {
+		return fmt.Errorf("failed to create the deployment: %w", err)
+	}
+	slog.Info("Created a deployment to approve; set its status to success to approve, or failure to reject", "environment", environment, "deployment", id)
+
+	deadline := time.Now().Add(timeout)
+	p := startProgress("Waiting for approval", "environment", environment)
+	defer p.stop()
+	for {
+		state, err := d.deploymentState(id)
+		if err != nil {
+			return err
+		}
+		switch state {
+		case "success":
+			slog.Info("Release approved", "environment", environment, "deployment", id)
+			return nil
+		case "failure", "error", "inactive":
+			return fmt.Errorf("deployment %d to %s was marked %s", id, environment, state)
+		}
+		if !time.Now().Add(approvalPollInterval).Before(deadline) {
+			return fmt.Errorf("deployment %d to %s not approved after %s", id, environment, timeout)
+		}
+		if err := sleep(approvalPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *githubClient) createDeployment(commit, environment, description string) (int64, error) {
+	body := map[string]any{
+		"ref":         commit,
+		"environment": environment,
+		"description": description,
+		"auto_merge":  false,
+		// The release checks CI itself, with -check-gate.
+		"required_contexts": []string{},
+	}
+	var out struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.do(http.MethodPost, "/repos/"+c.repo+"/deployments", body, &out); err != nil {
+		return 0, err
+	}
+	return out.ID, nil
+}
+
+func (c *githubClient) deploymentState(id int64) (string, error) {
+	var out []struct {
+		State string `json:"state"`
+	}
+	path := fmt.Sprintf("/repos/%s/deployments/%d/statuses?per_page=1", c.repo, id)
+	if err := c.do(http.MethodGet, path, nil, &out); err != nil {
+		return "", err
+	}
+	if len(out) == 0 {
+		return "", nil
+	}
+	return out[0].State, nil
+}
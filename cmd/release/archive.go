@@ -0,0 +1,181 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+// defaultArchiveName is the default template for archive names, without
+// the extension.
+const defaultArchiveName = "{{.Binary}}_{{.Version}}_{{.OS}}_{{.Arch}}"
+
+// archiveNameData is the data available to the archive name template.
+type archiveNameData struct {
+	Binary  string
+	Tag     string
+	Version string // Tag without the "v" prefix
+	OS      string
+	Arch    string
+}
+
+// archiveBinaries packages each binary, together with the LICENSE and
+// README files from the root of dir, into a .tar.gz archive (.zip for
+// Windows) named after tmpl. The archives replace the binaries as release
+// artifacts.
+func archiveBinaries(dir string, binaries []artifact, tag, tmpl, dist string) ([]artifact, error) {
+	t, err := template.New("archive").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive name template: %w", err)
+	}
+
+	extras, err := archiveExtras(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []artifact
+	for _, bin := range binaries {
+		var name bytes.Buffer
+		data := archiveNameData{
+			Binary:  bin.Binary,
+			Tag:     tag,
+			Version: versionNumber(tag),
+			OS:      bin.OS,
+			Arch:    bin.Arch,
+		}
+		if err := t.Execute(&name, data); err != nil {
+			return nil, fmt.Errorf("rendering archive name: %w", err)
+		}
+
+		files := append([]string{bin.Path}, extras...)
+		a := artifact{Binary: bin.Binary, OS: bin.OS, Arch: bin.Arch}
+		if bin.OS == "windows" {
+			a.Name = name.String() + ".zip"
+			a.Path = filepath.Join(dist, a.Name)
+			err = writeZip(a.Path, files)
+		} else {
+			a.Name = name.String() + ".tar.gz"
+			a.Path = filepath.Join(dist, a.Name)
+			err = writeTarGz(a.Path, files)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", a.Name, err)
+		}
+
+		slog.Info("Packaged archive", "path", a.Path)
+		archives = append(archives, a)
+	}
+
+	return archives, nil
+}
+
+// archiveExtras returns the license and readme files bundled with every
+// archive.
+func archiveExtras(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"LICENSE*", "README*"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func writeTarGz(out string, files []string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	for i, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		// Set the modes rather than keep the file's, which on Windows hosts
+		// lack the executable bit. The binary comes first.
+		hdr.Mode = 0644
+		if i == 0 {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if err := copyFile(tw, path); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func writeZip(out string, files []string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Method = zip.Deflate
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(w, path); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultPlatforms are the GOOS/GOARCH pairs binaries are built for unless
+// -platforms says otherwise.
+const defaultPlatforms = "linux/amd64,linux/arm64,darwin/amd64,darwin/arm64,windows/amd64"
+
+// artifact is a file produced for a release.
+type artifact struct {
+	Path string
+	// Name is the unique file name the artifact is published under.
+	Name   string
+	Binary string
+	OS     string
+	Arch   string
+}
+
+// checksumsFile is the name of the checksums artifact.
+const checksumsFile = "SHA256SUMS"
+
+// artifactOptions configures which release artifacts are produced.
+type artifactOptions struct {
+	// Build cross-compiles the cmd/ binaries for Platforms.
+	Build     bool
+	Platforms []string
+	Dist      string
+	// VerifyReproducible rebuilds the binaries and fails the release if
+	// they differ.
+	VerifyReproducible bool
+	// Parallel limits the binaries built at once; zero builds one per
+	// CPU.
+	Parallel int
+	// ArchiveName, if set, packages the binaries into archives named
+	// after this template instead of publishing them bare.
+	ArchiveName string
+	// SBOM selects the SBOM format, sbomSPDX or sbomCycloneDX; empty
+	// produces none.
+	SBOM       string
+	Provenance bool
+	Cosign     bool
+	// CosignKey is the key for Cosign; empty signs keyless.
+	CosignKey string
+	// GPGKey, if set, adds detached GPG signatures made with this key.
+	GPGKey string
+	// Params are recorded as the build's external parameters in the
+	// provenance.
+	Params map[string]any
+}
+
+func (o artifactOptions) enabled() bool {
+	return o.Build || o.SBOM != ""
+}
+
+// produceArtifacts builds the release artifacts for tag from a clean
+// checkout of the tagged commit, then adds the provenance, checksums, and
+// signatures covering them.
+func produceArtifacts(tag string, o artifactOptions) ([]artifact, error) {
+	started := time.Now()
+
+	dist, err := filepath.Abs(o.Dist)
+	if err != nil {
+		return nil, err
+	}
+
+	var artifacts []artifact
+	err = withWorktree(tag, func(dir string) error {
+		if o.Build {
+			binaries, err := buildBinaries(dir, tag, o.Platforms, dist, o.Parallel, o.VerifyReproducible)
+			if err != nil {
+				return err
+			}
+			if o.ArchiveName != "" && len(binaries) > 0 {
+				binaries, err = archiveBinaries(dir, binaries, tag, o.ArchiveName, dist)
+				if err != nil {
+					return err
+				}
+			}
+			artifacts = append(artifacts, binaries...)
+		}
+
+		if o.SBOM != "" {
+			sbom, err := writeSBOM(dir, tag, o.SBOM, dist)
+			if err != nil {
+				return err
+			}
+			artifacts = append(artifacts, sbom)
+		}
+		return nil
+	})
+	if err != nil || len(artifacts) == 0 {
+		return nil, err
+	}
+
+	if o.Provenance {
+		prov, err := writeProvenance(artifacts, tag, o.Params, started, dist)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, prov)
+	}
+
+	sums, err := writeChecksums(artifacts, dist)
+	if err != nil {
+		return nil, err
+	}
+	artifacts = append(artifacts, sums)
+
+	signable := artifacts
+
+	if o.Cosign {
+		sigs, err := cosignArtifacts(signable, o.CosignKey)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, sigs...)
+	}
+
+	if o.GPGKey != "" {
+		sigs, err := gpgSignArtifacts(signable, o.GPGKey)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, sigs...)
+	}
+
+	return artifacts, nil
+}
+
+// describeArtifacts prints what produceArtifacts would do at tag, for dry
+// runs, with the build commands of the main packages at HEAD.
+func describeArtifacts(o artifactOptions, tag string) {
+	if o.Build {
+		slog.Info("DRY RUN MODE - Would build cmd/ binaries", "platforms", strings.Join(o.Platforms, ","), "dist", o.Dist)
+		pkgs, err := mainPackages(".")
+		if err != nil {
+			slog.Warn("DRY RUN MODE - Cannot list the binaries", "err", err)
+		}
+		for _, pkg := range pkgs {
+			for _, platform := range o.Platforms {
+				goos, goarch, _ := strings.Cut(platform, "/")
+				dirName, name := binaryName(path.Base(pkg), goos, goarch)
+				showCommand(append(goBuildEnv(goos, goarch), goBuildCommand(pkg, tag, filepath.Join(o.Dist, dirName, name))...)...)
+			}
+		}
+		if o.VerifyReproducible {
+			slog.Info("DRY RUN MODE - Would rebuild the binaries to verify they are reproducible")
+		}
+		if o.ArchiveName != "" {
+			slog.Info("DRY RUN MODE - Would package the binaries as archives", "name", o.ArchiveName)
+		}
+	}
+	if o.SBOM != "" {
+		slog.Info("DRY RUN MODE - Would write an SBOM", "format", o.SBOM)
+	}
+	if o.Provenance {
+		slog.Info("DRY RUN MODE - Would write provenance for the artifacts", "file", provenanceFile)
+	}
+	slog.Info("DRY RUN MODE - Would write checksums of the artifacts", "file", checksumsFile)
+	if o.Cosign {
+		slog.Info("DRY RUN MODE - Would sign the artifacts with cosign")
+	}
+	if o.GPGKey != "" {
+		slog.Info("DRY RUN MODE - Would sign the artifacts with GPG", "key", o.GPGKey)
+	}
+}
+
+// withWorktree checks out tag into a temporary worktree and calls fn with
+// its directory, so artifacts are built from exactly the tagged sources.
+func withWorktree(tag string, fn func(dir string) error) error {
+	dir, err := os.MkdirTemp("", "release-build-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := newCommand("git", "worktree", "add", "--detach", dir, tag)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to check out %s: %v: %s", tag, err, out)
+	}
+	// The worktree is removed even if the run timed out.
+	defer func() { traceCommand(exec.Command("git", "worktree", "remove", "--force", dir)).Run() }()
+
+	return fn(dir)
+}
+
+// withModuleWorktree is like withWorktree but calls fn with the directory of
+// the current module within the worktree, which differs from its root for
+// a module in a subdirectory of the repository.
+func withModuleWorktree(tag string, fn func(dir string) error) error {
+	prefix, err := newCommand("git", "rev-parse", "--show-prefix").Output()
+	if err != nil {
+		return fmt.Errorf("failed to find the module directory: %w", err)
+	}
+	return withWorktree(tag, func(dir string) error {
+		return fn(filepath.Join(dir, strings.TrimSpace(string(prefix))))
+	})
+}
+
+// binaryName returns the directory and file name of binary built for
+// goos/goarch.
+func binaryName(binary, goos, goarch string) (dirName, name string) {
+	name = binary
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return fmt.Sprintf("%s_%s_%s", binary, goos, goarch), name
+}
+
+// buildBinaries cross-compiles every main package under a cmd/ directory of
+// the module in dir for each of platforms, placing the binaries in dist. The
+// tag is injected into main.toolVersion, the variable the release tool
+// reports with -version; binaries without it ignore it. Up to parallel
+// binaries are built at once. Modules without cmd/ packages produce no
+// binaries. With verify set, the binaries are checked to be reproducible.
+func buildBinaries(dir, tag string, platforms []string, dist string, parallel int, verify bool) ([]artifact, error) {
+	pkgs, err := mainPackages(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		slog.Info("No cmd/ main packages found, skipping binary artifacts")
+		return nil, nil
+	}
+
+	byBinary := make(map[string]string, len(pkgs))
+
+	var artifacts []artifact
+	for _, pkg := range pkgs {
+		binary := path.Base(pkg)
+		byBinary[binary] = pkg
+		for _, platform := range platforms {
+			goos, goarch, ok := strings.Cut(platform, "/")
+			if !ok {
+				return nil, fmt.Errorf("invalid platform %q, expected GOOS/GOARCH", platform)
+			}
+
+			dirName, name := binaryName(binary, goos, goarch)
+			artifacts = append(artifacts, artifact{
+				Path:   filepath.Join(dist, dirName, name),
+				Name:   dirName + strings.TrimPrefix(name, binary),
+				Binary: binary,
+				OS:     goos,
+				Arch:   goarch,
+			})
+		}
+	}
+
+	// The builds are independent, so they run concurrently; the artifacts
+	// keep their order regardless of which build finishes first.
+	p := startProgress(fmt.Sprintf("Building %d binaries", len(artifacts)))
+	err = runParallel(len(artifacts), parallel, func(i int) error {
+		bin := artifacts[i]
+		if err := goBuild(dir, byBinary[bin.Binary], tag, bin.OS, bin.Arch, bin.Path, ""); err != nil {
+			return err
+		}
+		slog.Info("Built binary", "path", bin.Path)
+		return nil
+	})
+	p.stop()
+	if err != nil {
+		return nil, err
+	}
+
+	if verify {
+		if err := verifyReproducible(dir, tag, artifacts, byBinary, parallel); err != nil {
+			return nil, err
+		}
+	}
+
+	return artifacts, nil
+}
+
+// goBuildCommand returns the command building pkg at tag into out.
+func goBuildCommand(pkg, tag, out string) []string {
+	return []string{"go", "build", "-trimpath", "-ldflags", "-s -w -buildid= -X main.toolVersion=" + tag, "-o", out, pkg}
+}
+
+// goBuildEnv returns the environment of goBuildCommand for goos/goarch.
+func goBuildEnv(goos, goarch string) []string {
+	return []string{"GOOS=" + goos, "GOARCH=" + goarch, "CGO_ENABLED=0", "GOFLAGS="}
+}
+
+// goBuild builds pkg of the module in dir for goos/goarch into out, with
+// the version injected into main.toolVersion. The flags and environment are
+// pinned so builds of the same sources are byte-identical. A non-empty
+// cache selects a separate build cache.
+func goBuild(dir, pkg, tag, goos, goarch, out, cache string) error {
+	args := goBuildCommand(pkg, tag, out)
+	cmd := commandIn(dir, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), goBuildEnv(goos, goarch)...)
+	if cache != "" {
+		cmd.Env = append(cmd.Env, "GOCACHE="+cache)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to build %s for %s/%s: %v: %s", pkg, goos, goarch, err, output)
+	}
+	return nil
+}
+
+// verifyReproducible rebuilds each binary from scratch, with an empty build
+// cache, and fails unless the result is byte-identical to the original. Up
+// to parallel binaries are rebuilt at once.
+func verifyReproducible(dir, tag string, binaries []artifact, pkgs map[string]string, parallel int) error {
+	tmp, err := os.MkdirTemp("", "release-verify-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	matched := make([]bool, len(binaries))
+	err = runParallel(len(binaries), parallel, func(i int) error {
+		bin := binaries[i]
+		out := filepath.Join(tmp, fmt.Sprintf("%d", i), filepath.Base(bin.Path))
+		if err := goBuild(dir, pkgs[bin.Binary], tag, bin.OS, bin.Arch, out, filepath.Join(tmp, "cache")); err != nil {
+			return err
+		}
+
+		want, err := sha256File(bin.Path)
+		if err != nil {
+			return err
+		}
+		got, err := sha256File(out)
+		if err != nil {
+			return err
+		}
+		matched[i] = got == want
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var mismatched []string
+	for i, bin := range binaries {
+		if !matched[i] {
+			mismatched = append(mismatched, bin.Name)
+		}
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("builds are not reproducible: %s", strings.Join(mismatched, ", "))
+	}
+
+	success("Verified binaries are reproducible", "count", len(binaries))
+	return nil
+}
+
+// mainPackages lists the import paths of the main packages under cmd/
+// directories of the module in dir.
+func mainPackages(dir string) ([]string, error) {
+	cmd := commandIn(dir, "go", "list", "-f", `{{if eq .Name "main"}}{{.ImportPath}}{{end}}`, "./...")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	var pkgs []string
+	for _, pkg := range strings.Fields(string(output)) {
+		if strings.Contains("/"+pkg+"/", "/cmd/") {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs, nil
+}
+
+// writeChecksums writes a SHA256SUMS file in dist covering artifacts, in the
+// format understood by "sha256sum -c", and returns it as an artifact.
+func writeChecksums(artifacts []artifact, dist string) (artifact, error) {
+	var b strings.Builder
+	for _, a := range artifacts {
+		sum, err := sha256File(a.Path)
+		if err != nil {
+			return artifact{}, err
+		}
+		fmt.Fprintf(&b, "%s  %s\n", sum, a.Name)
+	}
+
+	out := filepath.Join(dist, checksumsFile)
+	if err := os.WriteFile(out, []byte(b.String()), 0644); err != nil {
+		return artifact{}, err
+	}
+
+	slog.Info("Wrote checksums", "path", out)
+	return artifact{Path: out, Name: checksumsFile}, nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// auditRef holds the audit log: a git note on each released commit with
+// one JSON record per line for every run that released it or tried to.
+// Notes are only appended to, and are pushed with the release.
+const auditRef = "refs/notes/release-audit"
+
+// auditRecord is the record of a release run in the audit log.
+type auditRecord struct {
+	Time            time.Time    `json:"time"`
+	Actor           string       `json:"actor"`
+	PreviousVersion string       `json:"previous_version"`
+	NewVersion      string       `json:"new_version"`
+	Tag             string       `json:"tag"`
+	Commit          string       `json:"commit"`
+	Released        bool         `json:"released"`
+	Steps           []string     `json:"steps"`
+	Gates           []gateResult `json:"gates,omitempty"`
+	Errors          []string     `json:"errors,omitempty"`
+}
+
+// gateResult is the outcome of a gate of a release, such as check-gate.
+type gateResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+	// Justification is why the release proceeded despite the failed gate,
+	// e.g. with -allow-semver-violation.
+	Justification string `json:"justification,omitempty"`
+}
+
+// auditActor returns who runs the release: the CI user that triggered the
+// job, or the git user.
+func auditActor() string {
+	for _, env := range []string{"GITHUB_ACTOR", "GITLAB_USER_LOGIN", "BITBUCKET_STEP_TRIGGERER_UUID"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	name, _ := newCommand("git", "config", "user.name").Output()
+	email, _ := newCommand("git", "config", "user.email").Output()
+	actor := strings.TrimSpace(string(name))
+	if e := strings.TrimSpace(string(email)); e != "" {
+		actor = strings.TrimSpace(actor + " <" + e + ">")
+	}
+	return actor
+}
+
+// recordAudit appends the record of the run to the audit log, pushing it
+// unless the run does not push.
+func (r *runResult) recordAudit() error {
+	commit := r.Commit
+	if commit == "" {
+		var err error
+		if commit, err = resolveCommit("HEAD"); err != nil {
+			return err
+		}
+	}
+	rec := auditRecord{
+		Time:            time.Now().UTC(),
+		Actor:           auditActor(),
+		PreviousVersion: r.PreviousVersion,
+		NewVersion:      r.NewVersion,
+		Tag:             r.Tag,
+		Commit:          commit,
+		Released:        r.Released,
+		Steps:           r.Steps,
+		Gates:           r.Gates,
+		Errors:          r.Errors,
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	if r.auditPush {
+		// Merge the records of other clones first, so that the push is a
+		// fast-forward.
+		if err := fetchAudit(); err != nil {
+			return err
+		}
+	}
+	if err := newCommand("git", "notes", "--ref="+auditRef, "append", "-m", redact(string(line)), commit).Run(); err != nil {
+		return fmt.Errorf("failed to append to the audit log: %w", err)
+	}
+	slog.Debug("Recorded the run in the audit log", "ref", auditRef, "commit", commit)
+	if !r.auditPush {
+		return nil
+	}
+	if err := newCommand("git", "push", "--quiet", remoteName, auditRef).Run(); err != nil {
+		return fmt.Errorf("failed to push the audit log: %w", err)
+	}
+	return nil
+}
+
+// fetchAudit merges the audit log of the remote into the local one. The
+// records of both are kept, as notes of the same commit are concatenated.
+func fetchAudit() error {
+	output, err := newCommand("git", "ls-remote", remoteName, auditRef).Output()
+	if err != nil {
+		return fmt.Errorf("failed to look up the remote audit log: %w", err)
+	}
+	if strings.TrimSpace(string(output)) == "" {
+		return nil
+	}
+
+	tracking := "refs/notes/remotes/" + remoteName + "/release-audit"
+	if err := newCommand("git", "fetch", "--quiet", "--no-tags", remoteName, "+"+auditRef+":"+tracking).Run(); err != nil {
+		return fmt.Errorf("failed to fetch the audit log: %w", err)
+	}
+	if newCommand("git", "rev-parse", "-q", "--verify", auditRef).Run() != nil {
+		err = newCommand("git", "update-ref", auditRef, tracking).Run()
+	} else {
+		err = newCommand("git", "notes", "--ref="+auditRef, "merge", "--quiet", "--strategy=cat_sort_uniq", tracking).Run()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to merge the remote audit log: %w", err)
+	}
+	return nil
+}
+
+// readAudit returns the records of the audit log, oldest first.
+func readAudit() ([]auditRecord, error) {
+	if newCommand("git", "rev-parse", "-q", "--verify", auditRef).Run() != nil {
+		return nil, nil
+	}
+	output, err := newCommand("git", "notes", "--ref="+auditRef, "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the audit log: %w", err)
+	}
+
+	var records []auditRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		note, _, _ := strings.Cut(line, " ")
+		if note == "" {
+			continue
+		}
+		content, err := newCommand("git", "cat-file", "blob", note).Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit note %s: %w", note, err)
+		}
+		for _, l := range strings.Split(string(content), "\n") {
+			if strings.TrimSpace(l) == "" {
+				continue
+			}
+			var rec auditRecord
+			if err := json.Unmarshal([]byte(l), &rec); err != nil {
+				slog.Warn("Skipping invalid audit record", "note", note, "err", err)
+				continue
+			}
+			records = append(records, rec)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+// runAudit prints the audit log, fetched from the remote first.
+func runAudit(fs *flag.FlagSet) func() {
+	var (
+		tag    = fs.String("tag", "", "Only show the runs releasing this tag")
+		since  = fs.Duration("since", 0, "Only show the runs of this recent period, e.g. 720h")
+		failed = fs.Bool("failed", false, "Only show the runs that failed")
+		local  = fs.Bool("local", false, "Do not fetch the audit log of the remote first")
+		of     = fs.String("output", outputText, "Output format: text, or json for one record per line")
+	)
+
+	return func() {
+		if *of != outputText && *of != outputJSON {
+			slog.Error("Invalid output format, must be 'text' or 'json'", "output", *of)
+			os.Exit(exitUsage)
+		}
+		if !*local {
+			if err := fetchAudit(); err != nil {
+				slog.Warn("Using the local audit log", "err", err)
+			}
+		}
+
+		records, err := readAudit()
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitGit)
+		}
+
+		var shown []auditRecord
+		for _, rec := range records {
+			switch {
+			case *tag != "" && rec.Tag != *tag:
+			case *since > 0 && time.Since(rec.Time) > *since:
+			case *failed && len(rec.Errors) == 0:
+			default:
+				shown = append(shown, rec)
+			}
+		}
+
+		if *of == outputJSON {
+			enc := json.NewEncoder(os.Stdout)
+			for _, rec := range shown {
+				_ = enc.Encode(rec)
+			}
+			return
+		}
+		if len(shown) == 0 {
+			slog.Info("No release runs recorded", "ref", auditRef)
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tACTOR\tTAG\tCOMMIT\tRESULT")
+		for _, rec := range shown {
+			result := "released"
+			switch {
+			case len(rec.Errors) > 0:
+				result = "failed: " + rec.Errors[0]
+			case !rec.Released:
+				result = "tagged locally"
+			}
+			for _, g := range rec.Gates {
+				if g.Justification != "" {
+					result += fmt.Sprintf(" (%s overridden: %s)", g.Name, g.Justification)
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", rec.Time.Local().Format(time.DateTime), rec.Actor, rec.Tag, rec.Commit[:min(len(rec.Commit), 12)], result)
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// resolveToken finds the forge API token, trying in order the explicit
+// -token value, each of envs, and finally the gh CLI when ghFallback is set.
+// The error lists every source that was tried. The token is redacted from
+// all output.
+func resolveToken(explicit string, envs []string, ghFallback bool) (string, error) {
+	if explicit != "" {
+		addSecret(explicit)
+		return explicit, nil
+	}
+
+	tried := []string{"-token flag"}
+
+	for _, env := range envs {
+		if token := os.Getenv(env); token != "" {
+			addSecret(token)
+			return token, nil
+		}
+		tried = append(tried, "$"+env)
+	}
+
+	if ghFallback {
+		token, err := ghAuthToken()
+		if err == nil {
+			addSecret(token)
+			return token, nil
+		}
+		tried = append(tried, fmt.Sprintf("'gh auth token' (%v)", err))
+	}
+
+	return "", fmt.Errorf("no forge API token found; tried %s", strings.Join(tried, ", "))
+}
+
+func ghAuthToken() (string, error) {
+	path, err := exec.LookPath("gh")
+	if err != nil {
+		return "", fmt.Errorf("gh CLI not installed")
+	}
+
+	output, err := newCommand(path, "auth", "token").Output()
+	if err != nil {
+		return "", fmt.Errorf("gh CLI not logged in")
+	}
+
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return "", fmt.Errorf("gh CLI returned an empty token")
+	}
+	return token, nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// benchAlpha is the significance level of the comparisons of -bench: a
+// difference with a higher p-value is noise.
+const benchAlpha = 0.05
+
+// minBenchCount is the fewest runs of each benchmark for which a
+// difference can be significant at benchAlpha.
+const minBenchCount = 5
+
+// benchOptions configures the benchmark gate of a release.
+type benchOptions struct {
+	// Pattern selects the benchmarks, as go test's -bench; empty disables
+	// the gate.
+	Pattern string
+	// Threshold is the slowdown, in percent of the time per operation,
+	// beyond which a benchmark regressed.
+	Threshold float64
+	Count     int
+	// WarnOnly reports regressions without failing the release.
+	WarnOnly bool
+}
+
+// benchSamples are the times per operation of the runs of each benchmark,
+// in nanoseconds, by package and name. Packages are import paths without
+// the module path, which changes with a major release, e.g.
+// "internal/pkg.BenchmarkGreet", or just "BenchmarkGreet" in the root
+// package of the module.
+type benchSamples map[string][]float64
+
+// benchComparison compares the runs of a benchmark at two versions.
+type benchComparison struct {
+	Name     string
+	Old, New float64 // median nanoseconds per operation
+	Delta    float64 // change of the median, in percent
+	P        float64 // p-value of the difference
+}
+
+func (c benchComparison) significant() bool {
+	return c.P < benchAlpha
+}
+
+var benchProcs = regexp.MustCompile(`-\d+$`)
+
+// runBenchmarks runs the benchmarks matching pattern of the module in dir
+// count times.
+func runBenchmarks(dir, pattern string, count int) (benchSamples, error) {
+	p := startProgress("Running the benchmarks", "dir", dir)
+	out, err := commandIn(dir, "go", "test", "-run=^$", "-bench="+pattern, "-count="+strconv.Itoa(count), "./...").CombinedOutput()
+	p.stop()
+	if err != nil {
+		return nil, fmt.Errorf("benchmarks failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	module, err := commandIn(dir, "go", "list", "-m").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get module name: %w", err)
+	}
+
+	samples := make(benchSamples)
+	var pkg string
+	for _, line := range strings.Split(string(out), "\n") {
+		if p, ok := strings.CutPrefix(line, "pkg: "); ok {
+			pkg = benchPackage(strings.TrimSpace(string(module)), strings.TrimSpace(p))
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			if fields[i+1] != "ns/op" {
+				continue
+			}
+			if ns, err := strconv.ParseFloat(fields[i], 64); err == nil {
+				name := pkg + benchProcs.ReplaceAllString(fields[0], "")
+				samples[name] = append(samples[name], ns)
+			}
+		}
+	}
+	return samples, nil
+}
+
+// benchPackage returns the prefix of the names of the benchmarks of the
+// package with import path pkg in module: its path in the module followed
+// by a dot, or nothing for the root package.
+func benchPackage(module, pkg string) string {
+	if pkg == module {
+		return ""
+	}
+	return strings.TrimPrefix(pkg, module+"/") + "."
+}
+
+// compareBenchmarks compares the benchmarks run at both versions, sorted by
+// name. Benchmarks run at one version only are logged and left out.
+func compareBenchmarks(old, new benchSamples) []benchComparison {
+	for _, name := range sortedNames(old) {
+		if _, ok := new[name]; !ok {
+			slog.Info("Benchmark not run at the new version; skipping it", "benchmark", name)
+		}
+	}
+
+	var cs []benchComparison
+	for _, name := range sortedNames(new) {
+		o, ok := old[name]
+		if !ok {
+			slog.Info("Benchmark not run at the previous version; skipping it", "benchmark", name)
+			continue
+		}
+		c := benchComparison{Name: name, Old: median(o), New: median(new[name]), P: mannWhitneyP(o, new[name])}
+		if c.Old > 0 {
+			c.Delta = (c.New - c.Old) / c.Old * 100
+		}
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+func median(xs []float64) float64 {
+	s := append([]float64(nil), xs...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// mannWhitneyP returns the two-sided p-value of the Mann-Whitney U test of
+// whether a and b come from the same distribution, as benchstat does. It
+// is exact for samples without ties.
+func mannWhitneyP(a, b []float64) float64 {
+	// U counts the pairs in which the sample of a is the greater, ties
+	// counting half.
+	var u float64
+	for _, x := range a {
+		for _, y := range b {
+			switch {
+			case x > y:
+				u++
+			case x == y:
+				u += 0.5
+			}
+		}
+	}
+	mean := float64(len(a)*len(b)) / 2
+	dev := math.Abs(u - mean)
+
+	var extreme, total float64
+	for k, n := range uDistribution(len(a), len(b)) {
+		total += n
+		if math.Abs(float64(k)-mean) >= dev {
+			extreme += n
+		}
+	}
+	return extreme / total
+}
+
+// uDistribution returns how many orderings of n1 and n2 distinct samples
+// have each value of U, indexed by U.
+func uDistribution(n1, n2 int) []float64 {
+	// d[j] is the distribution for i-1 and j samples, from which the one
+	// for i and j follows: the greatest sample is either of the first kind,
+	// adding j to U, or of the second, adding nothing.
+	d := make([][]float64, n2+1)
+	for j := range d {
+		d[j] = []float64{1}
+	}
+	for i := 1; i <= n1; i++ {
+		next := make([][]float64, n2+1)
+		next[0] = []float64{1}
+		for j := 1; j <= n2; j++ {
+			c := make([]float64, i*j+1)
+			for k, n := range d[j] {
+				c[k+j] += n
+			}
+			for k, n := range next[j-1] {
+				c[k] += n
+			}
+			next[j] = c
+		}
+		d = next
+	}
+	return d[n2]
+}
+
+// formatNsPerOp formats a time per operation in the largest unit below it.
+func formatNsPerOp(ns float64) string {
+	for _, u := range []struct {
+		unit  string
+		scale float64
+	}{{"s", 1e9}, {"ms", 1e6}, {"µs", 1e3}} {
+		if ns >= u.scale {
+			return fmt.Sprintf("%.4g%s", ns/u.scale, u.unit)
+		}
+	}
+	return fmt.Sprintf("%.4gns", ns)
+}
+
+// benchmarkNotes renders the comparisons of the benchmarks from previous
+// to next as a section of the release notes.
+func benchmarkNotes(cs []benchComparison, previous, next string, count int) string {
+	var b strings.Builder
+	b.WriteString("## Benchmarks\n\n")
+	fmt.Fprintf(&b, "Time per operation, median of %d runs; differences with p ≥ %.2f are not significant (~).\n\n", count, benchAlpha)
+	fmt.Fprintf(&b, "| Benchmark | %s | %s | Delta |\n|---|---:|---:|---:|\n", previous, next)
+	for _, c := range cs {
+		delta := "~"
+		if c.significant() {
+			delta = fmt.Sprintf("%+.2f%%", c.Delta)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s (p=%.3f) |\n", c.Name, formatNsPerOp(c.Old), formatNsPerOp(c.New), delta, c.P)
+	}
+	return b.String()
+}
+
+// benchmarkGate runs the benchmarks of o in the working tree and at
+// previous, checked out in a worktree, and fails if any is significantly
+// slower by more than o.Threshold. It returns the comparison as a section
+// of the release notes of next; the first release has none.
+func benchmarkGate(o benchOptions, previous, next version) (string, error) {
+	if previous == (version{}) {
+		slog.Info("No previous version to compare the benchmarks with")
+		return "", nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	new, err := runBenchmarks(dir, o.Pattern, o.Count)
+	if err != nil {
+		return "", err
+	}
+	if len(new) == 0 {
+		return "", fmt.Errorf("no benchmarks match %q", o.Pattern)
+	}
+	var old benchSamples
+	err = withModuleWorktree(previous.String(), func(dir string) (err error) {
+		old, err = runBenchmarks(dir, o.Pattern, o.Count)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to run the benchmarks of %s: %w", previous, err)
+	}
+
+	cs := compareBenchmarks(old, new)
+	var regressed []string
+	for _, c := range cs {
+		if c.significant() && c.Delta > o.Threshold {
+			slog.Warn("Benchmark regressed", "benchmark", c.Name, "old", formatNsPerOp(c.Old), "new", formatNsPerOp(c.New), "delta", fmt.Sprintf("%+.2f%%", c.Delta))
+			regressed = append(regressed, c.Name)
+		}
+	}
+	notes := benchmarkNotes(cs, previous.String(), next.String(), o.Count)
+	if len(regressed) > 0 {
+		return notes, fmt.Errorf("%w since %s by more than %.1f%%: %s", ErrBenchmarkRegression, previous, o.Threshold, strings.Join(regressed, ", "))
+	}
+	success("No benchmark regressed", "since", previous.String(), "benchmarks", len(cs))
+	return notes, nil
+}
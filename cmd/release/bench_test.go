@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestBenchPackage(t *testing.T) {
+	tests := []struct {
+		pkg, want string
+	}{
+		{"example.com/m/v2", ""},
+		{"example.com/m/v2/svc", "svc."},
+		{"example.com/m/v2/internal/svc", "internal/svc."},
+		{"example.com/m/v2/cmd/svc", "cmd/svc."},
+	}
+	for _, tt := range tests {
+		if got := benchPackage("example.com/m/v2", tt.pkg); got != tt.want {
+			t.Errorf("benchPackage(%q) = %q, want %q", tt.pkg, got, tt.want)
+		}
+	}
+}
+
+func TestCompareBenchmarks(t *testing.T) {
+	old := benchSamples{
+		"svc.BenchmarkGreet":          {10, 11, 10, 12, 11},
+		"internal/svc.BenchmarkGreet": {20, 21, 20, 22, 21},
+		"BenchmarkRemoved":            {5, 5, 5, 5, 5},
+	}
+	new := benchSamples{
+		"svc.BenchmarkGreet":          {10, 11, 10, 12, 11},
+		"internal/svc.BenchmarkGreet": {40, 41, 40, 42, 41},
+		"BenchmarkAdded":              {5, 5, 5, 5, 5},
+	}
+
+	cs := compareBenchmarks(old, new)
+	if len(cs) != 2 || cs[0].Name != "internal/svc.BenchmarkGreet" || cs[1].Name != "svc.BenchmarkGreet" {
+		t.Fatalf("compareBenchmarks = %+v, want the two benchmarks run at both versions", cs)
+	}
+	if !cs[0].significant() || cs[0].Delta < 90 {
+		t.Errorf("%s: delta %+.2f%%, p=%.3f, want a significant slowdown of about 95%%", cs[0].Name, cs[0].Delta, cs[0].P)
+	}
+	if cs[1].significant() {
+		t.Errorf("%s: p=%.3f, want no significant difference", cs[1].Name, cs[1].P)
+	}
+}
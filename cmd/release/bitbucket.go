@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// bitbucketClient talks to the Bitbucket Cloud REST API. Bitbucket has no
+// release objects, so a release is published as a Code Insights report
+// annotating the tagged commit.
+type bitbucketClient struct {
+	restClient
+	remote remoteInfo
+}
+
+// bitbucketNotesLimit is the maximum length of a Code Insights report's
+// details field, in characters.
+const bitbucketNotesLimit = 2000
+
+func newBitbucketClient(remote remoteInfo, opts clientOptions) *bitbucketClient {
+	apiURL := opts.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.bitbucket.org/2.0"
+	}
+
+	return &bitbucketClient{
+		restClient: newRESTClient(strings.TrimSuffix(apiURL, "/"), map[string]string{
+			"Accept":        "application/json",
+			"Authorization": "Bearer " + opts.Token,
+		}, opts.HTTP),
+		remote: remote,
+	}
+}
+
+func (c *bitbucketClient) name() string {
+	return "Bitbucket"
+}
+
+func (c *bitbucketClient) pullRequestURL(number int) string {
+	return fmt.Sprintf("%s/pull-requests/%d", c.remote.WebURL(), number)
+}
+
+func (c *bitbucketClient) issueURL(number int) string {
+	return fmt.Sprintf("%s/issues/%d", c.remote.WebURL(), number)
+}
+
+// compareURL uses Bitbucket's branch comparison view, which takes the refs
+// newest first, separated by an encoded carriage return.
+func (c *bitbucketClient) compareURL(from, to string) string {
+	return fmt.Sprintf("%s/branches/compare/%s%%0D%s", c.remote.WebURL(), url.PathEscape(to), url.PathEscape(from))
+}
+
+// createRelease attaches a "Release <tag>" report with the release notes to
+// the tagged commit.
+func (c *bitbucketClient) createRelease(r releaseRequest) (string, error) {
+	if r.DiscussionCategory != "" {
+		slog.Warn("Discussions are not supported, skipping", "forge", c.name())
+	}
+	if r.NotesMode == notesGitHub {
+		return "", fmt.Errorf("GitHub-generated release notes are not available on %s", c.name())
+	}
+
+	tag, previousTag := r.Tag, r.PreviousTag
+	if r.Draft {
+		return "", fmt.Errorf("draft releases are not supported on Bitbucket")
+	}
+
+	commit, err := tagCommit(tag)
+	if err != nil {
+		return "", err
+	}
+
+	notes, err := releaseNotes(c, r)
+	if err != nil {
+		return "", err
+	}
+	if r := []rune(notes); len(r) > bitbucketNotesLimit {
+		notes = string(r[:bitbucketNotesLimit-1]) + "…"
+	}
+
+	in := map[string]any{
+		"title":       "Release " + tag,
+		"details":     notes,
+		"report_type": "TEST",
+		"reporter":    "release",
+		"result":      "PASSED",
+	}
+	if tagExists(previousTag) {
+		in["link"] = c.compareURL(previousTag, tag)
+	}
+
+	path := fmt.Sprintf("/repositories/%s/commit/%s/reports/%s", c.remote.Repo, commit, bitbucketReportKey(tag))
+	if err := c.do(http.MethodPut, path, in, nil); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/commits/%s", c.remote.WebURL(), commit), nil
+}
+
+// bitbucketReportKey returns the key of the report of the release of tag,
+// a path segment: the "/" of a tag prefix is replaced.
+func bitbucketReportKey(tag string) string {
+	return "release-" + strings.ReplaceAll(tag, "/", "-")
+}
+
+func (c *bitbucketClient) publishRelease(string, string) (string, error) {
+	return "", fmt.Errorf("draft releases are not supported on Bitbucket")
+}
+
+func (c *bitbucketClient) checkStatuses(commit string) ([]checkStatus, error) {
+	var page struct {
+		Values []struct {
+			Key   string `json:"key"`
+			Name  string `json:"name"`
+			State string `json:"state"`
+		} `json:"values"`
+	}
+	path := fmt.Sprintf("/repositories/%s/commit/%s/statuses?pagelen=100", c.remote.Repo, commit)
+	if err := c.do(http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+
+	var statuses []checkStatus
+	for _, v := range page.Values {
+		name := v.Name
+		if name == "" {
+			name = v.Key
+		}
+		statuses = append(statuses, checkStatus{Name: name, State: normalizeStatus(v.State)})
+	}
+	return statuses, nil
+}
+
+func (c *bitbucketClient) openPullRequest(head, base, title, body string) (int, string, error) {
+	in := map[string]any{
+		"title":       title,
+		"description": body,
+		"source":      map[string]any{"branch": map[string]string{"name": head}},
+		"destination": map[string]any{"branch": map[string]string{"name": base}},
+	}
+
+	var out struct {
+		ID    int `json:"id"`
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	}
+	if err := c.do(http.MethodPost, "/repositories/"+c.remote.Repo+"/pullrequests", in, &out); err != nil {
+		return 0, "", err
+	}
+	return out.ID, out.Links.HTML.Href, nil
+}
+
+func (c *bitbucketClient) listTags() ([]string, error) {
+	var tags []string
+	path := fmt.Sprintf("/repositories/%s/refs/tags?pagelen=100", c.remote.Repo)
+	for page := 1; path != "" && page <= maxTagPages; page++ {
+		var out struct {
+			Values []struct {
+				Name string `json:"name"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		if err := c.do(http.MethodGet, path, nil, &out); err != nil {
+			return nil, err
+		}
+		for _, t := range out.Values {
+			tags = append(tags, t.Name)
+		}
+		path = strings.TrimPrefix(out.Next, c.baseURL)
+	}
+	return tags, nil
+}
+
+func (c *bitbucketClient) commentOnIssue(number int, body string) error {
+	in := map[string]any{"content": map[string]string{"raw": body}}
+	path := fmt.Sprintf("/repositories/%s/issues/%d/comments", c.remote.Repo, number)
+	return c.do(http.MethodPost, path, in, nil)
+}
+
+func (c *bitbucketClient) requests(op string, r releaseRequest) []string {
+	repo := c.baseURL + "/repositories/" + c.remote.Repo
+	switch op {
+	case apiCreateRelease:
+		reqs := []string{"PUT " + repo + "/commit/{commit}/reports/" + bitbucketReportKey(r.Tag)}
+		for range r.Assets {
+			reqs = append(reqs, "POST "+repo+"/downloads")
+		}
+		return reqs
+	case apiOpenPullRequest:
+		return []string{"POST " + repo + "/pullrequests"}
+	case apiCommentIssue:
+		return []string{"POST " + repo + "/issues/{number}/comments"}
+	}
+	return nil
+}
+
+// assetURL links to the file in the repository's downloads, which are not
+// per release.
+func (c *bitbucketClient) assetURL(_, name string) string {
+	return fmt.Sprintf("%s/downloads/%s", c.remote.WebURL(), url.PathEscape(name))
+}
+
+// uploadAsset adds the file to the repository's downloads, Bitbucket's only
+// place for release files.
+func (c *bitbucketClient) uploadAsset(_ string, a artifact) error {
+	body, contentType, err := multipartFile("files", a.Name, a.Path)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/repositories/%s/downloads", c.remote.Repo)
+	return c.send(http.MethodPost, c.baseURL+path, contentType, body, nil)
+}
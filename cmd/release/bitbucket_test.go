@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newTestBitbucket returns a client of repository o/r on a server with
+// handler.
+func newTestBitbucket(t *testing.T, handler http.HandlerFunc) *bitbucketClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return newBitbucketClient(remoteInfo{Host: "bitbucket.org", Repo: "o/r"}, clientOptions{Token: "t0ken", APIURL: srv.URL, HTTP: srv.Client()})
+}
+
+func TestBitbucketCompareURL(t *testing.T) {
+	c := newTestBitbucket(t, nil)
+	want := "https://bitbucket.org/o/r/branches/compare/v1.1.0%0Dv1.0.0"
+	if got := c.compareURL("v1.0.0", "v1.1.0"); got != want {
+		t.Errorf("compareURL = %q, want %q", got, want)
+	}
+}
+
+func TestBitbucketDraftRelease(t *testing.T) {
+	c := newTestBitbucket(t, nil)
+	if _, err := c.createRelease(releaseRequest{Tag: "v1.1.0", PreviousTag: "v1.0.0", Draft: true}); err == nil {
+		t.Error("createRelease of a draft succeeded, want error")
+	}
+	if _, err := c.publishRelease("v1.1.0", "true"); err == nil {
+		t.Error("publishRelease succeeded, want error")
+	}
+}
+
+func TestBitbucketCheckStatuses(t *testing.T) {
+	c := newTestBitbucket(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" || r.URL.Path != "/repositories/o/r/commit/abc123/statuses" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		fmt.Fprint(w, `{"values": [{"key": "build", "name": "Build", "state": "SUCCESSFUL"}, {"key": "lint", "state": "INPROGRESS"}]}`)
+	})
+
+	statuses, err := c.checkStatuses("abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []checkStatus{{Name: "Build", State: checkSuccess}, {Name: "lint", State: checkPending}}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("checkStatuses = %+v, want %+v", statuses, want)
+	}
+}
+
+func TestBitbucketOpenPullRequest(t *testing.T) {
+	c := newTestBitbucket(t, func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Source struct {
+				Branch struct{ Name string } `json:"branch"`
+			} `json:"source"`
+			Destination struct {
+				Branch struct{ Name string } `json:"branch"`
+			} `json:"destination"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		if r.Method != "POST" || r.URL.Path != "/repositories/o/r/pullrequests" || in.Source.Branch.Name != "release-v2" || in.Destination.Branch.Name != "main" {
+			t.Errorf("unexpected request %s %s %+v", r.Method, r.URL, in)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": 4, "links": {"html": {"href": "https://bitbucket.org/o/r/pull-requests/4"}}}`)
+	})
+
+	number, url, err := c.openPullRequest("release-v2", "main", "Release v2", "")
+	if err != nil || number != 4 || url != "https://bitbucket.org/o/r/pull-requests/4" {
+		t.Errorf("openPullRequest = %d, %q, %v", number, url, err)
+	}
+}
+
+func TestBitbucketListTags(t *testing.T) {
+	c := newTestBitbucket(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" || r.URL.Path != "/repositories/o/r/refs/tags" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		// Pages link to the next one with an absolute URL.
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprintf(w, `{"values": [{"name": "v1.0.0"}, {"name": "v1.1.0"}], "next": "http://%s/repositories/o/r/refs/tags?pagelen=100&page=2"}`, r.Host)
+		case "2":
+			fmt.Fprint(w, `{"values": [{"name": "v2.0.0"}]}`)
+		}
+	})
+
+	tags, err := c.listTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v1.0.0", "v1.1.0", "v2.0.0"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("listTags = %q, want %q", tags, want)
+	}
+}
+
+func TestBitbucketReportKey(t *testing.T) {
+	tests := []struct {
+		tag, want string
+	}{
+		{"v1.2.3", "release-v1.2.3"},
+		{"tools/v1.2.3", "release-tools-v1.2.3"},
+	}
+	for _, tt := range tests {
+		if got := bitbucketReportKey(tt.tag); got != tt.want {
+			t.Errorf("bitbucketReportKey(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
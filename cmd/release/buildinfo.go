@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// toolVersion is the version of the release tool, set when building it with
+// -ldflags='-X main.toolVersion=<version>'. It defaults to the module
+// version of the build info, which go install records.
+var toolVersion string
+
+// buildVersion describes the build of the release tool for bug reports:
+// its version, the commit it was built from, and the Go version, e.g.
+// "v4.2.0 (commit 1a2b3c4d5e6f, 2025-01-02T15:04:05Z) go1.24.0".
+func buildVersion() string {
+	version := installedVersion()
+	var details []string
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		settings := make(map[string]string)
+		for _, s := range info.Settings {
+			settings[s.Key] = s.Value
+		}
+		if rev := settings["vcs.revision"]; rev != "" {
+			if len(rev) > 12 {
+				rev = rev[:12]
+			}
+			if settings["vcs.modified"] == "true" {
+				rev += "-dirty"
+			}
+			details = append(details, "commit "+rev)
+		}
+		if t := settings["vcs.time"]; t != "" {
+			details = append(details, t)
+		}
+	}
+	s := version
+	if len(details) > 0 {
+		s += " (" + strings.Join(details, ", ") + ")"
+	}
+	return fmt.Sprintf("%s %s", s, runtime.Version())
+}
+
+// installedVersion returns the version of the release tool, "(devel)" when
+// it was built from a checkout.
+func installedVersion() string {
+	if toolVersion != "" {
+		return toolVersion
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// runChangelog prints the release notes of a tag, or of the commits since
+// the latest tag, in the builtin format.
+func runChangelog(fs *flag.FlagSet) func() {
+	tag := fs.String("tag", "", "Tag to print the notes of (default: the unreleased commits)")
+
+	return func() {
+		var (
+			previousTag string
+			err         error
+		)
+		if *tag == "" {
+			var current version
+			current, err = getCurrentVersion()
+			if errors.Is(err, ErrNoTags) {
+				err = nil // the first release lists every commit
+			}
+			*tag, previousTag = "HEAD", current.String()
+		} else if !tagExists(*tag) {
+			err = fmt.Errorf("tag %s does not exist", *tag)
+		} else {
+			previousTag, err = previousVersionTag(*tag)
+		}
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitGit)
+		}
+
+		// Links need the forge's URLs but no API access.
+		f, err := newLinkForge(forgeFlags)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitForge)
+		}
+
+		notes, err := releaseNotes(f, releaseRequest{Tag: *tag, PreviousTag: previousTag})
+		if err != nil {
+			slog.Error("Failed to render release notes", "err", err)
+			os.Exit(exitGit)
+		}
+		fmt.Print(notes)
+	}
+}
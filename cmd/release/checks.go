@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Normalized states of a commit check or status across forges.
+const (
+	checkPending = "pending"
+	checkSuccess = "success"
+	checkFailure = "failure"
+)
+
+// checkStatus is a single CI check or commit status reported for a commit.
+type checkStatus struct {
+	Name  string
+	State string
+}
+
+// checksPollInterval is how often pending checks are re-queried while
+// waiting for them to complete.
+const checksPollInterval = 15 * time.Second
+
+// waitForChecks verifies that the CI checks of commit are green. When
+// required is empty every reported check must succeed; otherwise only the
+// named checks are considered and each must be present. Pending or missing
+// checks are polled until timeout elapses; a zero timeout fails immediately.
+func waitForChecks(f forge, commit string, required []string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	var p *progress
+	defer func() {
+		if p != nil {
+			p.stop()
+		}
+	}()
+
+	for {
+		statuses, err := f.checkStatuses(commit)
+		if err != nil {
+			return fmt.Errorf("failed to query checks for %s: %w", commit, err)
+		}
+
+		failed, pending := evaluateChecks(statuses, required)
+		if len(failed) > 0 {
+			return fmt.Errorf("checks failed for %s: %s", commit, strings.Join(failed, ", "))
+		}
+		if len(pending) == 0 {
+			if len(statuses) == 0 {
+				slog.Info("No checks reported", "commit", commit)
+			} else {
+				success("All checks passed", "commit", commit)
+			}
+			return nil
+		}
+
+		if !time.Now().Add(checksPollInterval).Before(deadline) {
+			return fmt.Errorf("checks not completed for %s: %s", commit, strings.Join(pending, ", "))
+		}
+
+		if p == nil {
+			p = startProgress("Waiting for checks", "pending", strings.Join(pending, ", "))
+		}
+		slog.Debug("Pending checks", "pending", strings.Join(pending, ", "))
+		if err := sleep(checksPollInterval); err != nil {
+			return fmt.Errorf("checks not completed for %s: %w", commit, err)
+		}
+	}
+}
+
+// evaluateChecks returns the names of failed and of pending (or missing)
+// checks, sorted for stable output.
+func evaluateChecks(statuses []checkStatus, required []string) (failed, pending []string) {
+	states := make(map[string]string, len(statuses))
+	for _, s := range statuses {
+		// A check can be reported several times (e.g. re-runs); a failure
+		// anywhere wins over success, and pending over both.
+		switch states[s.Name] {
+		case checkPending:
+		case checkFailure:
+			if s.State == checkPending {
+				states[s.Name] = s.State
+			}
+		default:
+			states[s.Name] = s.State
+		}
+	}
+
+	names := required
+	if len(names) == 0 {
+		for name := range states {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		switch states[name] {
+		case checkSuccess:
+		case checkFailure:
+			failed = append(failed, name)
+		default:
+			pending = append(pending, name)
+		}
+	}
+
+	sort.Strings(failed)
+	sort.Strings(pending)
+	return failed, pending
+}
+
+// normalizeStatus maps the commit status states used by the various forges
+// onto the normalized check states.
+func normalizeStatus(state string) string {
+	switch strings.ToLower(state) {
+	case "success", "successful":
+		return checkSuccess
+	case "pending", "inprogress", "running", "":
+		return checkPending
+	default:
+		return checkFailure
+	}
+}
+
+func resolveCommit(ref string) (string, error) {
+	cmd := newCommand("git", "rev-parse", ref+"^{commit}")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// compensation undoes a completed part of a release.
+type compensation struct {
+	desc string
+	undo func() error
+}
+
+// onFailure registers undo to run if the release fails before the commit,
+// tag, and push sequence is sealed.
+func (r *runResult) onFailure(desc string, undo func() error) {
+	r.undo = append(r.undo, compensation{desc, undo})
+}
+
+// seal ends the commit, tag, and push sequence: once the tag is out, later
+// failures leave the release in place, to be resumed.
+func (r *runResult) seal() {
+	r.undo = nil
+}
+
+// compensate undoes the parts of the release registered with onFailure, in
+// reverse order, reporting whether all of them were.
+func (r *runResult) compensate() bool {
+	ok := true
+	for i := len(r.undo) - 1; i >= 0; i-- {
+		c := r.undo[i]
+		slog.Info("Cleaning up: " + c.desc)
+		if err := c.undo(); err != nil {
+			slog.Error("Failed to clean up: "+c.desc, "err", err)
+			ok = false
+		}
+	}
+	r.undo = nil
+	return ok
+}
+
+// modifiedFiles returns the tracked files with changes, relative to the
+// repository root.
+func modifiedFiles() ([]string, error) {
+	output, err := newCommand("git", "diff", "--name-only", "HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modified files: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// restoreFiles discards the changes to tracked files, except those that
+// were already modified before the release.
+func restoreFiles(before []string) error {
+	after, err := modifiedFiles()
+	if err != nil {
+		return err
+	}
+	var files []string
+	for _, f := range after {
+		if !slices.Contains(before, f) {
+			files = append(files, ":(top)"+f)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	args := append([]string{"checkout", "HEAD", "--"}, files...)
+	if err := newCommand("git", args...).Run(); err != nil {
+		return fmt.Errorf("failed to restore %s: %v", strings.Join(after, ", "), err)
+	}
+	return nil
+}
+
+// undoVersionCommit resets away or reverts the commit updating the module
+// path for tag, like the rollback command.
+func undoVersionCommit(tag string) error {
+	vc, err := findVersionCommit(tag)
+	if err != nil || vc.sha == "" {
+		return err
+	}
+	return vc.undo()
+}
+
+// deleteTag deletes tag locally and, if it was pushed, on the remote. A
+// remote tag of the same name pointing elsewhere, which rejected the push,
+// is kept.
+func deleteTag(tag string) error {
+	local, err := newCommand("git", "rev-parse", "-q", "--verify", "refs/tags/"+tag).Output()
+	if err != nil {
+		return nil
+	}
+	remote, err := newCommand("git", "ls-remote", remoteName, "refs/tags/"+tag).Output()
+	if fields := strings.Fields(string(remote)); err == nil && len(fields) > 0 && fields[0] == strings.TrimSpace(string(local)) {
+		if err := newCommand("git", "push", remoteName, "--delete", "refs/tags/"+tag).Run(); err != nil {
+			return fmt.Errorf("failed to delete remote tag: %w", err)
+		}
+	}
+	if err := newCommand("git", "tag", "-d", tag).Run(); err != nil {
+		return fmt.Errorf("failed to delete local tag: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,411 @@
+// Release bumps, tags, and publishes the versions of a Go module. Install it
+// with
+//
+//	go install github.com/raducristianpopa/test-go-pkg/v4/cmd/release@latest
+//
+// and run it in the repository of the module, e.g. "release -type=patch
+// -dry-run"; "release help" lists the commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// command is a subcommand of the release tool.
+type command struct {
+	name     string
+	synopsis string // arguments shown after the command name in the usage
+	summary  string
+	examples []string
+	// exits are the exit codes of the command's failures.
+	exits []int
+	// section is the config file section with the command's options, if
+	// not its name.
+	section string
+	// standalone commands do not need git, so they run outside a
+	// repository and without git installed.
+	standalone bool
+	// skipConfig commands do not read their options from the config file,
+	// such as the config command, which checks it.
+	skipConfig bool
+	// run defines the command's flags on fs and returns its action, which
+	// is called once they are parsed.
+	run func(fs *flag.FlagSet) func()
+}
+
+// commands is populated in init, as the wizard command refers to it.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name:     "release",
+			synopsis: "-type=<bump_type> [options]",
+			summary:  "Bump the version, tag it, and optionally publish a forge release.",
+			examples: []string{
+				"release -type=patch     # Bump patch version (1.0.0 -> 1.0.1)",
+				"release -type=minor     # Bump minor version (1.0.0 -> 1.1.0)",
+				"release -type=major     # Bump major version (1.0.0 -> 2.0.0)",
+				"release -type=patch -dry-run  # Show what would happen",
+				"release -type=major -prerelease=rc  # Release a candidate (1.0.0 -> 2.0.0-rc.1)",
+				"release -type=minor -create-release -draft  # Release as a draft",
+			},
+			exits: []int{exitFailure, exitUsage, exitPreflight, exitGit, exitForge, exitPartial, exitIncomplete},
+			run:   runRelease,
+		},
+		{
+			name:     "plan",
+			synopsis: "-type=<bump_type> [release options] [-out=<file>]",
+			summary:  "Compute a release and write its plan to a file for review, without releasing.",
+			examples: []string{
+				"plan -type=minor -create-release  # Write release-plan.json",
+			},
+			section: "release",
+			exits:   []int{exitFailure, exitUsage, exitPreflight, exitGit, exitForge},
+			run:     runPlan,
+		},
+		{
+			name:     "apply",
+			synopsis: "<plan-file>",
+			summary:  "Release exactly as planned by the plan command, if the repository did not change.",
+			examples: []string{
+				"apply release-plan.json",
+			},
+			exits: []int{exitFailure, exitUsage, exitPreflight, exitGit, exitForge, exitPartial, exitIncomplete},
+			run:   runApply,
+		},
+		{
+			name:    "resume",
+			summary: "Continue an interrupted release from its failed step, with the same options.",
+			examples: []string{
+				"resume  # After fixing what made a step fail",
+			},
+			exits: []int{exitFailure, exitUsage, exitPreflight, exitGit, exitForge, exitPartial, exitIncomplete},
+			run:   runResume,
+		},
+		{
+			name:     "publish",
+			synopsis: "[-tag=<tag>] [-latest]",
+			summary:  "Publish a draft release created with 'release -draft'.",
+			examples: []string{
+				"publish -tag=v1.1.0 -latest  # Publish the draft",
+			},
+			exits: []int{exitFailure, exitUsage, exitPreflight, exitGit, exitForge, exitIncomplete},
+			run:   runPublish,
+		},
+		{
+			name:     "changelog",
+			synopsis: "[-tag=<tag>]",
+			summary:  "Print the release notes of a tag, or of the unreleased commits.",
+			examples: []string{
+				"changelog              # Notes of the commits since the latest tag",
+				"changelog -tag=v1.1.0  # Notes of v1.1.0",
+			},
+			exits: []int{exitUsage, exitGit, exitForge},
+			run:   runChangelog,
+		},
+		{
+			name:     "feed",
+			synopsis: "[-o=<file>] [-entries=<n>]",
+			summary:  "Print an Atom feed of the releases, with their notes, from the tag history.",
+			examples: []string{
+				"feed -o=public/releases.atom  # Publish it with the project's pages",
+			},
+			exits: []int{exitFailure, exitUsage, exitGit},
+			run:   runFeed,
+		},
+		{
+			name:     "version",
+			synopsis: "[-type=<bump_type>]",
+			summary:  "Print the current version, or the next one for a bump type.",
+			examples: []string{
+				"version              # Current version",
+				"version -type=minor  # Version the next minor release would get",
+			},
+			exits: []int{exitUsage, exitGit, exitForge},
+			run:   runVersion,
+		},
+		{
+			name:     "verify",
+			synopsis: "[-tag=<tag>] [-dist=<dir>]",
+			summary:  "Check a released tag and, optionally, its artifacts against their checksums.",
+			examples: []string{
+				"verify -tag=v2.0.0              # Check the tag and its module path",
+				"verify -tag=v2.0.0 -dist=dist   # Also check the downloaded artifacts",
+			},
+			exits: []int{exitFailure, exitUsage, exitGit},
+			run:   runVerify,
+		},
+		{
+			name:     "rollback",
+			synopsis: "-tag=<tag> [-keep-commit]",
+			summary:  "Delete a tag locally and on the remote and revert its module path commit, undoing a failed release.",
+			examples: []string{
+				"rollback -tag=v1.1.0 -dry-run  # Show what would be deleted",
+			},
+			exits: []int{exitUsage, exitPreflight, exitGit},
+			run:   runRollback,
+		},
+		{
+			name:     "audit",
+			synopsis: "[-tag=<tag>] [-since=<duration>] [-failed]",
+			summary:  "Show the audit log of release runs, recorded as git notes and pushed with each release.",
+			examples: []string{
+				"audit -tag=v1.1.0   # Who released v1.1.0, when, and how",
+				"audit -failed -since=720h -output=json",
+			},
+			exits: []int{exitUsage, exitGit},
+			run:   runAudit,
+		},
+		{
+			name:     "init",
+			synopsis: "[-force] [-notes-template=<file>]",
+			summary:  "Inspect the repository, report how it would be released, and write a starter '.release.yaml'.",
+			examples: []string{
+				"init -notes-template=.github/release-notes.md",
+			},
+			exits: []int{exitFailure, exitUsage, exitPreflight, exitGit},
+			run:   runInit,
+		},
+		{
+			name:     "yank",
+			synopsis: "-tag=<tag> [-reason=<text>]",
+			summary:  "Retract a published version in 'go.mod' so the go command stops selecting it.",
+			examples: []string{
+				"yank -tag=v1.1.0 -reason='broken build'",
+			},
+			exits: []int{exitFailure, exitUsage, exitPreflight, exitGit},
+			run:   runYank,
+		},
+		{
+			name:     "config",
+			synopsis: "validate|schema",
+			summary:  "Check the config file for unknown options and invalid values, or print its JSON Schema.",
+			examples: []string{
+				"config validate                     # Check .release.yaml",
+				"config schema > release.schema.json  # Schema for editor completion",
+			},
+			skipConfig: true,
+			exits:      []int{exitUsage},
+			run:        runConfig,
+		},
+		{
+			name:    "wizard",
+			summary: "Walk through a release interactively: bump type, notes, and gates.",
+			exits:   []int{exitFailure, exitUsage, exitPreflight, exitGit, exitForge, exitPartial, exitIncomplete},
+			run:     runWizard,
+		},
+		{
+			name:     "completion",
+			synopsis: "[-name=<binary>] bash|zsh|fish",
+			summary:  "Print a shell completion script for commands, flags, and flag values.",
+			examples: []string{
+				"completion bash > /etc/bash_completion.d/release",
+				"completion -name=release zsh > \"${fpath[1]}/_release\"",
+				"completion fish > ~/.config/fish/completions/release.fish",
+			},
+			standalone: true,
+			exits:      []int{exitUsage},
+			run:        runCompletion,
+		},
+		{
+			name:     "self-update",
+			synopsis: "[-check] [-require-signature]",
+			summary:  "Replace the release tool with its latest release, verifying the download.",
+			examples: []string{
+				"self-update -check  # Report whether an update is available",
+			},
+			standalone: true,
+			exits:      []int{exitFailure, exitUsage, exitPreflight, exitForge},
+			run:        runSelfUpdate,
+		},
+	}
+}
+
+func main() {
+	registerGlobalFlags(flag.CommandLine)
+	printVersion := flag.Bool("version", false, "Print the version of the release tool and exit")
+	flag.Usage = usage
+	flag.Parse()
+	if *printVersion {
+		fmt.Printf("release %s\n", buildVersion())
+		return
+	}
+	if err := setupLogging(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	slog.Debug("Release tool", "version", buildVersion())
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	if args[0] == "help" {
+		if len(args) < 2 {
+			usage()
+			return
+		}
+		c := findCommand(args[1])
+		if c == nil {
+			slog.Error("Unknown command", "name", args[1])
+			usage()
+			os.Exit(exitUsage)
+		}
+		c.execute([]string{"-h"})
+		return
+	}
+
+	c := findCommand(args[0])
+	if c == nil {
+		slog.Error("Unknown command", "name", args[0])
+		usage()
+		os.Exit(exitUsage)
+	}
+	c.execute(args[1:])
+}
+
+func usage() {
+	fmt.Printf("Usage: %s [global options] <command> [options]\n\n", program)
+	fmt.Printf("Commands:\n")
+	for _, c := range commands {
+		fmt.Printf("  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Printf("\nGlobal options:\n")
+	printFlags(flag.CommandLine, true)
+	fmt.Printf("\nOptions are read, in order of precedence, from the command line, their\n")
+	fmt.Printf("environment variable, and the config file (%s by default).\n", defaultConfigFile)
+	fmt.Printf("\nExit codes:\n")
+	printExitCodes(nil)
+	fmt.Printf("\nRun '%s help <command>' for the options of a command, and\n", program)
+	fmt.Printf("'%s -version' for the version of the release tool.\n", program)
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// execute runs c with args.
+func (c *command) execute(args []string) {
+	fs := c.flagSet()
+	action := c.run(fs)
+	if err := c.parse(fs, args); err != nil {
+		slog.Error(err.Error())
+		os.Exit(exitUsage)
+	}
+
+	// exec.LookPath finds git.exe through %PATHEXT% on Windows, so this
+	// only fails if git is not installed at all.
+	if !c.standalone {
+		if _, err := exec.LookPath("git"); err != nil {
+			slog.Error("git not found in PATH; install it (Git for Windows on Windows) and retry", "err", err)
+			os.Exit(exitPreflight)
+		}
+	}
+	startRun()
+	action()
+}
+
+// flagSet returns the flag set of c, with the global flags registered and
+// the usage printing c's help.
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	registerGlobalFlags(fs)
+	fs.Usage = func() { c.printUsage(fs) }
+	return fs
+}
+
+func (c *command) printUsage(fs *flag.FlagSet) {
+	fmt.Printf("Usage: %s\n\n", strings.TrimSpace(program+" "+c.name+" "+c.synopsis))
+	fmt.Printf("%s\n", c.summary)
+
+	if hasFlags(fs, false) {
+		fmt.Printf("\nOptions:\n")
+		printFlags(fs, false)
+	}
+	fmt.Printf("\nGlobal options:\n")
+	printFlags(fs, true)
+
+	if len(c.examples) > 0 {
+		fmt.Printf("\nExamples:\n")
+		for _, e := range c.examples {
+			fmt.Printf("  %s %s\n", program, e)
+		}
+	}
+
+	fmt.Printf("\nExit codes:\n")
+	printExitCodes(c.exits)
+}
+
+// forgeFlags is the forge configuration set by the global forge flags.
+var forgeFlags = forgeConfig{Kind: "auto"}
+
+// remoteName is the git remote releases are pushed to.
+var remoteName = "origin"
+
+// registerGlobalFlags defines the flags accepted by every command, both
+// before and after the command name, on fs. The current values are the
+// defaults, so registering them on a command does not undo the global
+// flags given before it.
+func registerGlobalFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&verbose, "v", verbose, "Show debug output, such as forge API quota")
+	fs.BoolVar(&verbose, "verbose", verbose, "Alias of -v")
+	fs.BoolVar(&veryVerbose, "vv", veryVerbose, "Show trace output, such as every forge API request")
+	fs.BoolVar(&quiet, "quiet", quiet, "Only show errors")
+	fs.BoolVar(&traceCommands, "x", traceCommands, "Print each external command, with its working directory, right before running it")
+	fs.StringVar(&logFormat, "log-format", logFormat, "Log format: text or json")
+	fs.BoolVar(&noColor, "no-color", noColor, "Disable colored output (also disabled by $NO_COLOR or when not writing to a terminal)")
+	fs.StringVar(&configPath, "config", configPath, "Config file with option defaults (default: "+defaultConfigFile+" in the repository root, if present)")
+	fs.StringVar(&profileName, "profile", profileName, "Config file profile to apply, e.g. staging")
+	fs.StringVar(&remoteName, "remote", remoteName, "Git remote releases are pushed to")
+	fs.BoolVar(&assumeYes, "yes", assumeYes, "Make changes without asking for confirmation, e.g. in CI")
+	fs.BoolVar(&assumeYes, "non-interactive", assumeYes, "Alias of -yes")
+	fs.DurationVar(&runTimeout, "timeout", runTimeout, "Fail the run once it takes longer than this, e.g. 15m, killing its running commands (default: no timeout)")
+	fs.StringVar(&tagPrefix, "tag-prefix", tagPrefix, "Prefix of the version tags, e.g. 'tools/' for a module in the tools directory")
+	registerForgeFlags(fs, &forgeFlags)
+}
+
+var globalFlags = func() map[string]bool {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	registerGlobalFlags(fs)
+
+	names := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) { names[f.Name] = true })
+	return names
+}()
+
+func hasFlags(fs *flag.FlagSet, global bool) bool {
+	found := false
+	fs.VisitAll(func(f *flag.Flag) {
+		if globalFlags[f.Name] == global {
+			found = true
+		}
+	})
+	return found
+}
+
+// printFlags prints the defaults of either the global or the command's own
+// flags in fs.
+func printFlags(fs *flag.FlagSet, global bool) {
+	sub := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	fs.VisitAll(func(f *flag.Flag) {
+		if globalFlags[f.Name] == global {
+			sub.Var(f.Value, f.Name, f.Usage+" [$"+envName(f.Name)+"]")
+			sub.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	sub.SetOutput(os.Stdout)
+	sub.PrintDefaults()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// flagValues are the values completed for flags taking one of a fixed set.
+var flagValues = map[string][]string{
+	"type":           {"major", "minor", "patch"},
+	"forge":          {"auto", "github", "gitea", "bitbucket"},
+	"notes":          {"auto", "builtin", "github"},
+	"sbom":           {"spdx", "cyclonedx"},
+	"version-source": {"git", "forge"},
+	"output":         {"text", "json"},
+	"log-format":     {"text", "json"},
+}
+
+// runCompletion prints a shell completion script for the release tool
+// installed as a binary named -name.
+func runCompletion(fs *flag.FlagSet) func() {
+	name := fs.String("name", "release", "Name of the installed binary the completion is for")
+
+	return func() {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			slog.Error("Expected one shell: bash, zsh, or fish")
+			os.Exit(exitUsage)
+		}
+
+		switch fs.Arg(0) {
+		case "bash":
+			writeBashCompletion(*name)
+		case "zsh":
+			writeZshCompletion(*name)
+		case "fish":
+			writeFishCompletion(*name)
+		default:
+			slog.Error("Unsupported shell, must be bash, zsh, or fish", "shell", fs.Arg(0))
+			os.Exit(exitUsage)
+		}
+	}
+}
+
+// commandFlags returns the flags of c, global ones included, sorted by name.
+func commandFlags(c *command) []*flag.Flag {
+	fs := c.flagSet()
+	c.run(fs)
+
+	var flags []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+	return flags
+}
+
+func globalFlagList() []*flag.Flag {
+	var flags []*flag.Flag
+	flag.CommandLine.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+	return flags
+}
+
+func flagNames(flags []*flag.Flag) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "-" + f.Name
+	}
+	return strings.Join(names, " ")
+}
+
+func commandNames() string {
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+	}
+	return strings.Join(names, " ")
+}
+
+func sortedFlagValues() []string {
+	names := make([]string, 0, len(flagValues))
+	for name := range flagValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeBashCompletion(name string) {
+	fn := "_" + strings.ReplaceAll(name, "-", "_")
+
+	fmt.Printf("# bash completion for %s\n", name)
+	fmt.Printf("%s() {\n", fn)
+	fmt.Printf("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" cmd= i\n")
+	fmt.Printf("\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Printf("\t\tcase \"${COMP_WORDS[i]}\" in -*) ;; *) cmd=\"${COMP_WORDS[i]}\"; break ;; esac\n")
+	fmt.Printf("\tdone\n\n")
+
+	fmt.Printf("\tlocal flag=\"${prev#-}\"\n")
+	fmt.Printf("\tif [[ \"$cur\" == -*=* ]]; then flag=\"${cur%%%%=*}\"; flag=\"${flag#-}\"; cur=\"${cur#*=}\"; fi\n")
+	fmt.Printf("\tcase \"$flag\" in\n")
+	for _, f := range sortedFlagValues() {
+		fmt.Printf("\t%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", f, strings.Join(flagValues[f], " "))
+	}
+	fmt.Printf("\tesac\n\n")
+
+	fmt.Printf("\tcase \"$cmd\" in\n")
+	fmt.Printf("\t\"\") COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", commandNames()+" help "+flagNames(globalFlagList()))
+	for _, c := range commands {
+		fmt.Printf("\t%s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", c.name, flagNames(commandFlags(c)))
+	}
+	fmt.Printf("\thelp) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", commandNames())
+	fmt.Printf("\tesac\n")
+	fmt.Printf("}\n")
+	fmt.Printf("complete -F %s %s\n", fn, name)
+}
+
+func writeZshCompletion(name string) {
+	fmt.Printf("#compdef %s\n\n", name)
+	fmt.Printf("local -a commands\ncommands=(\n")
+	for _, c := range commands {
+		fmt.Printf("\t%s\n", zshQuote(c.name+":"+c.summary))
+	}
+	fmt.Printf(")\n\n")
+
+	fmt.Printf("local cmd=${words[(r)[^-]*]:#%s}\n", name)
+	fmt.Printf("local cur=${words[CURRENT]} prev=${words[CURRENT-1]}\n")
+	fmt.Printf("local flag=${prev#-}\n")
+	fmt.Printf("if [[ $cur == -*=* ]]; then flag=${${cur%%%%=*}#-}; compset -P '*='; fi\n")
+	fmt.Printf("case $flag in\n")
+	for _, f := range sortedFlagValues() {
+		fmt.Printf("%s) compadd -- %s; return ;;\n", f, strings.Join(flagValues[f], " "))
+	}
+	fmt.Printf("esac\n\n")
+
+	fmt.Printf("case $cmd in\n")
+	fmt.Printf("'') [[ $cur == -* ]] && compadd -- %s || _describe command commands ;;\n", flagNames(globalFlagList()))
+	for _, c := range commands {
+		fmt.Printf("%s) compadd -- %s ;;\n", c.name, flagNames(commandFlags(c)))
+	}
+	fmt.Printf("help) _describe command commands ;;\n")
+	fmt.Printf("esac\n")
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeFishCompletion(name string) {
+	fmt.Printf("# fish completion for %s\n", name)
+	fmt.Printf("complete -c %s -f\n", name)
+	for _, c := range commands {
+		fmt.Printf("complete -c %s -n __fish_use_subcommand -a %s -d %s\n", name, c.name, fishQuote(c.summary))
+	}
+	fmt.Printf("complete -c %s -n __fish_use_subcommand -a help -d %s\n", name, fishQuote("Show the help of a command"))
+	fmt.Printf("complete -c %s -n '__fish_seen_subcommand_from help' -a %s\n", name, fishQuote(commandNames()))
+
+	for _, f := range globalFlagList() {
+		fmt.Printf("complete -c %s -o %s%s -d %s\n", name, f.Name, fishValues(f.Name), fishQuote(f.Usage))
+	}
+	for _, c := range commands {
+		for _, f := range commandFlags(c) {
+			if globalFlags[f.Name] {
+				continue
+			}
+			fmt.Printf("complete -c %s -n '__fish_seen_subcommand_from %s' -o %s%s -d %s\n", name, c.name, f.Name, fishValues(f.Name), fishQuote(f.Usage))
+		}
+	}
+}
+
+func fishValues(flag string) string {
+	values, ok := flagValues[flag]
+	if !ok {
+		return ""
+	}
+	return " -xa " + fishQuote(strings.Join(values, " "))
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultConfigFile is the config file looked up in the repository root.
+const defaultConfigFile = ".release.yaml"
+
+// configPath is the config file set with -config; empty uses
+// defaultConfigFile if the repository has one.
+var configPath string
+
+// profileName is the config file profile selected with -profile.
+var profileName string
+
+// config holds the option defaults of the config file. Keys are flag names:
+// top-level values apply to every command with that flag, and the values of
+// a section named after a command apply to that command only, taking
+// precedence over top-level ones; the plan command shares the release
+// section. For example:
+//
+//	remote: upstream
+//	release:
+//	  branch: main
+//	  check-gate: true
+//	  required-checks: [build, test]
+//	  notes: builtin
+//
+// Lists are joined with commas, the separator of the list flags.
+//
+// The profiles section holds named sets of values for different release
+// targets, selected with -profile. Like top-level values they apply to
+// every command with the flag, but they take precedence over sections:
+//
+//	profiles:
+//	  staging:
+//	    remote: mirror
+//	    prerelease: rc
+//	  prod:
+//	    create-release: true
+//
+// The hooks section holds commands run at points of a release; see
+// hookPoints. The notify section holds the notifiers announcing a release;
+// see notifySlack.
+type config struct {
+	values   map[string]string
+	sections map[string]map[string]string
+	profiles map[string]map[string]string
+	hooks    map[string][]string
+	notify   []notifier
+}
+
+// configFile returns the path of the config file: the one given with
+// -config, or else the default one of the repository, which may not
+// exist. It is empty outside a repository.
+func configFile() string {
+	if configPath != "" {
+		return configPath
+	}
+	output, err := newCommand("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(strings.TrimSpace(string(output)), defaultConfigFile)
+}
+
+// loadConfig reads the config file, returning an empty config if none was
+// given and the repository has no default one.
+func loadConfig() (*config, error) {
+	path := configFile()
+	if path == "" {
+		return &config{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && configPath == "" {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// apply sets the flags of fs not in set to their configured values, those
+// of the selected profile taking precedence over those of section, which
+// take precedence over top-level ones.
+func (cfg *config) apply(fs *flag.FlagSet, section string, set map[string]bool) error {
+	if profileName != "" {
+		profile, ok := cfg.profiles[profileName]
+		if !ok {
+			return fmt.Errorf("unknown profile %q", profileName)
+		}
+		// Profiles may set other commands' flags.
+		for name, value := range profile {
+			if fs.Lookup(name) == nil || set[name] {
+				continue
+			}
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid value %q for profiles.%s.%s: %w", value, profileName, name, err)
+			}
+			set[name] = true
+		}
+	}
+
+	for name, value := range cfg.sections[section] {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown option %q in section %q", name, section)
+		}
+		if set[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %q for %s.%s: %w", value, section, name, err)
+		}
+		set[name] = true
+	}
+
+	// Top-level values may belong to other commands' flags.
+	for name, value := range cfg.values {
+		if fs.Lookup(name) == nil || set[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %w", value, name, err)
+		}
+	}
+	return nil
+}
+
+// parse parses args into fs and fills in the options not given on the
+// command line, before or after the command name, from the environment and
+// then from the config file.
+func (c *command) parse(fs *flag.FlagSet, args []string) error {
+	_ = fs.Parse(args)
+
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if err := applyEnv(fs, set); err != nil {
+		return err
+	}
+
+	if !c.skipConfig {
+		cfg, err := loadConfig()
+		if err == nil {
+			configHooks, configNotifiers = cfg.hooks, cfg.notify
+			err = cfg.apply(fs, c.configSection(), set)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
+	return setupLogging()
+}
+
+// configSection returns the config file section with c's options.
+func (c *command) configSection() string {
+	if c.section != "" {
+		return c.section
+	}
+	return c.name
+}
+
+// envName is the environment variable setting the flag name, e.g.
+// RELEASE_DRY_RUN for -dry-run.
+func envName(name string) string {
+	return "RELEASE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnv sets the flags of fs not in set from their environment
+// variables, adding them to set.
+func applyEnv(fs *flag.FlagSet, set map[string]bool) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || err != nil {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if e := fs.Set(f.Name, value); e != nil {
+			err = fmt.Errorf("invalid value %q for $%s: %v", value, envName(f.Name), e)
+			return
+		}
+		set[f.Name] = true
+	})
+	return err
+}
+
+type configLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// parseConfig parses the YAML subset the config file is written in:
+// mappings nested by indentation, scalars, and lists either in flow style
+// ("[a, b]") or as "- item" lines.
+func parseConfig(data []byte) (*config, error) {
+	var lines []configLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripComment(raw), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, configLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return &config{}, nil
+	}
+
+	root, next, err := parseConfigBlock(lines, 0)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].num)
+	}
+
+	top, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("line %d: expected a mapping", lines[0].num)
+	}
+
+	cfg := &config{values: make(map[string]string), sections: make(map[string]map[string]string)}
+	for key, value := range top {
+		if key == "profiles" {
+			if cfg.profiles, err = parseProfiles(value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if key == "hooks" {
+			if cfg.hooks, err = parseHooks(value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if key == "notify" {
+			if cfg.notify, err = parseNotify(value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		section, ok := value.(map[string]any)
+		if !ok {
+			cfg.values[key] = configValue(value)
+			continue
+		}
+
+		cfg.sections[key] = make(map[string]string)
+		for name, value := range section {
+			if _, ok := value.(map[string]any); ok {
+				return nil, fmt.Errorf("%s.%s: options cannot be nested further", key, name)
+			}
+			cfg.sections[key][name] = configValue(value)
+		}
+	}
+	return cfg, nil
+}
+
+// parseProfiles converts the profiles section, a mapping of profile names
+// to their options.
+func parseProfiles(v any) (map[string]map[string]string, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("profiles: expected a mapping of profile names")
+	}
+
+	profiles := make(map[string]map[string]string)
+	for name, value := range m {
+		options, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("profiles.%s: expected a mapping of options", name)
+		}
+		profiles[name] = make(map[string]string)
+		for option, value := range options {
+			if _, ok := value.(map[string]any); ok {
+				return nil, fmt.Errorf("profiles.%s.%s: options cannot be nested further", name, option)
+			}
+			profiles[name][option] = configValue(value)
+		}
+	}
+	return profiles, nil
+}
+
+// parseConfigBlock parses the mapping or list starting at lines[i],
+// returning it and the index of the first line after it.
+func parseConfigBlock(lines []configLine, i int) (any, int, error) {
+	indent := lines[i].indent
+
+	if isListItem(lines[i].text) {
+		var list []string
+		for ; i < len(lines) && lines[i].indent == indent && isListItem(lines[i].text); i++ {
+			item, err := configScalar(strings.TrimSpace(strings.TrimPrefix(lines[i].text, "-")))
+			if err != nil {
+				return nil, 0, fmt.Errorf("line %d: %w", lines[i].num, err)
+			}
+			list = append(list, item)
+		}
+		return list, i, nil
+	}
+
+	m := make(map[string]any)
+	for i < len(lines) && lines[i].indent == indent {
+		l := lines[i]
+		key, rest, ok := strings.Cut(l.text, ":")
+		if !ok || key == "" {
+			return nil, 0, fmt.Errorf("line %d: expected 'key: value'", l.num)
+		}
+		if _, dup := m[key]; dup {
+			return nil, 0, fmt.Errorf("line %d: duplicate key %q", l.num, key)
+		}
+		rest = strings.TrimSpace(rest)
+		i++
+
+		switch {
+		case rest != "":
+			value, err := configFlowValue(rest)
+			if err != nil {
+				return nil, 0, fmt.Errorf("line %d: %w", l.num, err)
+			}
+			m[key] = value
+		case i < len(lines) && lines[i].indent > indent:
+			value, next, err := parseConfigBlock(lines, i)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key], i = value, next
+		default:
+			m[key] = ""
+		}
+	}
+
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, 0, fmt.Errorf("line %d: unexpected indentation", lines[i].num)
+	}
+	return m, i, nil
+}
+
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// configFlowValue parses an inline value: a flow list or a scalar.
+func configFlowValue(s string) (any, error) {
+	if !strings.HasPrefix(s, "[") {
+		return configScalar(s)
+	}
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("unterminated list %s", s)
+	}
+
+	var list []string
+	for _, item := range strings.Split(s[1:len(s)-1], ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		v, err := configScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// configScalar unquotes a scalar value.
+func configScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	default:
+		return s, nil
+	}
+}
+
+func configValue(v any) string {
+	if list, ok := v.([]string); ok {
+		return strings.Join(list, ",")
+	}
+	return v.(string)
+}
+
+// stripComment removes a "#" comment, which starts a line or follows
+// whitespace, outside of quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
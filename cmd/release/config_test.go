@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		values   map[string]string
+		sections map[string]map[string]string
+		profiles map[string]map[string]string
+	}{
+		{
+			name:     "empty",
+			data:     "# nothing\n\n",
+			values:   nil,
+			sections: nil,
+		},
+		{
+			name:     "scalars",
+			data:     "remote: upstream # the fork\nbranch: \"main\"\nnotes: 'it''s'\n",
+			values:   map[string]string{"remote": "upstream", "branch": "main", "notes": "it's"},
+			sections: map[string]map[string]string{},
+		},
+		{
+			name:     "sections and lists",
+			data:     "remote: upstream\nrelease:\n  check-gate: true\n  required-checks: [build, \"test\"]\n  platforms:\n    - linux/amd64\n    - darwin/arm64\n",
+			values:   map[string]string{"remote": "upstream"},
+			sections: map[string]map[string]string{"release": {"check-gate": "true", "required-checks": "build,test", "platforms": "linux/amd64,darwin/arm64"}},
+		},
+		{
+			name:     "profiles",
+			data:     "profiles:\n  staging:\n    remote: mirror\n  prod:\n    create-release: true\n",
+			values:   map[string]string{},
+			sections: map[string]map[string]string{},
+			profiles: map[string]map[string]string{"staging": {"remote": "mirror"}, "prod": {"create-release": "true"}},
+		},
+		{
+			name:     "hash in a value",
+			data:     "notes: \"see #12\"\nbranch: a#b\n",
+			values:   map[string]string{"notes": "see #12", "branch": "a#b"},
+			sections: map[string]map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		cfg, err := parseConfig([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: parseConfig error: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.values, tt.values) {
+			t.Errorf("%s: values = %v, want %v", tt.name, cfg.values, tt.values)
+		}
+		if !reflect.DeepEqual(cfg.sections, tt.sections) {
+			t.Errorf("%s: sections = %v, want %v", tt.name, cfg.sections, tt.sections)
+		}
+		if !reflect.DeepEqual(cfg.profiles, tt.profiles) {
+			t.Errorf("%s: profiles = %v, want %v", tt.name, cfg.profiles, tt.profiles)
+		}
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"tab indentation", "release:\n\tbranch: main\n"},
+		{"no colon", "remote upstream\n"},
+		{"duplicate key", "remote: a\nremote: b\n"},
+		{"unexpected indentation", "remote: a\n  branch: main\n"},
+		{"nested option", "release:\n  checks:\n    build: true\n"},
+		{"unterminated list", "checks: [build, test\n"},
+		{"unterminated string", "notes: 'open\n"},
+		{"top-level list", "- a\n- b\n"},
+		{"invalid profile", "profiles:\n  prod: true\n"},
+	}
+	for _, tt := range tests {
+		if _, err := parseConfig([]byte(tt.data)); err == nil {
+			t.Errorf("%s: parseConfig(%q) succeeded, want an error", tt.name, tt.data)
+		}
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	cfg, err := parseConfig([]byte(`remote: top
+branch: top
+draft: true
+prerelease: top
+notes: top
+release:
+  branch: section
+  prerelease: section
+  notes: section
+profiles:
+  staging:
+    prerelease: profile
+    notes: profile
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldProfile := profileName
+	profileName = "staging"
+	t.Cleanup(func() { profileName = oldProfile })
+	t.Setenv(envName("notes"), "env")
+	t.Setenv(envName("draft"), "false")
+
+	fs := flag.NewFlagSet("release", flag.ContinueOnError)
+	flags := map[string]*string{}
+	for _, name := range []string{"remote", "branch", "prerelease", "notes", "tag"} {
+		flags[name] = fs.String(name, "default", "")
+	}
+	draft := fs.Bool("draft", true, "")
+	if err := fs.Parse([]string{"-tag=flag", "-draft"}); err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]bool{"tag": true, "draft": true}
+	if err := applyEnv(fs, set); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.apply(fs, "release", set); err != nil {
+		t.Fatal(err)
+	}
+
+	// The command line takes precedence over the environment, which takes
+	// precedence over the profile, the command's section, and the top
+	// level, in this order.
+	want := map[string]string{
+		"tag":        "flag",
+		"notes":      "env",
+		"prerelease": "profile",
+		"branch":     "section",
+		"remote":     "top",
+	}
+	for name, value := range want {
+		if got := *flags[name]; got != value {
+			t.Errorf("-%s = %q, want %q", name, got, value)
+		}
+	}
+	if !*draft {
+		t.Error("-draft = false, want true from the command line")
+	}
+}
+
+func TestConfigUnknownOption(t *testing.T) {
+	cfg, err := parseConfig([]byte("release:\n  no-such-flag: 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("release", flag.ContinueOnError)
+	if err := cfg.apply(fs, "release", map[string]bool{}); err == nil {
+		t.Error("apply succeeded with an unknown option in the section")
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// runConfig validates the config file or prints its JSON Schema.
+func runConfig(fs *flag.FlagSet) func() {
+	return func() {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			slog.Error("Expected one subcommand: validate or schema")
+			os.Exit(exitUsage)
+		}
+
+		switch fs.Arg(0) {
+		case "validate":
+			path := configFile()
+			if path == "" {
+				slog.Error("Not in a repository; give the config file with -config")
+				os.Exit(exitUsage)
+			}
+			problems, err := validateConfig(path)
+			if err != nil {
+				slog.Error("Invalid config", "file", path, "err", err)
+				os.Exit(exitUsage)
+			}
+			for _, p := range problems {
+				slog.Error(p)
+			}
+			if len(problems) > 0 {
+				slog.Error("Invalid config", "file", path, "problems", len(problems))
+				os.Exit(exitUsage)
+			}
+			success("Config is valid", "file", path)
+		case "schema":
+			printConfigSchema()
+		default:
+			fs.Usage()
+			slog.Error("Unknown subcommand, must be 'validate' or 'schema'", "name", fs.Arg(0))
+			os.Exit(exitUsage)
+		}
+	}
+}
+
+// configFlags returns the flag sets of the config file sections, by
+// section, with the flags of the commands reading them.
+func configFlags() map[string]*flag.FlagSet {
+	sections := make(map[string]*flag.FlagSet)
+	for _, c := range commands {
+		if c.skipConfig {
+			continue
+		}
+		fs := c.flagSet()
+		c.run(fs)
+		if prev, ok := sections[c.configSection()]; ok {
+			// Commands sharing a section, like plan and release.
+			prev.VisitAll(func(f *flag.Flag) {
+				if fs.Lookup(f.Name) == nil {
+					fs.Var(f.Value, f.Name, f.Usage)
+				}
+			})
+		}
+		sections[c.configSection()] = fs
+	}
+	return sections
+}
+
+// allConfigFlags returns the flags of every section by name, those
+// allowed at the top level and in profiles.
+func allConfigFlags(sections map[string]*flag.FlagSet) map[string]*flag.Flag {
+	flags := make(map[string]*flag.Flag)
+	for _, fs := range sections {
+		fs.VisitAll(func(f *flag.Flag) { flags[f.Name] = f })
+	}
+	return flags
+}
+
+// validateConfig returns the unknown options and invalid values of the
+// config file at path, failing if it cannot be read or parsed.
+func validateConfig(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := configFlags()
+	flags := allConfigFlags(sections)
+
+	var problems []string
+	check := func(where string, values map[string]string, known map[string]*flag.Flag) {
+		for _, name := range sortedNames(values) {
+			f, ok := known[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%sunknown option %q%s", where, name, suggestion(name, known)))
+				continue
+			}
+			if err := checkFlagValue(f, values[name]); err != nil {
+				problems = append(problems, fmt.Sprintf("%sinvalid value %q for %s: %v", where, values[name], name, err))
+			}
+		}
+	}
+
+	check("", cfg.values, flags)
+	for _, name := range sortedNames(cfg.sections) {
+		fs, ok := sections[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown section %q%s", name, suggestion(name, sections)))
+			continue
+		}
+		known := make(map[string]*flag.Flag)
+		fs.VisitAll(func(f *flag.Flag) { known[f.Name] = f })
+		check(name+": ", cfg.sections[name], known)
+	}
+	for _, name := range sortedNames(cfg.profiles) {
+		check("profiles."+name+": ", cfg.profiles[name], flags)
+	}
+
+	return problems, nil
+}
+
+// checkFlagValue reports whether value can be set on f, without setting it.
+func checkFlagValue(f *flag.Flag, value string) error {
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return nil
+	}
+	var err error
+	switch getter.Get().(type) {
+	case bool:
+		_, err = strconv.ParseBool(value)
+	case time.Duration:
+		_, err = time.ParseDuration(value)
+	case int:
+		_, err = strconv.Atoi(value)
+	}
+	return err
+}
+
+// suggestion returns a hint naming the key of known closest to name, if
+// any is close enough to be a likely typo.
+func suggestion[V any](name string, known map[string]V) string {
+	best, bestDist := "", len(name)/3+1
+	for _, k := range sortedNames(known) {
+		if d := editDistance(name, k); d <= bestDist && (best == "" || d < bestDist) {
+			best, bestDist = k, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("; did you mean %q?", best)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printConfigSchema prints the JSON Schema of the config file, for editors
+// to complete and check it, e.g. with the YAML language server's
+// "# yaml-language-server: $schema=<file>" comment.
+func printConfigSchema() {
+	sections := configFlags()
+	flags := allConfigFlags(sections)
+
+	options := func(flags map[string]*flag.Flag) map[string]any {
+		props := make(map[string]any)
+		for name, f := range flags {
+			props[name] = flagSchema(f)
+		}
+		return props
+	}
+
+	props := options(flags)
+	for name, fs := range sections {
+		known := make(map[string]*flag.Flag)
+		fs.VisitAll(func(f *flag.Flag) { known[f.Name] = f })
+		props[name] = map[string]any{
+			"type":                 "object",
+			"description":          "Options of the " + name + " command, taking precedence over top-level ones",
+			"properties":           options(known),
+			"additionalProperties": false,
+		}
+	}
+	props["profiles"] = map[string]any{
+		"type":        "object",
+		"description": "Named sets of options selected with -profile, taking precedence over sections",
+		"additionalProperties": map[string]any{
+			"type":                 "object",
+			"properties":           options(flags),
+			"additionalProperties": false,
+		},
+	}
+	hooks := make(map[string]any)
+	for _, point := range hookPoints {
+		hooks[point] = map[string]any{
+			"description": "Commands run at the " + point + " hook point",
+			"oneOf": []any{
+				map[string]any{"type": "string"},
+				map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			},
+		}
+	}
+	props["hooks"] = map[string]any{
+		"type":                 "object",
+		"description":          "Commands run at points of a release",
+		"properties":           hooks,
+		"additionalProperties": false,
+	}
+	notifyProps := map[string]any{
+		"type":    map[string]any{"description": "Kind of notifier (default: inferred from the URL, or the name)", "enum": sortedNames(notifyOptions)},
+		"url":     map[string]any{"type": "string", "description": "URL the notification is sent to; $VAR and ${VAR} are expanded"},
+		"message": map[string]any{"type": "string", "description": "Template of the message; fields: .Module, .Tag, .Commit, .Version, .PreviousTag, .Prerelease, .ReleaseURL, .CompareURL, .Changes, .MoreChanges, .Notes"},
+		"changes": map[string]any{"type": "integer", "minimum": 0, "description": fmt.Sprintf("Number of changes listed (default %d)", defaultNotifyChanges)},
+	}
+	for _, kind := range sortedNames(notifyOptions) {
+		for _, option := range notifyOptions[kind] {
+			notifyProps[option] = map[string]any{"type": "string", "description": "Option of " + kind + " notifiers"}
+		}
+	}
+	props["notify"] = map[string]any{
+		"type":        "object",
+		"description": "Notifiers announcing a published release, by name",
+		"additionalProperties": map[string]any{
+			"type":                 "object",
+			"properties":           notifyProps,
+			"required":             []string{"url"},
+			"additionalProperties": false,
+		},
+	}
+
+	schema := map[string]any{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                "Release tool config (" + defaultConfigFile + ")",
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(schema)
+}
+
+// flagSchema returns the JSON Schema of the config value of f. List flags
+// are strings, which the config file may also give as lists.
+func flagSchema(f *flag.Flag) map[string]any {
+	s := map[string]any{"description": f.Usage}
+	getter, _ := f.Value.(flag.Getter)
+	var v any
+	if getter != nil {
+		v = getter.Get()
+	}
+	switch v.(type) {
+	case bool:
+		s["type"] = "boolean"
+	case int:
+		s["type"] = "integer"
+	case time.Duration:
+		s["type"] = "string"
+		s["pattern"] = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$|^0$`
+	default:
+		if values, ok := flagValues[f.Name]; ok {
+			s["enum"] = values
+		} else {
+			s["type"] = []string{"string", "array"}
+			s["items"] = map[string]any{"type": "string"}
+		}
+	}
+	return s
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// assumeYes skips the confirmation before changes are made, for CI.
+var assumeYes bool
+
+// confirm shows summary and the steps about to run, then asks to go ahead.
+// It fails unless the answer is yes, or without asking if stdin is not a
+// terminal, so unattended runs must opt in with -yes.
+func confirm(summary string, steps []string) error {
+	fmt.Fprintf(messages, "\n%s\n", summary)
+	for i, step := range steps {
+		fmt.Fprintf(messages, "  %d. %s\n", i+1, step)
+	}
+	fmt.Fprintln(messages)
+
+	if assumeYes {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return errors.New("confirmation required but stdin is not a terminal; pass -yes to proceed")
+	}
+
+	fmt.Fprint(messages, "Proceed? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return errors.New("aborted")
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// isTerminalWriter reports whether w is a file that is a terminal.
+func isTerminalWriter(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isTerminal(f)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// coverageThresholds are the limits of -min-coverage and
+// -max-coverage-drop, in percent of statements; zero disables a limit.
+type coverageThresholds struct {
+	Min     float64
+	MaxDrop float64
+}
+
+func (t coverageThresholds) enabled() bool {
+	return t.Min > 0 || t.MaxDrop > 0
+}
+
+// testCoverage runs the tests of the module in dir and returns the total
+// coverage of its statements, in percent.
+func testCoverage(dir string) (float64, error) {
+	tmp, err := os.MkdirTemp("", "release-coverage-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmp)
+	profile := filepath.Join(tmp, "cover.out")
+
+	p := startProgress("Running the tests with coverage", "dir", dir)
+	out, err := commandIn(dir, "go", "test", "-coverprofile="+profile, "./...").CombinedOutput()
+	p.stop()
+	if err != nil {
+		return 0, fmt.Errorf("tests failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	if _, err := os.Stat(profile); err != nil {
+		return 0, errors.New("the module has no tests")
+	}
+
+	out, err = commandIn(dir, "go", "tool", "cover", "-func="+profile).Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the coverage profile: %w", err)
+	}
+	// The last line is the total: "total:	(statements)	72.5%".
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) == 0 || fields[0] != "total:" {
+		return 0, fmt.Errorf("unexpected coverage report: %s", lines[len(lines)-1])
+	}
+	return strconv.ParseFloat(strings.TrimSuffix(fields[len(fields)-1], "%"), 64)
+}
+
+// coverageGate verifies that the test coverage of the working tree meets
+// t, comparing it with the coverage at previous, measured in a worktree,
+// for t.MaxDrop. The first release is not compared.
+func coverageGate(t coverageThresholds, previous version) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	coverage, err := testCoverage(dir)
+	if err != nil {
+		return err
+	}
+	if t.Min > 0 && coverage < t.Min {
+		return fmt.Errorf("test coverage %.1f%% is below the minimum of %.1f%%", coverage, t.Min)
+	}
+
+	if t.MaxDrop > 0 && previous != (version{}) {
+		var before float64
+		err := withModuleWorktree(previous.String(), func(dir string) (err error) {
+			before, err = testCoverage(dir)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to measure the coverage of %s: %w", previous, err)
+		}
+		if drop := before - coverage; drop > t.MaxDrop {
+			return fmt.Errorf("test coverage dropped by %.1f points since %s, from %.1f%% to %.1f%%, more than the maximum of %.1f", drop, previous, before, coverage, t.MaxDrop)
+		}
+		success("Test coverage", "coverage", fmt.Sprintf("%.1f%%", coverage), "previous", fmt.Sprintf("%.1f%%", before))
+		return nil
+	}
+	success("Test coverage", "coverage", fmt.Sprintf("%.1f%%", coverage))
+	return nil
+}
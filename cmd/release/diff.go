@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes.
+const diffContext = 3
+
+// unifiedDiff renders the changes from old to new of the file name as a
+// unified diff, empty if there are none.
+func unifiedDiff(name, old, new string) string {
+	if old == new {
+		return ""
+	}
+	a, b := splitLines(old), splitLines(new)
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- a/%s\n+++ b/%s\n", name, name)
+
+	// Group the operations into hunks of changes separated by more than
+	// twice the context.
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			} else if j-end > 2*diffContext {
+				break
+			}
+		}
+		end = min(end+diffContext+1, len(ops))
+
+		hunk := ops[start:end]
+		aStart, bStart := hunk[0].a+1, hunk[0].b+1
+		var aLen, bLen int
+		for _, op := range hunk {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, op := range hunk {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.text)
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-'), or added ('+').
+// a and b are its index in the old and new lines, or where it would be.
+type diffOp struct {
+	kind byte
+	text string
+	a, b int
+}
+
+// diffLines computes a minimal line diff from the longest common
+// subsequence of a and b, which is quadratic but fine for source files.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// printDiff prints a unified diff, colored on a terminal.
+func printDiff(diff string) {
+	color := colorEnabled() && logFormat == "text" && isTerminalWriter(messages)
+	for _, line := range splitLines(diff) {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			line = paint(color, colorDim, line)
+		case strings.HasPrefix(line, "+"):
+			line = paint(color, colorGreen, line)
+		case strings.HasPrefix(line, "-"):
+			line = paint(color, colorRed, line)
+		}
+		fmt.Fprintln(messages, line)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// dockerImageTags returns the references the release image is tagged with:
+// the full version plus, for stable releases, the major and minor
+// convenience tags and "latest".
+func dockerImageTags(image string, v version) []string {
+	refs := []string{fmt.Sprintf("%s:%s", image, versionNumber(v.String()))}
+	if v.Pre != "" {
+		return refs
+	}
+
+	return append(refs,
+		fmt.Sprintf("%s:%d.%d", image, v.Major, v.Minor),
+		fmt.Sprintf("%s:%d", image, v.Major),
+		image+":latest",
+	)
+}
+
+// buildAndPushImage builds dockerfile at tag and pushes it to the registry
+// under every tag of dockerImageTags. When DOCKER_USERNAME and
+// DOCKER_PASSWORD are set, the registry is logged in to first.
+func buildAndPushImage(tag string, v version, image, dockerfile string) error {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return fmt.Errorf("docker is not installed")
+	}
+
+	if err := dockerLogin(image); err != nil {
+		return err
+	}
+
+	refs := dockerImageTags(image, v)
+
+	return withWorktree(tag, func(dir string) error {
+		args := dockerBuildCommand(refs, v, dockerfile)
+		cmd := commandIn(dir, args[0], args[1:]...)
+		cmd.Stdout = messages
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to build image: %w", err)
+		}
+
+		for _, ref := range refs {
+			p := startProgress("Pushing image", "ref", ref)
+			output, err := execCommand(dockerPushCommand(ref)).CombinedOutput()
+			p.stop()
+			if err != nil {
+				return fmt.Errorf("failed to push %s: %v: %s", ref, err, output)
+			}
+			slog.Info("Pushed image", "ref", ref)
+		}
+		return nil
+	})
+}
+
+// dockerBuildCommand returns the command building dockerfile of version v
+// as the image refs.
+func dockerBuildCommand(refs []string, v version, dockerfile string) []string {
+	args := []string{"docker", "build", "-f", dockerfile, "--label", "org.opencontainers.image.version=" + v.String()}
+	for _, ref := range refs {
+		args = append(args, "-t", ref)
+	}
+	return append(args, ".")
+}
+
+// dockerPushCommand returns the command pushing the image ref.
+func dockerPushCommand(ref string) []string {
+	return []string{"docker", "push", ref}
+}
+
+func dockerLogin(image string) error {
+	user, password := os.Getenv("DOCKER_USERNAME"), os.Getenv("DOCKER_PASSWORD")
+	if user == "" || password == "" {
+		return nil
+	}
+
+	// Images without a registry host live on Docker Hub.
+	registry := ""
+	if host, _, ok := strings.Cut(image, "/"); ok && strings.ContainsAny(host, ".:") {
+		registry = host
+	}
+
+	args := []string{"login", "-u", user, "--password-stdin"}
+	if registry != "" {
+		args = append(args, registry)
+	}
+
+	cmd := newCommand("docker", args...)
+	cmd.Stdin = strings.NewReader(password)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to log in to registry: %v: %s", err, output)
+	}
+	return nil
+}
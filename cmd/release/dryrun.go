@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Forge operations whose API requests a dry run shows.
+const (
+	apiCreateRelease   = "create-release"
+	apiPublishRelease  = "publish-release"
+	apiOpenPullRequest = "open-pull-request"
+	apiAutoMerge       = "auto-merge"
+	apiCommentIssue    = "comment-issue"
+	apiLabelPR         = "label-pull-request"
+)
+
+// showCommand logs the command a dry run would run, quoted for the shell.
+// Secrets are redacted like in all output.
+func showCommand(args ...string) {
+	slog.Info("DRY RUN MODE - Would run", "command", shellQuote(args))
+}
+
+// showRequests logs the API requests of op that a dry run would send to the
+// forge of fc, for the release described by r. Values only known once the
+// run gets there are {placeholders}.
+func showRequests(fc forgeConfig, op string, r releaseRequest) {
+	f, err := newLinkForge(fc)
+	if err != nil {
+		slog.Warn("DRY RUN MODE - Cannot show the API requests", "err", err)
+		return
+	}
+	for _, req := range f.requests(op, r) {
+		slog.Info("DRY RUN MODE - Would call", "request", req)
+	}
+}
+
+// shellQuote renders args as a shell command line, quoting the arguments
+// that need it.
+func shellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && strings.Trim(a, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+%^") == "" {
+			quoted[i] = a
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
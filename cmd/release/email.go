@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// smtpTimeout bounds the exchange with the SMTP server of an email
+// notifier.
+const smtpTimeout = time.Minute
+
+// sendEmail sends an email with subject and body, in plain text, from from
+// to the addresses of to through the SMTP server of serverURL; see
+// notifyEmail.
+func sendEmail(serverURL, from string, to []string, subject, body string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP URL: %w", err)
+	}
+	if u.Scheme != "smtp" && u.Scheme != "smtps" {
+		return fmt.Errorf("invalid SMTP URL scheme %q, must be smtp or smtps", u.Scheme)
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+	var recipients []string
+	for _, addr := range to {
+		a, err := mail.ParseAddress(addr)
+		if err != nil {
+			return fmt.Errorf("invalid to address %q: %w", addr, err)
+		}
+		recipients = append(recipients, a.Address)
+	}
+	if len(recipients) == 0 {
+		return errors.New("no recipients")
+	}
+
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "587"
+		if u.Scheme == "smtps" {
+			port = "465"
+		}
+	}
+	d := net.Dialer{Timeout: smtpTimeout}
+	conn, err := d.DialContext(runCtx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(smtpTimeout))
+	if u.Scheme == "smtps" {
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && u.Scheme == "smtp" {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		// PlainAuth refuses to send the password unencrypted, except to
+		// localhost.
+		if err := c.Auth(smtp.PlainAuth("", u.User.Username(), password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(sender.Address); err != nil {
+		return err
+	}
+	for _, r := range recipients {
+		if err := c.Rcpt(r); err != nil {
+			return fmt.Errorf("recipient %s: %w", r, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(emailMessage(sender.String(), to, subject, body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// emailMessage returns the email with the headers and plain text body,
+// quoted-printable encoded.
+func emailMessage(from string, to []string, subject, body string) []byte {
+	var b bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	header("From", from)
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	qp.Close()
+	return b.Bytes()
+}
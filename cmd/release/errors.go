@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Errors of the release flow that callers branch on with errors.Is. The
+// failures of git and go commands wrap the *exec.ExitError.
+var (
+	// ErrTagExists is returned for a version tag that already exists at
+	// another commit than the one to tag.
+	ErrTagExists = errors.New("tag already exists")
+	// ErrDirtyWorktree is returned for a release that would commit files
+	// with uncommitted changes it did not make.
+	ErrDirtyWorktree = errors.New("worktree has uncommitted changes")
+	// ErrNoTags is returned when the repository has no version tags to
+	// start from.
+	ErrNoTags = errors.New("no version tags")
+	// ErrPushRejected is returned for a push the remote rejected, e.g. as
+	// not a fast-forward or by a protection rule.
+	ErrPushRejected = errors.New("push rejected by the remote")
+	// ErrSemverViolation is returned by -semver-gate for a patch or minor
+	// release with incompatible API changes.
+	ErrSemverViolation = errors.New("incompatible API changes")
+	// ErrBenchmarkRegression is returned by -bench for benchmarks that
+	// got slower than its threshold allows.
+	ErrBenchmarkRegression = errors.New("benchmarks regressed")
+)
+
+// runPush runs the git push command args, returning ErrPushRejected if the
+// remote rejected any of the refs.
+func runPush(args []string) error {
+	output, err := execCommand(args).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	output = bytes.TrimSpace(output)
+	if bytes.Contains(output, []byte("[rejected]")) || bytes.Contains(output, []byte("[remote rejected]")) {
+		return fmt.Errorf("%w: %s", ErrPushRejected, output)
+	}
+	return fmt.Errorf("%w: %s", err, output)
+}
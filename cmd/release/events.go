@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/raducristianpopa/test-go-pkg/v4/release"
+)
+
+// event is a point of progress of a release run, passed to the observers
+// of the run as it happens, e.g. to drive a UI or an audit system. It is
+// the event of the release package, so that a run of the command reports
+// the same events as one embedding the package.
+type event = release.Event
+
+// Types of the events of a release run.
+const (
+	eventStepStarted     = release.StepStarted
+	eventStepFinished    = release.StepFinished
+	eventVersionComputed = release.VersionComputed
+	eventTagPushed       = release.TagPushed
+)
+
+// observer is called with each event of a run, in order.
+type observer func(event)
+
+// observe adds o to the observers of the run.
+func (r *runResult) observe(o observer) {
+	r.observers = append(r.observers, o)
+}
+
+// emit passes e, stamped with the current time, to the observers of the
+// run.
+func (r *runResult) emit(e event) {
+	e.Time = time.Now().UTC()
+	for _, o := range r.observers {
+		o(e)
+	}
+}
+
+// eventLog returns an observer writing the events to w as JSON lines.
+func eventLog(w io.Writer) observer {
+	enc := json.NewEncoder(w)
+	return func(e event) {
+		_ = enc.Encode(e)
+	}
+}
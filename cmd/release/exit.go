@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Exit codes of the release tool, so that CI can branch on the class of a
+// failure. They are listed in the usage.
+const (
+	// exitFailure is any failure not covered below.
+	exitFailure = 1
+	// exitUsage is an invalid command line or config file; the flag
+	// package exits with it too.
+	exitUsage = 2
+	// exitPreflight is a failed precondition or gate before any change was
+	// made, such as the release branch, the CI checks, or the confirmation.
+	exitPreflight = 3
+	// exitGit is a failed git command.
+	exitGit = 4
+	// exitForge is a failed forge API call.
+	exitForge = 5
+	// exitPartial is a failure after the release was partly pushed, which
+	// the rollback command undoes.
+	exitPartial = 6
+	// exitIncomplete is a failure after the tag was pushed, such as of a
+	// notifier; the release is out, and the resume command completes it.
+	exitIncomplete = 7
+	// exitInterrupted is a run stopped by SIGINT or SIGTERM; shells report
+	// a process killed by SIGINT with the same code.
+	exitInterrupted = 130
+)
+
+// exitCodes describes the exit codes for the usage, in order.
+var exitCodes = []struct {
+	code int
+	desc string
+}{
+	{exitFailure, "Failure not covered below"},
+	{exitUsage, "Invalid command line or config file"},
+	{exitPreflight, "Precondition or gate failed before any change, e.g. branch, CI checks, or confirmation"},
+	{exitGit, "git command failed"},
+	{exitForge, "Forge API call failed"},
+	{exitPartial, "Release partly pushed; undo it with the rollback command"},
+	{exitIncomplete, "Release published, but a later step failed; finish it with the resume command"},
+	{exitInterrupted, "Interrupted by SIGINT or SIGTERM; the release was cleaned up unless partly pushed"},
+}
+
+// printExitCodes prints the descriptions of codes, or of all exit codes if
+// codes is empty.
+func printExitCodes(codes []int) {
+	fmt.Printf("  0  Success\n")
+	for _, e := range exitCodes {
+		if len(codes) == 0 || slices.Contains(codes, e.code) {
+			fmt.Printf("  %d  %s\n", e.code, e.desc)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// feedFile is the name of the Atom feed of the releases, attached to a
+// release by -feed.
+const feedFile = "releases.atom"
+
+// defaultFeedEntries is how many releases the feed lists by default.
+const defaultFeedEntries = 20
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Link    *atomLink   `xml:"link,omitempty"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    *atomLink   `xml:"link,omitempty"`
+	Content atomContent `xml:"content"`
+}
+
+// atomContent is the release notes of an entry, in Markdown, which feed
+// readers show as text.
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// releaseFeed returns the Atom feed of the latest entries version tags,
+// newest first, with their release notes in the builtin format. The
+// date of a release is the date of its tag.
+func releaseFeed(fc forgeConfig, entries int) ([]byte, error) {
+	output, err := newCommand("git", "for-each-ref", "--format=%(refname:short) %(creatordate:iso-strict)", "refs/tags").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	type release struct {
+		tag  string
+		v    version
+		date time.Time
+	}
+	var releases []release
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		tag, date, _ := strings.Cut(line, " ")
+		v, err := parseVersion(tag)
+		if err != nil || v.String() != tag {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, date)
+		releases = append(releases, release{tag, v, t})
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[j].v.less(releases[i].v) })
+
+	module := "module"
+	if out, err := newCommand("go", "list", "-m").Output(); err == nil {
+		module = strings.TrimSpace(string(out))
+	}
+	// Links need the forge's URLs but no API access; without them, the
+	// feed has none.
+	f, err := newLinkForge(fc)
+	if err != nil {
+		f = nil
+	}
+	feed := atomFeed{
+		Title:  module + " releases",
+		ID:     "urn:go-module:" + module,
+		Author: atomAuthor{Name: module},
+	}
+	if remote, err := parseRemote(remoteName); err == nil {
+		feed.ID = remote.WebURL()
+		feed.Link = &atomLink{Href: remote.WebURL()}
+	}
+
+	for i, r := range releases {
+		if i == entries {
+			break
+		}
+		previousTag := ""
+		if i+1 < len(releases) {
+			previousTag = releases[i+1].tag
+		}
+		notes, err := releaseNotes(f, releaseRequest{Tag: r.tag, PreviousTag: previousTag})
+		if err != nil {
+			return nil, err
+		}
+		e := atomEntry{
+			Title:   module + " " + r.tag,
+			ID:      feed.ID + "#" + r.tag,
+			Updated: r.date.UTC().Format(time.RFC3339),
+			Content: atomContent{Type: "text", Body: notes},
+		}
+		if f != nil && previousTag != "" {
+			e.Link = &atomLink{Href: f.compareURL(previousTag, r.tag)}
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+	feed.Updated = time.Now().UTC().Format(time.RFC3339)
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// writeFeed writes the feed of the releases to feedFile in dist and
+// returns it as an artifact.
+func writeFeed(fc forgeConfig, dist string) (artifact, error) {
+	data, err := releaseFeed(fc, defaultFeedEntries)
+	if err != nil {
+		return artifact{}, err
+	}
+	if err := os.MkdirAll(dist, 0o755); err != nil {
+		return artifact{}, err
+	}
+	path := filepath.Join(dist, feedFile)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return artifact{}, err
+	}
+	slog.Info("Wrote the release feed", "file", path)
+	return artifact{Path: path, Name: feedFile}, nil
+}
+
+// runFeed prints the Atom feed of the releases, e.g. to publish it with
+// the project's pages.
+func runFeed(fs *flag.FlagSet) func() {
+	var (
+		out     = fs.String("o", "", "Write the feed to this file instead of stdout")
+		entries = fs.Int("entries", defaultFeedEntries, "Number of releases in the feed, newest first")
+	)
+
+	return func() {
+		if *entries <= 0 {
+			slog.Error("Invalid -entries, must be 1 or more", "entries", *entries)
+			os.Exit(exitUsage)
+		}
+		data, err := releaseFeed(forgeFlags, *entries)
+		if err != nil {
+			slog.Error("Failed to render the feed", "err", err)
+			os.Exit(exitGit)
+		}
+		if *out == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(*out, data, 0o644); err != nil {
+			slog.Error("Failed to write the feed", "err", err)
+			os.Exit(exitFailure)
+		}
+		success("Wrote the release feed", "file", *out)
+	}
+}
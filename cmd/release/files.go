@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// defaultRequiredFiles are the files -files-gate requires by default:
+// published modules need a license to be usable, and a README and a
+// security policy to be maintained in the open.
+const defaultRequiredFiles = "LICENSE*,go.mod,README*,SECURITY.md"
+
+// filesGate verifies that HEAD has, in the current directory, a non-empty
+// file matching each of patterns. Patterns are path.Match patterns
+// matched regardless of case, e.g. "README*" matches "Readme.md".
+func filesGate(patterns []string) error {
+	output, err := newCommand("git", "ls-tree", "-l", "HEAD").Output()
+	if err != nil {
+		return fmt.Errorf("failed to list the files of HEAD: %w", err)
+	}
+	// Sizes of the files by name; directories have none.
+	sizes := make(map[string]int64)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		info, name, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 4 || fields[1] != "blob" {
+			continue
+		}
+		sizes[name], _ = strconv.ParseInt(fields[3], 10, 64)
+	}
+
+	var problems []string
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid required file pattern %q: %w", pattern, err)
+		}
+		found, empty := false, ""
+		for name, size := range sizes {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name)); !ok {
+				continue
+			}
+			if size > 0 {
+				found = true
+				break
+			}
+			empty = name
+		}
+		switch {
+		case found:
+		case empty != "":
+			problems = append(problems, empty+" is empty")
+		default:
+			problems = append(problems, pattern+" is missing")
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("required files: %s", strings.Join(problems, ", "))
+	}
+	success("All required files are present", "files", strings.Join(patterns, ", "))
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chdirRepo makes the current directory a new git repository with a
+// commit of files, by name and content, for the test.
+func chdirRepo(t *testing.T, files map[string]string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "."}, {"commit", "-q", "--allow-empty", "-m", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestFilesGate(t *testing.T) {
+	patterns := splitList(defaultRequiredFiles)
+	tests := []struct {
+		name    string
+		files   map[string]string
+		problem string // in the error; empty for none
+	}{
+		{
+			name:  "all present",
+			files: map[string]string{"LICENSE": "MIT", "go.mod": "module m", "README.md": "# m", "SECURITY.md": "Report"},
+		},
+		{
+			name:  "case-insensitive",
+			files: map[string]string{"License.txt": "MIT", "go.mod": "module m", "Readme.md": "# m", "security.md": "Report"},
+		},
+		{
+			name:    "missing",
+			files:   map[string]string{"LICENSE": "MIT", "go.mod": "module m", "README.md": "# m"},
+			problem: "SECURITY.md is missing",
+		},
+		{
+			name:    "empty",
+			files:   map[string]string{"LICENSE": "", "go.mod": "module m", "README.md": "# m", "SECURITY.md": "Report"},
+			problem: "LICENSE is empty",
+		},
+		{
+			name:    "in a subdirectory only",
+			files:   map[string]string{"docs/LICENSE": "MIT", "go.mod": "module m", "README.md": "# m", "SECURITY.md": "Report"},
+			problem: "LICENSE* is missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdirRepo(t, tt.files)
+			err := filesGate(patterns)
+			switch {
+			case tt.problem == "" && err != nil:
+				t.Errorf("filesGate error: %v", err)
+			case tt.problem != "" && (err == nil || !strings.Contains(err.Error(), tt.problem)):
+				t.Errorf("filesGate error = %v, want %q", err, tt.problem)
+			}
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		chdirRepo(t, map[string]string{"go.mod": "module m"})
+		if err := filesGate([]string{"[README"}); err == nil {
+			t.Error("filesGate succeeded with an invalid pattern")
+		}
+	})
+}
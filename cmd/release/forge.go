@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// forge is a code hosting service the release flow publishes releases to.
+type forge interface {
+	name() string
+	// createRelease creates a release for an already pushed tag and returns
+	// its web URL.
+	createRelease(r releaseRequest) (string, error)
+	// publishRelease publishes the draft release of tag. latest is "true"
+	// or "false" to mark it as the latest release or not, or empty for the
+	// forge's default.
+	publishRelease(tag, latest string) (string, error)
+	pullRequestURL(number int) string
+	issueURL(number int) string
+	// compareURL links to the diff between two refs in the web UI.
+	compareURL(from, to string) string
+	// checkStatuses lists the CI checks and commit statuses of commit.
+	checkStatuses(commit string) ([]checkStatus, error)
+	// openPullRequest opens a pull request merging head into base and
+	// returns its number and web URL.
+	openPullRequest(head, base, title, body string) (int, string, error)
+	// requests returns the API requests making changes that op sends for
+	// the release described by r, such as "POST <url>", for dry runs to
+	// show. Values only known when they are sent are {placeholders}.
+	requests(op string, r releaseRequest) []string
+}
+
+// tagLister is implemented by forges that can list the repository's tags.
+type tagLister interface {
+	listTags() ([]string, error)
+}
+
+// maxTagPages bounds how many pages of tags are fetched from a forge.
+const maxTagPages = 20
+
+// releaseRequest describes a release to create on a forge.
+type releaseRequest struct {
+	Tag string
+	// PreviousTag is the tag the release notes are computed from; it does
+	// not exist for the first release.
+	PreviousTag string
+	Draft       bool
+	// Prerelease marks the release as a prerelease, which is never marked
+	// as the latest release.
+	Prerelease bool
+	// Notes are prepended to the release notes computed by the forge or
+	// from the commit log.
+	Notes string
+	// NotesMode selects how the release notes are produced: notesBuiltin,
+	// notesGitHub, or empty for the forge's default.
+	NotesMode string
+	// Assets are uploaded to the release once it is created.
+	Assets []artifact
+	// DiscussionCategory, if set, opens a discussion for the release in
+	// that category. Only GitHub supports discussions.
+	DiscussionCategory string
+}
+
+// assetUploader is implemented by forges that can attach files to a release.
+type assetUploader interface {
+	uploadAsset(tag string, a artifact) error
+	// assetURL returns the download URL of the asset name uploaded to the
+	// release of tag.
+	assetURL(tag, name string) string
+}
+
+// multipartFile encodes the file at path as a multipart form with a single
+// file field, returning the body and its content type.
+func multipartFile(field, name, path string) ([]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	part, err := w.CreateFormFile(field, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return b.Bytes(), w.FormDataContentType(), nil
+}
+
+// remoteInfo describes the repository behind a git remote.
+type remoteInfo struct {
+	Host string // e.g. "github.com"
+	Repo string // "owner/name"
+}
+
+// WebURL is the browsable URL of the repository.
+func (r remoteInfo) WebURL() string {
+	return "https://" + r.Host + "/" + r.Repo
+}
+
+// forgeConfig selects and configures the forge client.
+type forgeConfig struct {
+	// Kind is the forge type; empty or "auto" selects it from the host of
+	// the release remote.
+	Kind string
+	// Token is an explicit API token, taking precedence over the
+	// environment.
+	Token string
+	// APIURL overrides the API base URL, e.g. for GitHub Enterprise
+	// ("https://ghe.example.com/api/v3").
+	APIURL string
+	// CACert is a PEM bundle of additional CAs trusted for API calls.
+	CACert string
+	// ClientCert and ClientKey are a PEM client certificate and key for
+	// instances requiring mutual TLS.
+	ClientCert string
+	ClientKey  string
+}
+
+// registerForgeFlags defines the flags configuring forge access on fs,
+// storing them in fc. The current values of fc are the defaults.
+func registerForgeFlags(fs *flag.FlagSet, fc *forgeConfig) {
+	fs.StringVar(&fc.Kind, "forge", fc.Kind, "Forge hosting the repository: auto, github, gitea (also used for Forgejo), or bitbucket")
+	fs.StringVar(&fc.Token, "token", fc.Token, "Forge API token (default: $GITHUB_TOKEN, $GH_TOKEN, or 'gh auth token' for GitHub)")
+	fs.StringVar(&fc.APIURL, "api-url", fc.APIURL, "Forge API base URL, e.g. https://ghe.example.com/api/v3 (default: derived from the remote); with -forge=auto, a host of no known forge is taken for GitHub Enterprise")
+	fs.StringVar(&fc.CACert, "ca-cert", fc.CACert, "PEM file with additional CA certificates trusted for forge API calls")
+	fs.StringVar(&fc.ClientCert, "client-cert", fc.ClientCert, "PEM client certificate for forge API calls requiring mutual TLS")
+	fs.StringVar(&fc.ClientKey, "client-key", fc.ClientKey, "PEM private key for -client-cert")
+}
+
+// clientOptions are the resolved settings a forge client is built from.
+type clientOptions struct {
+	Token  string
+	APIURL string
+	HTTP   *http.Client
+}
+
+// newForge returns the forge client described by fc.
+func newForge(fc forgeConfig) (forge, error) {
+	return openForge(fc, true)
+}
+
+// newLinkForge returns the forge client described by fc for building links
+// only; unlike newForge it does not require an API token.
+func newLinkForge(fc forgeConfig) (forge, error) {
+	return openForge(fc, false)
+}
+
+func openForge(fc forgeConfig, needToken bool) (forge, error) {
+	remote, err := parseRemote(remoteName)
+	if err != nil {
+		return nil, err
+	}
+
+	hc, err := fc.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	kind := fc.Kind
+	if kind == "" || kind == "auto" {
+		kind = fc.detectForge(remote.Host)
+	}
+
+	var (
+		envs       []string
+		ghFallback bool
+	)
+	switch kind {
+	case "github":
+		envs, ghFallback = []string{"GITHUB_TOKEN", "GH_TOKEN"}, true
+	case "gitea", "forgejo":
+		envs = []string{"GITEA_TOKEN"}
+	case "bitbucket":
+		envs = []string{"BITBUCKET_TOKEN"}
+	default:
+		return nil, fmt.Errorf("unsupported forge %q for host %s (use -forge to select one)", kind, remote.Host)
+	}
+
+	token, err := resolveToken(fc.Token, envs, ghFallback)
+	if err != nil && needToken {
+		return nil, err
+	}
+
+	opts := clientOptions{Token: token, APIURL: fc.APIURL, HTTP: hc}
+	switch kind {
+	case "github":
+		return newGitHubClient(remote, opts), nil
+	case "gitea", "forgejo":
+		return newGiteaClient(remote, opts), nil
+	default:
+		return newBitbucketClient(remote, opts), nil
+	}
+}
+
+// httpClient builds the HTTP client for API calls, applying the custom CA
+// bundle and client certificate if configured.
+func (fc forgeConfig) httpClient() (*http.Client, error) {
+	hc := &http.Client{Timeout: 30 * time.Second}
+	if fc.CACert == "" && fc.ClientCert == "" {
+		return hc, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if fc.CACert != "" {
+		pem, err := os.ReadFile(fc.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", fc.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if fc.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(fc.ClientCert, fc.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	hc.Transport = transport
+	return hc, nil
+}
+
+// detectForge returns the forge hosting host. Unknown hosts given an API URL
+// are taken for GitHub Enterprise, the self-hosted forge with the most
+// varied host names.
+func (fc forgeConfig) detectForge(host string) string {
+	switch {
+	case host == "github.com":
+		return "github"
+	case host == "bitbucket.org":
+		return "bitbucket"
+	case host == "codeberg.org", strings.Contains(host, "gitea"), strings.Contains(host, "forgejo"):
+		return "gitea"
+	case fc.APIURL != "":
+		return "github"
+	default:
+		return ""
+	}
+}
+
+// parseRemote derives the host and "owner/name" from the URL of the given git
+// remote. Both SCP-like (git@host:owner/name.git) and URL forms are accepted.
+func parseRemote(remote string) (remoteInfo, error) {
+	cmd := newCommand("git", "remote", "get-url", remote)
+	output, err := cmd.Output()
+	if err != nil {
+		return remoteInfo{}, fmt.Errorf("failed to get URL of remote %s: %w", remote, err)
+	}
+
+	url := strings.TrimSpace(string(output))
+	re := regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?([^/:]+)(?::\d+)?[:/](.+?)(?:\.git)?/?$`)
+	matches := re.FindStringSubmatch(url)
+	if len(matches) != 3 || strings.Count(matches[2], "/") < 1 {
+		return remoteInfo{}, fmt.Errorf("could not determine repository from remote URL: %s", url)
+	}
+
+	return remoteInfo{Host: matches[1], Repo: matches[2]}, nil
+}
+
+// restClient performs JSON requests against a forge REST API.
+type restClient struct {
+	baseURL string
+	headers map[string]string
+	http    *http.Client
+}
+
+func newRESTClient(baseURL string, headers map[string]string, hc *http.Client) restClient {
+	return restClient{
+		baseURL: baseURL,
+		headers: headers,
+		http:    hc,
+	}
+}
+
+// maxAPIAttempts bounds how often a forge request is retried after being
+// rate limited or hitting a server error.
+const maxAPIAttempts = 5
+
+// maxRateLimitWait caps how long a request waits for a rate limit to reset
+// before giving up.
+const maxRateLimitWait = 15 * time.Minute
+
+func (c *restClient) do(method, path string, in, out any) error {
+	return c.doURL(method, c.baseURL+path, in, out)
+}
+
+// doURL is like do but takes an absolute URL, for endpoints outside the
+// REST base URL.
+func (c *restClient) doURL(method, url string, in, out any) error {
+	if in == nil {
+		return c.send(method, url, "", nil, out)
+	}
+
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+	return c.send(method, url, "application/json", payload, out)
+}
+
+// send performs a request with a raw payload of the given content type,
+// decoding a JSON response into out. Rate-limited requests are retried,
+// and so are idempotent ones failing with a server error; see retryAfter.
+func (c *restClient) send(method, url, contentType string, payload []byte, out any) error {
+	for attempt := 1; ; attempt++ {
+		var body io.Reader
+		if payload != nil {
+			body = bytes.NewReader(payload)
+		}
+
+		req, err := http.NewRequestWithContext(runCtx, method, url, body)
+		if err != nil {
+			return err
+		}
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		slog.Log(context.Background(), levelTrace, "API request", "method", method, "url", url)
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", method, url, err)
+		}
+
+		logRateLimit(resp)
+
+		if wait, retry := retryAfter(resp, method, attempt); retry && attempt < maxAPIAttempts {
+			resp.Body.Close()
+			slog.Warn("API request failed, retrying", "method", method, "url", url, "status", resp.Status, "wait", wait)
+			if err := sleep(wait); err != nil {
+				return fmt.Errorf("%s %s: %w", method, url, err)
+			}
+			continue
+		}
+
+		err = decodeResponse(resp, out)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", method, url, err)
+		}
+		return nil
+	}
+}
+
+// apiError is returned for forge API responses with a non-2xx status.
+type apiError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *apiError) Error() string {
+	return e.Status + ": " + e.Body
+}
+
+// isStatus reports whether err is an API error with the given status code.
+func isStatus(err error, code int) bool {
+	var ae *apiError
+	return errors.As(err, &ae) && ae.StatusCode == code
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &apiError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(bytes.TrimSpace(msg)),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// retryAfter decides whether a response to a method request is worth
+// retrying and how long to wait first. Rate-limited responses (429, or 403
+// with an exhausted quota) wait for Retry-After or the quota reset; server
+// errors back off exponentially. The server may have acted on a request
+// failing with a server error, so only idempotent ones are retried then:
+// retrying a POST could create a release or comment twice.
+func retryAfter(resp *http.Response, method string, attempt int) (time.Duration, bool) {
+	backoff := time.Duration(1<<uint(attempt-1)) * time.Second
+
+	rateLimited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden &&
+			(resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"))
+
+	switch {
+	case rateLimited:
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait := time.Until(time.Unix(reset, 0)) + time.Second
+			if wait > maxRateLimitWait {
+				return 0, false
+			}
+			if wait > 0 {
+				return wait, true
+			}
+		}
+		return backoff, true
+	case resp.StatusCode >= 500 && idempotent(method):
+		return backoff, true
+	default:
+		return 0, false
+	}
+}
+
+// idempotent reports whether repeating a method request has the same effect
+// as making it once.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func logRateLimit(resp *http.Response) {
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	if remaining == "" {
+		return
+	}
+
+	slog.Debug("API quota", "remaining", remaining, "limit", resp.Header.Get("X-RateLimit-Limit"))
+}
+
+// commitRange is the git revision range of the commits released in tag.
+func commitRange(tag, previousTag string) string {
+	if tagExists(previousTag) {
+		return previousTag + ".." + tag
+	}
+	return tag
+}
+
+// releasedCommits lists the commits released in tag, newest first.
+func releasedCommits(tag, previousTag string) ([]string, error) {
+	rng := commitRange(tag, previousTag)
+	cmd := newCommand("git", "rev-list", rng)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits for %s: %w", rng, err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+func tagCommit(tag string) (string, error) {
+	cmd := newCommand("git", "rev-list", "-n", "1", tag)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve commit of tag %s: %w", tag, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func tagExists(tag string) bool {
+	cmd := newCommand("git", "rev-parse", "-q", "--verify", "refs/tags/"+tag)
+	return cmd.Run() == nil
+}
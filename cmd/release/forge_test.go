@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		method  string
+		attempt int
+		wait    time.Duration
+		retry   bool
+	}{
+		{name: "success", status: 200, method: "GET", attempt: 1},
+		{name: "not found", status: 404, method: "GET", attempt: 1},
+		{name: "server error", status: 502, method: "GET", attempt: 1, wait: time.Second, retry: true},
+		{name: "server error backs off", status: 503, method: "PUT", attempt: 3, wait: 4 * time.Second, retry: true},
+		{name: "server error of a POST", status: 502, method: "POST", attempt: 1},
+		{name: "server error of a PATCH", status: 500, method: "PATCH", attempt: 1},
+		{name: "too many requests", status: 429, method: "POST", attempt: 2, wait: 2 * time.Second, retry: true},
+		{name: "Retry-After", status: 429, header: map[string]string{"Retry-After": "7"}, method: "POST", attempt: 1, wait: 7 * time.Second, retry: true},
+		{name: "secondary rate limit", status: 403, header: map[string]string{"Retry-After": "60"}, method: "PATCH", attempt: 1, wait: time.Minute, retry: true},
+		{name: "exhausted quota resetting too late", status: 403, header: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}, method: "GET", attempt: 1},
+		{name: "forbidden", status: 403, method: "GET", attempt: 1},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: make(http.Header)}
+		for k, v := range tt.header {
+			resp.Header.Set(k, v)
+		}
+		wait, retry := retryAfter(resp, tt.method, tt.attempt)
+		if wait != tt.wait || retry != tt.retry {
+			t.Errorf("%s: retryAfter = %s, %t, want %s, %t", tt.name, wait, retry, tt.wait, tt.retry)
+		}
+	}
+}
+
+func TestRetryAfterQuotaReset(t *testing.T) {
+	resp := &http.Response{StatusCode: 403, Header: make(http.Header)}
+	resp.Header.Set("X-RateLimit-Remaining", "0")
+	resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+	wait, retry := retryAfter(resp, "GET", 1)
+	if !retry || wait < 50*time.Second || wait > 62*time.Second {
+		t.Errorf("retryAfter = %s, %t, want about a minute", wait, retry)
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int // of the responses, in order
+		requests int
+		wantErr  bool
+	}{
+		{"rate-limited POST", "POST", []int{429, 201}, 2, false},
+		{"failed POST", "POST", []int{502, 201}, 1, true},
+		{"failed GET", "GET", []int{502, 200}, 2, false},
+		{"client error", "GET", []int{422, 200}, 1, true},
+	}
+	for _, tt := range tests {
+		requests := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := tt.statuses[requests]
+			requests++
+			if status == 429 {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(status)
+		}))
+		c := newRESTClient(srv.URL, nil, srv.Client())
+		err := c.do(tt.method, "/", nil, nil)
+		srv.Close()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %t", tt.name, err, tt.wantErr)
+		}
+		if requests != tt.requests {
+			t.Errorf("%s: sent %d requests, want %d", tt.name, requests, tt.requests)
+		}
+	}
+}
+
+func TestDetectForge(t *testing.T) {
+	tests := []struct {
+		host, apiURL, want string
+	}{
+		{"github.com", "", "github"},
+		{"bitbucket.org", "", "bitbucket"},
+		{"codeberg.org", "", "gitea"},
+		{"gitea.example.com", "", "gitea"},
+		{"git.example.com", "", ""},
+		{"git.example.com", "https://git.example.com/api/v3", "github"},
+	}
+	for _, tt := range tests {
+		if got := (forgeConfig{APIURL: tt.apiURL}).detectForge(tt.host); got != tt.want {
+			t.Errorf("detectForge(%q) with API URL %q = %q, want %q", tt.host, tt.apiURL, got, tt.want)
+		}
+	}
+}
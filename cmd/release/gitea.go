@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// giteaClient talks to the Gitea REST API, which Forgejo implements as well.
+type giteaClient struct {
+	restClient
+	remote remoteInfo
+}
+
+type giteaRelease struct {
+	ID         int64  `json:"id,omitempty"`
+	TagName    string `json:"tag_name,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Body       string `json:"body,omitempty"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	HTMLURL    string `json:"html_url,omitempty"`
+}
+
+func newGiteaClient(remote remoteInfo, opts clientOptions) *giteaClient {
+	apiURL := opts.APIURL
+	if apiURL == "" {
+		apiURL = remote.WebURL() + "/api/v1"
+	}
+
+	return &giteaClient{
+		restClient: newRESTClient(strings.TrimSuffix(apiURL, "/"), map[string]string{
+			"Accept":        "application/json",
+			"Authorization": "token " + opts.Token,
+		}, opts.HTTP),
+		remote: remote,
+	}
+}
+
+func (c *giteaClient) name() string {
+	return "Gitea"
+}
+
+func (c *giteaClient) pullRequestURL(number int) string {
+	return fmt.Sprintf("%s/pulls/%d", c.remote.WebURL(), number)
+}
+
+func (c *giteaClient) issueURL(number int) string {
+	return fmt.Sprintf("%s/issues/%d", c.remote.WebURL(), number)
+}
+
+func (c *giteaClient) compareURL(from, to string) string {
+	return fmt.Sprintf("%s/compare/%s...%s", c.remote.WebURL(), from, to)
+}
+
+// createRelease creates a Gitea release. Gitea cannot generate release notes,
+// so the body is built from the commits since the previous tag.
+func (c *giteaClient) createRelease(r releaseRequest) (string, error) {
+	if r.DiscussionCategory != "" {
+		slog.Warn("Discussions are not supported, skipping", "forge", c.name())
+	}
+	if r.NotesMode == notesGitHub {
+		return "", fmt.Errorf("GitHub-generated release notes are not available on %s", c.name())
+	}
+
+	body, err := releaseNotes(c, r)
+	if err != nil {
+		return "", err
+	}
+
+	in := giteaRelease{
+		TagName:    r.Tag,
+		Name:       r.Tag,
+		Body:       body,
+		Draft:      r.Draft,
+		Prerelease: r.Prerelease,
+	}
+
+	var out giteaRelease
+	if err := c.do(http.MethodPost, "/repos/"+c.remote.Repo+"/releases", in, &out); err != nil {
+		return "", err
+	}
+	return out.HTMLURL, nil
+}
+
+func (c *giteaClient) findRelease(tag string) (*giteaRelease, error) {
+	var releases []giteaRelease
+	if err := c.do(http.MethodGet, "/repos/"+c.remote.Repo+"/releases?limit=50", nil, &releases); err != nil {
+		return nil, err
+	}
+
+	for i := range releases {
+		if releases[i].TagName == tag {
+			return &releases[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no release found for tag %s", tag)
+}
+
+// publishRelease flips a draft release to published. Gitea has no notion of
+// a "latest" flag; the most recent published release is always the latest.
+func (c *giteaClient) publishRelease(tag, _ string) (string, error) {
+	release, err := c.findRelease(tag)
+	if err != nil {
+		return "", err
+	}
+
+	if !release.Draft {
+		slog.Info("Release is already published", "tag", tag)
+		return release.HTMLURL, nil
+	}
+
+	in := map[string]any{"draft": false}
+
+	var out giteaRelease
+	path := fmt.Sprintf("/repos/%s/releases/%d", c.remote.Repo, release.ID)
+	if err := c.do(http.MethodPatch, path, in, &out); err != nil {
+		return "", err
+	}
+	return out.HTMLURL, nil
+}
+
+func (c *giteaClient) checkStatuses(commit string) ([]checkStatus, error) {
+	var combined struct {
+		Statuses []struct {
+			Context string `json:"context"`
+			Status  string `json:"status"`
+		} `json:"statuses"`
+	}
+	path := fmt.Sprintf("/repos/%s/commits/%s/status", c.remote.Repo, commit)
+	if err := c.do(http.MethodGet, path, nil, &combined); err != nil {
+		return nil, err
+	}
+
+	var statuses []checkStatus
+	for _, s := range combined.Statuses {
+		statuses = append(statuses, checkStatus{Name: s.Context, State: normalizeStatus(s.Status)})
+	}
+	return statuses, nil
+}
+
+func (c *giteaClient) openPullRequest(head, base, title, body string) (int, string, error) {
+	in := map[string]string{
+		"head":  head,
+		"base":  base,
+		"title": title,
+		"body":  body,
+	}
+
+	var out struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := c.do(http.MethodPost, "/repos/"+c.remote.Repo+"/pulls", in, &out); err != nil {
+		return 0, "", err
+	}
+	return out.Number, out.HTMLURL, nil
+}
+
+// enableAutoMerge schedules the pull request to be merged once its checks
+// succeed.
+func (c *giteaClient) enableAutoMerge(number int) error {
+	in := map[string]any{
+		"Do":                        "merge",
+		"merge_when_checks_succeed": true,
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d/merge", c.remote.Repo, number)
+	return c.do(http.MethodPost, path, in, nil)
+}
+
+func (c *giteaClient) mergeCommit(number int) (string, bool, error) {
+	var pr struct {
+		State          string `json:"state"`
+		Merged         bool   `json:"merged"`
+		MergeCommitSHA string `json:"merge_commit_sha"`
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d", c.remote.Repo, number)
+	if err := c.do(http.MethodGet, path, nil, &pr); err != nil {
+		return "", false, err
+	}
+
+	if pr.Merged {
+		return pr.MergeCommitSHA, true, nil
+	}
+	if pr.State == "closed" {
+		return "", false, fmt.Errorf("pull request #%d was closed without being merged", number)
+	}
+	return "", false, nil
+}
+
+func (c *giteaClient) openMilestones() ([]milestone, error) {
+	var out []struct {
+		ID    int    `json:"id"`
+		Title string `json:"title"`
+	}
+	if err := c.do(http.MethodGet, "/repos/"+c.remote.Repo+"/milestones?state=open&limit=50", nil, &out); err != nil {
+		return nil, err
+	}
+
+	milestones := make([]milestone, 0, len(out))
+	for _, m := range out {
+		url := fmt.Sprintf("%s/milestone/%d", c.remote.WebURL(), m.ID)
+		milestones = append(milestones, milestone{ID: m.ID, Title: m.Title, URL: url})
+	}
+	return milestones, nil
+}
+
+func (c *giteaClient) closeMilestone(m *milestone) error {
+	path := fmt.Sprintf("/repos/%s/milestones/%d", c.remote.Repo, m.ID)
+	return c.do(http.MethodPatch, path, map[string]string{"state": "closed"}, nil)
+}
+
+func (c *giteaClient) listTags() ([]string, error) {
+	var tags []string
+	for page := 1; page <= maxTagPages; page++ {
+		var out []struct {
+			Name string `json:"name"`
+		}
+		path := fmt.Sprintf("/repos/%s/tags?limit=50&page=%d", c.remote.Repo, page)
+		if err := c.do(http.MethodGet, path, nil, &out); err != nil {
+			return nil, err
+		}
+		for _, t := range out {
+			tags = append(tags, t.Name)
+		}
+		if len(out) < 50 {
+			break
+		}
+	}
+	return tags, nil
+}
+
+func (c *giteaClient) commentOnIssue(number int, body string) error {
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", c.remote.Repo, number)
+	return c.do(http.MethodPost, path, map[string]string{"body": body}, nil)
+}
+
+func (c *giteaClient) requests(op string, r releaseRequest) []string {
+	repo := c.baseURL + "/repos/" + c.remote.Repo
+	switch op {
+	case apiCreateRelease:
+		reqs := []string{"POST " + repo + "/releases"}
+		for _, a := range r.Assets {
+			reqs = append(reqs, "POST "+repo+"/releases/{id}/assets?name="+url.QueryEscape(a.Name))
+		}
+		return reqs
+	case apiPublishRelease:
+		return []string{"PATCH " + repo + "/releases/{id}"}
+	case apiOpenPullRequest:
+		return []string{"POST " + repo + "/pulls"}
+	case apiAutoMerge:
+		return []string{"POST " + repo + "/pulls/{number}/merge"}
+	case apiCommentIssue:
+		return []string{"POST " + repo + "/issues/{number}/comments"}
+	}
+	return nil
+}
+
+func (c *giteaClient) assetURL(tag, name string) string {
+	return fmt.Sprintf("%s/releases/download/%s/%s", c.remote.WebURL(), url.PathEscape(tag), url.PathEscape(name))
+}
+
+func (c *giteaClient) uploadAsset(tag string, a artifact) error {
+	release, err := c.findRelease(tag)
+	if err != nil {
+		return err
+	}
+
+	body, contentType, err := multipartFile("attachment", a.Name, a.Path)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/repos/%s/releases/%d/assets?name=%s", c.remote.Repo, release.ID, url.QueryEscape(a.Name))
+	return c.send(http.MethodPost, c.baseURL+path, contentType, body, nil)
+}
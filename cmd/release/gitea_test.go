@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// newTestGitea returns a client of repository o/r on a server with handler.
+func newTestGitea(t *testing.T, handler http.HandlerFunc) *giteaClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return newGiteaClient(remoteInfo{Host: "gitea.example.com", Repo: "o/r"}, clientOptions{Token: "t0ken", APIURL: srv.URL + "/api/v1", HTTP: srv.Client()})
+}
+
+func TestGiteaPublishRelease(t *testing.T) {
+	var patched map[string]any
+	c := newTestGitea(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token t0ken" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/repos/o/r/releases":
+			fmt.Fprint(w, `[{"id": 3, "tag_name": "v1.1.0", "draft": true}, {"id": 2, "tag_name": "v1.0.0", "html_url": "https://gitea.example.com/o/r/releases/tag/v1.0.0"}]`)
+		case r.Method == "PATCH" && r.URL.Path == "/api/v1/repos/o/r/releases/3":
+			json.NewDecoder(r.Body).Decode(&patched)
+			fmt.Fprint(w, `{"id": 3, "html_url": "https://gitea.example.com/o/r/releases/tag/v1.1.0"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	url, err := c.publishRelease("v1.1.0", "true")
+	if err != nil || url != "https://gitea.example.com/o/r/releases/tag/v1.1.0" {
+		t.Errorf("publishRelease = %q, %v", url, err)
+	}
+	// Gitea has no latest flag to send.
+	if len(patched) != 1 || patched["draft"] != false {
+		t.Errorf("request = %v, want only draft: false", patched)
+	}
+
+	patched = nil
+	url, err = c.publishRelease("v1.0.0", "")
+	if err != nil || url != "https://gitea.example.com/o/r/releases/tag/v1.0.0" || patched != nil {
+		t.Errorf("publishRelease of a published release = %q, %v, sending %v", url, err, patched)
+	}
+}
+
+func TestGiteaOpenPullRequest(t *testing.T) {
+	c := newTestGitea(t, func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		if r.Method != "POST" || r.URL.Path != "/api/v1/repos/o/r/pulls" || in["head"] != "release-v2" || in["base"] != "main" {
+			t.Errorf("unexpected request %s %s %v", r.Method, r.URL, in)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number": 7, "html_url": "https://gitea.example.com/o/r/pulls/7"}`)
+	})
+
+	number, url, err := c.openPullRequest("release-v2", "main", "Release v2", "")
+	if err != nil || number != 7 || url != "https://gitea.example.com/o/r/pulls/7" {
+		t.Errorf("openPullRequest = %d, %q, %v", number, url, err)
+	}
+}
+
+func TestGiteaAPIError(t *testing.T) {
+	c := newTestGitea(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"message": "pull request already exists"}`)
+	})
+
+	_, _, err := c.openPullRequest("release-v2", "main", "Release v2", "")
+	if !isStatus(err, http.StatusConflict) {
+		t.Errorf("openPullRequest error = %v, want a 409 API error", err)
+	}
+}
+
+func TestGiteaListTags(t *testing.T) {
+	c := newTestGitea(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/o/r/tags" || r.URL.Query().Get("limit") != "50" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		n := 50
+		if page == 3 {
+			n = 0
+		}
+		tags := make([]map[string]string, n)
+		for i := range tags {
+			tags[i] = map[string]string{"name": fmt.Sprintf("v%d.%d.0", page, i)}
+		}
+		json.NewEncoder(w).Encode(tags)
+	})
+
+	tags, err := c.listTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 100 || tags[99] != "v2.49.0" {
+		t.Errorf("listTags = %d tags ending with %q, want 100 ending with v2.49.0", len(tags), tags[len(tags)-1])
+	}
+}
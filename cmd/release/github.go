@@ -0,0 +1,455 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// githubClient is a minimal client for the GitHub REST API covering only the
+// endpoints needed by the release flow.
+type githubClient struct {
+	restClient
+	repo       string // "owner/name"
+	webURL     string
+	graphqlURL string
+}
+
+type githubRelease struct {
+	ID                   int64  `json:"id,omitempty"`
+	TagName              string `json:"tag_name,omitempty"`
+	Name                 string `json:"name,omitempty"`
+	Body                 string `json:"body,omitempty"`
+	Draft                bool   `json:"draft"`
+	Prerelease           bool   `json:"prerelease"`
+	MakeLatest           string `json:"make_latest,omitempty"`
+	GenerateReleaseNotes bool   `json:"generate_release_notes,omitempty"`
+	// DiscussionCategoryName opens a discussion linked to the release, with
+	// the release notes as its body.
+	DiscussionCategoryName string        `json:"discussion_category_name,omitempty"`
+	HTMLURL                string        `json:"html_url,omitempty"`
+	UploadURL              string        `json:"upload_url,omitempty"`
+	Assets                 []githubAsset `json:"assets,omitempty"`
+}
+
+type githubAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// newGitHubClient returns a client for github.com or, when the remote is on
+// another host, for the GitHub Enterprise Server instance on that host.
+func newGitHubClient(remote remoteInfo, opts clientOptions) *githubClient {
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if repo == "" {
+		repo = remote.Repo
+	}
+
+	apiURL := opts.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+		if remote.Host != "github.com" {
+			apiURL = "https://" + remote.Host + "/api/v3"
+		}
+	}
+	apiURL = strings.TrimSuffix(apiURL, "/")
+
+	// GitHub Enterprise serves GraphQL at /api/graphql next to /api/v3.
+	graphqlURL := apiURL + "/graphql"
+	if strings.HasSuffix(apiURL, "/api/v3") {
+		graphqlURL = strings.TrimSuffix(apiURL, "/v3") + "/graphql"
+	}
+
+	return &githubClient{
+		restClient: newRESTClient(apiURL, map[string]string{
+			"Accept":               "application/vnd.github+json",
+			"Authorization":        "Bearer " + opts.Token,
+			"X-GitHub-Api-Version": "2022-11-28",
+		}, opts.HTTP),
+		repo:       repo,
+		webURL:     "https://" + remote.Host + "/" + repo,
+		graphqlURL: graphqlURL,
+	}
+}
+
+func (c *githubClient) name() string {
+	return "GitHub"
+}
+
+func (c *githubClient) pullRequestURL(number int) string {
+	return fmt.Sprintf("%s/pull/%d", c.webURL, number)
+}
+
+func (c *githubClient) issueURL(number int) string {
+	return fmt.Sprintf("%s/issues/%d", c.webURL, number)
+}
+
+func (c *githubClient) compareURL(from, to string) string {
+	return fmt.Sprintf("%s/compare/%s...%s", c.webURL, from, to)
+}
+
+// createRelease creates a GitHub release for an already pushed tag. Unless
+// the built-in notes are requested, GitHub generates the notes. Draft
+// releases are not visible to users until they are published.
+func (c *githubClient) createRelease(r releaseRequest) (string, error) {
+	var body string
+	var err error
+	if r.NotesMode == notesBuiltin {
+		body, err = releaseNotes(c, r)
+	} else {
+		body, err = c.generatedNotes(r)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	in := githubRelease{
+		TagName:                r.Tag,
+		Name:                   r.Tag,
+		Body:                   body,
+		Draft:                  r.Draft,
+		Prerelease:             r.Prerelease,
+		DiscussionCategoryName: r.DiscussionCategory,
+	}
+	if r.Prerelease {
+		in.MakeLatest = "false"
+	}
+
+	var out githubRelease
+	if err := c.do(http.MethodPost, "/repos/"+c.repo+"/releases", in, &out); err != nil {
+		return "", err
+	}
+	return out.HTMLURL, nil
+}
+
+// generatedNotes asks GitHub to generate the notes for the release and
+// prepends the breaking changes, which GitHub does not single out.
+func (c *githubClient) generatedNotes(r releaseRequest) (string, error) {
+	in := map[string]string{"tag_name": r.Tag}
+	if tagExists(r.PreviousTag) {
+		in["previous_tag_name"] = r.PreviousTag
+	}
+
+	var out struct {
+		Body string `json:"body"`
+	}
+	if err := c.do(http.MethodPost, "/repos/"+c.repo+"/releases/generate-notes", in, &out); err != nil {
+		return "", fmt.Errorf("generating release notes: %w", err)
+	}
+
+	var b strings.Builder
+	if err := writeNotesHeader(&b, c, r); err != nil {
+		return "", err
+	}
+	b.WriteString(out.Body)
+	return b.String(), nil
+}
+
+// findRelease looks up the release for tag, including drafts, which are not
+// reachable through the releases/tags endpoint.
+func (c *githubClient) findRelease(tag string) (*githubRelease, error) {
+	var releases []githubRelease
+	if err := c.do(http.MethodGet, "/repos/"+c.repo+"/releases?per_page=100", nil, &releases); err != nil {
+		return nil, err
+	}
+
+	for i := range releases {
+		if releases[i].TagName == tag {
+			return &releases[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no release found for tag %s", tag)
+}
+
+// publishRelease flips a draft release to published. Without latest, GitHub
+// marks it as the latest release if it is the newest stable one.
+func (c *githubClient) publishRelease(tag, latest string) (string, error) {
+	release, err := c.findRelease(tag)
+	if err != nil {
+		return "", err
+	}
+
+	if !release.Draft {
+		slog.Info("Release is already published", "tag", tag)
+		return release.HTMLURL, nil
+	}
+
+	in := map[string]any{"draft": false}
+	if latest != "" {
+		in["make_latest"] = latest
+	}
+
+	var out githubRelease
+	path := fmt.Sprintf("/repos/%s/releases/%d", c.repo, release.ID)
+	if err := c.do(http.MethodPatch, path, in, &out); err != nil {
+		return "", err
+	}
+	return out.HTMLURL, nil
+}
+
+// checkStatuses combines check runs (GitHub Actions and apps) with legacy
+// commit statuses, since either may be used by required checks.
+func (c *githubClient) checkStatuses(commit string) ([]checkStatus, error) {
+	var runs struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		} `json:"check_runs"`
+	}
+	path := fmt.Sprintf("/repos/%s/commits/%s/check-runs?per_page=100", c.repo, commit)
+	if err := c.do(http.MethodGet, path, nil, &runs); err != nil {
+		return nil, err
+	}
+
+	var combined struct {
+		Statuses []struct {
+			Context string `json:"context"`
+			State   string `json:"state"`
+		} `json:"statuses"`
+	}
+	path = fmt.Sprintf("/repos/%s/commits/%s/status", c.repo, commit)
+	if err := c.do(http.MethodGet, path, nil, &combined); err != nil {
+		return nil, err
+	}
+
+	var statuses []checkStatus
+	for _, r := range runs.CheckRuns {
+		state := checkPending
+		if r.Status == "completed" {
+			switch r.Conclusion {
+			case "success", "neutral", "skipped":
+				state = checkSuccess
+			default:
+				state = checkFailure
+			}
+		}
+		statuses = append(statuses, checkStatus{Name: r.Name, State: state})
+	}
+	for _, s := range combined.Statuses {
+		statuses = append(statuses, checkStatus{Name: s.Context, State: normalizeStatus(s.State)})
+	}
+	return statuses, nil
+}
+
+func (c *githubClient) openPullRequest(head, base, title, body string) (int, string, error) {
+	in := map[string]string{
+		"head":  head,
+		"base":  base,
+		"title": title,
+		"body":  body,
+	}
+
+	var out struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := c.do(http.MethodPost, "/repos/"+c.repo+"/pulls", in, &out); err != nil {
+		return 0, "", err
+	}
+	return out.Number, out.HTMLURL, nil
+}
+
+type githubPullRequest struct {
+	NodeID         string `json:"node_id"`
+	State          string `json:"state"`
+	Merged         bool   `json:"merged"`
+	MergeCommitSHA string `json:"merge_commit_sha"`
+}
+
+func (c *githubClient) pullRequest(number int) (*githubPullRequest, error) {
+	var pr githubPullRequest
+	path := fmt.Sprintf("/repos/%s/pulls/%d", c.repo, number)
+	if err := c.do(http.MethodGet, path, nil, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// enableAutoMerge turns on auto-merge, which is only exposed through the
+// GraphQL API. The repository must allow auto-merge.
+func (c *githubClient) enableAutoMerge(number int) error {
+	pr, err := c.pullRequest(number)
+	if err != nil {
+		return err
+	}
+
+	in := map[string]any{
+		"query": `mutation($id: ID!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: MERGE}) { clientMutationId }
+}`,
+		"variables": map[string]string{"id": pr.NodeID},
+	}
+
+	var out struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.doURL(http.MethodPost, c.graphqlURL, in, &out); err != nil {
+		return err
+	}
+	if len(out.Errors) > 0 {
+		return fmt.Errorf("enabling auto-merge: %s", out.Errors[0].Message)
+	}
+	return nil
+}
+
+func (c *githubClient) mergeCommit(number int) (string, bool, error) {
+	pr, err := c.pullRequest(number)
+	if err != nil {
+		return "", false, err
+	}
+
+	if pr.Merged {
+		return pr.MergeCommitSHA, true, nil
+	}
+	if pr.State == "closed" {
+		return "", false, fmt.Errorf("pull request #%d was closed without being merged", number)
+	}
+	return "", false, nil
+}
+
+func (c *githubClient) openMilestones() ([]milestone, error) {
+	var out []struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := c.do(http.MethodGet, "/repos/"+c.repo+"/milestones?state=open&per_page=100", nil, &out); err != nil {
+		return nil, err
+	}
+
+	milestones := make([]milestone, 0, len(out))
+	for _, m := range out {
+		milestones = append(milestones, milestone{ID: m.Number, Title: m.Title, URL: m.HTMLURL})
+	}
+	return milestones, nil
+}
+
+func (c *githubClient) closeMilestone(m *milestone) error {
+	path := fmt.Sprintf("/repos/%s/milestones/%d", c.repo, m.ID)
+	return c.do(http.MethodPatch, path, map[string]string{"state": "closed"}, nil)
+}
+
+func (c *githubClient) pullRequestsForCommit(commit string) ([]int, error) {
+	var out []struct {
+		Number   int     `json:"number"`
+		MergedAt *string `json:"merged_at"`
+	}
+	path := fmt.Sprintf("/repos/%s/commits/%s/pulls", c.repo, commit)
+	if err := c.do(http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+
+	var numbers []int
+	for _, pr := range out {
+		if pr.MergedAt != nil {
+			numbers = append(numbers, pr.Number)
+		}
+	}
+	return numbers, nil
+}
+
+func (c *githubClient) ensureLabel(name, color string) error {
+	in := map[string]string{"name": name, "color": color}
+	err := c.do(http.MethodPost, "/repos/"+c.repo+"/labels", in, nil)
+	if isStatus(err, http.StatusUnprocessableEntity) {
+		return nil // already exists
+	}
+	return err
+}
+
+func (c *githubClient) addLabel(number int, name string) error {
+	in := map[string][]string{"labels": {name}}
+	path := fmt.Sprintf("/repos/%s/issues/%d/labels", c.repo, number)
+	return c.do(http.MethodPost, path, in, nil)
+}
+
+func (c *githubClient) listTags() ([]string, error) {
+	var tags []string
+	for page := 1; page <= maxTagPages; page++ {
+		var out []struct {
+			Name string `json:"name"`
+		}
+		path := fmt.Sprintf("/repos/%s/tags?per_page=100&page=%d", c.repo, page)
+		if err := c.do(http.MethodGet, path, nil, &out); err != nil {
+			return nil, err
+		}
+		for _, t := range out {
+			tags = append(tags, t.Name)
+		}
+		if len(out) < 100 {
+			break
+		}
+	}
+	return tags, nil
+}
+
+func (c *githubClient) pullRequestBody(number int) (string, error) {
+	var pr struct {
+		Body string `json:"body"`
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d", c.repo, number)
+	if err := c.do(http.MethodGet, path, nil, &pr); err != nil {
+		return "", err
+	}
+	return pr.Body, nil
+}
+
+func (c *githubClient) commentOnIssue(number int, body string) error {
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", c.repo, number)
+	return c.do(http.MethodPost, path, map[string]string{"body": body}, nil)
+}
+
+func (c *githubClient) requests(op string, r releaseRequest) []string {
+	repo := c.baseURL + "/repos/" + c.repo
+	switch op {
+	case apiCreateRelease:
+		var reqs []string
+		if r.NotesMode != notesBuiltin {
+			reqs = append(reqs, "POST "+repo+"/releases/generate-notes")
+		}
+		reqs = append(reqs, "POST "+repo+"/releases")
+		for _, a := range r.Assets {
+			reqs = append(reqs, "POST {upload_url}?name="+url.QueryEscape(a.Name))
+		}
+		return reqs
+	case apiPublishRelease:
+		return []string{"PATCH " + repo + "/releases/{id}"}
+	case apiOpenPullRequest:
+		return []string{"POST " + repo + "/pulls"}
+	case apiAutoMerge:
+		return []string{"POST " + c.graphqlURL + " (enablePullRequestAutoMerge)"}
+	case apiCommentIssue:
+		return []string{"POST " + repo + "/issues/{number}/comments"}
+	case apiLabelPR:
+		return []string{"POST " + repo + "/labels", "POST " + repo + "/issues/{number}/labels"}
+	}
+	return nil
+}
+
+func (c *githubClient) assetURL(tag, name string) string {
+	return fmt.Sprintf("%s/releases/download/%s/%s", c.webURL, url.PathEscape(tag), url.PathEscape(name))
+}
+
+// uploadAsset attaches a file to the release of tag through the release's
+// upload URL, which lives on a separate host.
+func (c *githubClient) uploadAsset(tag string, a artifact) error {
+	release, err := c.findRelease(tag)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(a.Path)
+	if err != nil {
+		return err
+	}
+
+	uploadURL, _, _ := strings.Cut(release.UploadURL, "{")
+	uploadURL += "?name=" + url.QueryEscape(a.Name)
+	return c.send(http.MethodPost, uploadURL, "application/octet-stream", data, nil)
+}
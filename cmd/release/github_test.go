@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// newTestGitHub returns a client of repository o/r on a server with
+// handler.
+func newTestGitHub(t *testing.T, handler http.HandlerFunc) *githubClient {
+	t.Helper()
+	t.Setenv("GITHUB_REPOSITORY", "")
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return newGitHubClient(remoteInfo{Host: "github.com", Repo: "o/r"}, clientOptions{Token: "t0ken", APIURL: srv.URL, HTTP: srv.Client()})
+}
+
+func TestGitHubPublishRelease(t *testing.T) {
+	tests := []struct {
+		name, latest string
+		want         map[string]any
+	}{
+		{"forge default", "", map[string]any{"draft": false}},
+		{"latest", "true", map[string]any{"draft": false, "make_latest": "true"}},
+		{"not latest", "false", map[string]any{"draft": false, "make_latest": "false"}},
+	}
+	for _, tt := range tests {
+		var got map[string]any
+		c := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer t0ken" {
+				t.Errorf("%s: Authorization = %q", tt.name, r.Header.Get("Authorization"))
+			}
+			switch {
+			case r.Method == "GET" && r.URL.Path == "/repos/o/r/releases":
+				fmt.Fprint(w, `[{"id": 6, "tag_name": "v1.0.0"}, {"id": 7, "tag_name": "v1.1.0", "draft": true}]`)
+			case r.Method == "PATCH" && r.URL.Path == "/repos/o/r/releases/7":
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Error(err)
+				}
+				fmt.Fprint(w, `{"id": 7, "html_url": "https://github.com/o/r/releases/tag/v1.1.0"}`)
+			default:
+				t.Errorf("%s: unexpected request %s %s", tt.name, r.Method, r.URL)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+
+		url, err := c.publishRelease("v1.1.0", tt.latest)
+		if err != nil {
+			t.Errorf("%s: publishRelease error: %v", tt.name, err)
+			continue
+		}
+		if url != "https://github.com/o/r/releases/tag/v1.1.0" {
+			t.Errorf("%s: URL = %q", tt.name, url)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: request = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubPublishReleaseErrors(t *testing.T) {
+	c := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		fmt.Fprint(w, `[{"id": 6, "tag_name": "v1.0.0", "html_url": "https://github.com/o/r/releases/tag/v1.0.0"}]`)
+	})
+
+	// A published release is left as is.
+	url, err := c.publishRelease("v1.0.0", "true")
+	if err != nil || url != "https://github.com/o/r/releases/tag/v1.0.0" {
+		t.Errorf("publishRelease of a published release = %q, %v", url, err)
+	}
+	if _, err := c.publishRelease("v2.0.0", ""); err == nil {
+		t.Error("publishRelease of a missing release succeeded")
+	}
+}
+
+func TestGitHubOpenPullRequest(t *testing.T) {
+	c := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		if r.Method != "POST" || r.URL.Path != "/repos/o/r/pulls" || in["head"] != "release-v2" || in["base"] != "main" {
+			t.Errorf("unexpected request %s %s %v", r.Method, r.URL, in)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number": 12, "html_url": "https://github.com/o/r/pull/12"}`)
+	})
+
+	number, url, err := c.openPullRequest("release-v2", "main", "Release v2", "")
+	if err != nil || number != 12 || url != "https://github.com/o/r/pull/12" {
+		t.Errorf("openPullRequest = %d, %q, %v", number, url, err)
+	}
+}
+
+func TestGitHubListTags(t *testing.T) {
+	c := newTestGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		n := 100
+		if page == 2 {
+			n = 1
+		}
+		tags := make([]map[string]string, n)
+		for i := range tags {
+			tags[i] = map[string]string{"name": fmt.Sprintf("v%d.%d.0", page, i)}
+		}
+		json.NewEncoder(w).Encode(tags)
+	})
+
+	tags, err := c.listTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 101 || tags[0] != "v1.0.0" || tags[100] != "v2.0.0" {
+		t.Errorf("listTags = %d tags from %q to %q, want 101 from v1.0.0 to v2.0.0", len(tags), tags[0], tags[len(tags)-1])
+	}
+}
+
+func TestGitHubAPIURL(t *testing.T) {
+	tests := []struct {
+		host, apiURL, wantREST, wantGraphQL string
+	}{
+		{"github.com", "", "https://api.github.com", "https://api.github.com/graphql"},
+		{"ghe.example.com", "", "https://ghe.example.com/api/v3", "https://ghe.example.com/api/graphql"},
+		{"ghe.example.com", "https://api.ghe.example.com/", "https://api.ghe.example.com", "https://api.ghe.example.com/graphql"},
+	}
+	t.Setenv("GITHUB_REPOSITORY", "")
+	for _, tt := range tests {
+		c := newGitHubClient(remoteInfo{Host: tt.host, Repo: "o/r"}, clientOptions{APIURL: tt.apiURL})
+		if c.baseURL != tt.wantREST || c.graphqlURL != tt.wantGraphQL {
+			t.Errorf("client of %s with API URL %q uses %s and %s, want %s and %s", tt.host, tt.apiURL, c.baseURL, c.graphqlURL, tt.wantREST, tt.wantGraphQL)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Hook points of a release, at which the commands of the hooks section of
+// the config file run:
+//
+//	hooks:
+//	  pre-bump:
+//	    - go test ./...
+//	  post-tag: ./scripts/announce.sh
+const (
+	hookPreBump     = "pre-bump"     // before the first change
+	hookPreTag      = "pre-tag"      // before tagging
+	hookPostTag     = "post-tag"     // after tagging
+	hookPostPublish = "post-publish" // after the forge release is published
+)
+
+// hookPoints are the hook points, in order.
+var hookPoints = []string{hookPreBump, hookPreTag, hookPostTag, hookPostPublish}
+
+// configHooks are the commands of the config file by hook point.
+var configHooks map[string][]string
+
+// parseHooks converts the hooks section, a mapping of hook points to a
+// command or a list of them.
+func parseHooks(v any) (map[string][]string, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("hooks: expected a mapping of hook points")
+	}
+
+	hooks := make(map[string][]string)
+	for point, value := range m {
+		if !slices.Contains(hookPoints, point) {
+			return nil, fmt.Errorf("hooks: unknown hook point %q, must be one of %s", point, strings.Join(hookPoints, ", "))
+		}
+		switch value := value.(type) {
+		case string:
+			hooks[point] = []string{value}
+		case []string:
+			hooks[point] = value
+		default:
+			return nil, fmt.Errorf("hooks.%s: expected a command or a list of commands", point)
+		}
+	}
+	return hooks, nil
+}
+
+// hookStep returns the name of the plan step running the hooks of point.
+func hookStep(point string) string {
+	return point + "-hook"
+}
+
+// runHooks runs the commands of the hook point in order, in the shell, with
+// the release of tag after previousTag, if known, exposed in the environment
+// as $RELEASE_HOOK, $RELEASE_PREVIOUS_VERSION, $RELEASE_NEW_VERSION, and
+// $RELEASE_TAG. Their output goes to the release's.
+func runHooks(point, previousTag, tag string) error {
+	for _, command := range configHooks[point] {
+		slog.Info("Running hook", "hook", point, "command", command)
+
+		cmd := execCommand(shellCommand(command))
+		cmd.Env = append(os.Environ(),
+			"RELEASE_HOOK="+point,
+			"RELEASE_PREVIOUS_VERSION="+versionNumber(previousTag),
+			"RELEASE_NEW_VERSION="+versionNumber(tag),
+			"RELEASE_TAG="+tag,
+		)
+		cmd.Stdout, cmd.Stderr = messages, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", point, command, err)
+		}
+	}
+	return nil
+}
+
+// shellCommand returns the command running command in the shell.
+func shellCommand(command string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", command}
+	}
+	return []string{"sh", "-c", command}
+}
+
+// describeHooks returns the plan step running the hooks of point, if any.
+func describeHooks(point string) (planStep, bool) {
+	commands := configHooks[point]
+	if len(commands) == 0 {
+		return planStep{}, false
+	}
+	return planStep{hookStep(point), point, fmt.Sprintf("Run the %s hook: %s", point, strings.Join(commands, "; "))}, true
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultNotesTemplate is the introduction template init writes for
+// -notes-file, prepended to the generated release notes.
+const defaultNotesTemplate = `<!-- Introduction prepended to the release notes; edit it before each release. -->
+`
+
+// runInit inspects the repository, reports the settings the release
+// commands would use, flagging anything that would make a release fail, and
+// writes a starter config file with them. On a terminal the key choices are
+// prompted for.
+func runInit(fs *flag.FlagSet) func() {
+	var (
+		force = fs.Bool("force", false, "Overwrite an existing config file")
+		tmpl  = fs.String("notes-template", "", "Also write a release notes introduction template to this file, used as release -notes-file")
+	)
+
+	return func() {
+		output, err := newCommand("go", "list", "-m").Output()
+		if err != nil {
+			slog.Error("Not in a Go module", "err", err)
+			os.Exit(exitPreflight)
+		}
+		module := strings.TrimSpace(string(output))
+		fmt.Printf("Module: %s\n", module)
+
+		current, err := getCurrentVersion()
+		if errors.Is(err, ErrNoTags) {
+			err = nil // not released yet
+		}
+		if err != nil {
+			slog.Error("Could not retrieve current version", "err", err)
+			os.Exit(exitGit)
+		}
+		fmt.Printf("Current version: %s\n", current)
+
+		ok := true
+		if err := checkModulePath(module, current.Major); err != nil {
+			slog.Warn(err.Error())
+			ok = false
+		}
+
+		var kind string
+		remote, err := parseRemote(remoteName)
+		if err != nil {
+			slog.Warn(err.Error())
+			ok = false
+		} else {
+			fmt.Printf("Repository: %s\n", remote.WebURL())
+
+			kind = forgeFlags.Kind
+			if kind == "" || kind == "auto" {
+				kind = forgeFlags.detectForge(remote.Host)
+			}
+			if kind == "" {
+				slog.Warn("Unknown forge; pass -forge to create releases", "host", remote.Host)
+				ok = false
+			} else {
+				fmt.Printf("Forge: %s\n", kind)
+			}
+
+			if _, err := newForge(forgeFlags); err != nil {
+				slog.Warn("Releases cannot be created", "err", err)
+				ok = false
+			}
+		}
+
+		path, err := initConfigPath()
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitGit)
+		}
+		if _, err := os.Stat(path); err == nil && !*force {
+			fmt.Printf("Config: %s (exists, pass -force to overwrite)\n", path)
+		} else {
+			if err := writeStarterConfig(path, kind, *tmpl); err != nil {
+				slog.Error("Failed to write config", "err", err)
+				os.Exit(exitFailure)
+			}
+			success("Wrote config", "file", path)
+		}
+
+		if ok {
+			fmt.Printf("\nReady to release. Try: %s release -type=patch -dry-run\n", program)
+		}
+	}
+}
+
+// initConfigPath returns the config file init writes: -config if given,
+// the default one in the repository root otherwise.
+func initConfigPath() (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	output, err := newCommand("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Join(strings.TrimSpace(string(output)), defaultConfigFile), nil
+}
+
+// writeStarterConfig writes a config file to path with the detected
+// settings, asking for the release branch, the notes source, and whether
+// to use a notes template when on a terminal. kind is the detected forge,
+// if any, and tmpl the notes template to write, if any.
+func writeStarterConfig(path, kind, tmpl string) error {
+	output, err := newCommand("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return fmt.Errorf("failed to get current branch: %w", err)
+	}
+	branch := strings.TrimSpace(string(output))
+	notes := "auto"
+
+	if !assumeYes && isTerminal(os.Stdin) {
+		in := bufio.NewReader(os.Stdin)
+		fmt.Println()
+		if branch, err = prompt(in, "Branch releases are made from", branch); err != nil {
+			return err
+		}
+		for notes = ""; notes != "auto" && notes != notesBuiltin && notes != notesGitHub; {
+			if notes, err = prompt(in, "Release notes source (auto, builtin, or github)", "auto"); err != nil {
+				return err
+			}
+		}
+		if tmpl == "" {
+			write, err := yes(in, "Write a release notes introduction template?", false)
+			if err != nil {
+				return err
+			}
+			if write {
+				if tmpl, err = prompt(in, "Template file", ".github/release-notes.md"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if tmpl != "" {
+		if _, err := os.Stat(tmpl); errors.Is(err, os.ErrNotExist) {
+			if err := os.MkdirAll(filepath.Dir(tmpl), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(tmpl, []byte(defaultNotesTemplate), 0644); err != nil {
+				return err
+			}
+			success("Wrote release notes template", "file", tmpl)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Option defaults of %s; keys are flag names, see\n", program)
+	fmt.Fprintf(&b, "# '%s help <command>'. Command line flags and $RELEASE_*\n", program)
+	fmt.Fprintf(&b, "# environment variables take precedence.\n\n")
+	fmt.Fprintf(&b, "remote: %s\n", remoteName)
+	if kind != "" {
+		fmt.Fprintf(&b, "forge: %s\n", kind)
+	}
+	if tagPrefix != "" {
+		fmt.Fprintf(&b, "tag-prefix: %s\n", tagPrefix)
+	}
+	fmt.Fprintf(&b, "\nrelease:\n")
+	fmt.Fprintf(&b, "  branch: %s\n", branch)
+	fmt.Fprintf(&b, "  notes: %s\n", notes)
+	if tmpl != "" {
+		fmt.Fprintf(&b, "  notes-file: %s\n", filepath.ToSlash(tmpl))
+	}
+	fmt.Fprintf(&b, "  # check-gate: true\n")
+	fmt.Fprintf(&b, "  # required-checks: [build, test]\n")
+
+	// Validate what was written, so init never leaves a broken config.
+	if _, err := parseConfig([]byte(b.String())); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// defaultIssueCommentTemplate is the comment posted on issues fixed in a
+// release.
+const defaultIssueCommentTemplate = "This issue was fixed in [{{.Tag}}]({{.URL}})."
+
+// issueCommenter is implemented by forges that can comment on issues.
+type issueCommenter interface {
+	commentOnIssue(number int, body string) error
+}
+
+// pullRequestDescriber is implemented by forges that can return the
+// description of a pull request, to find the issues it closes.
+type pullRequestDescriber interface {
+	pullRequestBody(number int) (string, error)
+}
+
+// closingKeywords matches issue references that close the issue when merged,
+// e.g. "Fixes #12" or "closes: #34".
+var closingKeywords = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+#(\d+)\b`)
+
+// issueCommentData is the data available to the issue comment template.
+type issueCommentData struct {
+	Tag   string
+	URL   string
+	Issue int
+}
+
+// commentOnFixedIssues comments on every issue closed by the commits or pull
+// requests released in tag, telling watchers which release contains the fix.
+func commentOnFixedIssues(f forge, tag, previousTag, releaseURL, tmpl string) error {
+	ic, ok := f.(issueCommenter)
+	if !ok {
+		slog.Warn("Commenting on issues is not supported, skipping", "forge", f.name())
+		return nil
+	}
+
+	t, err := template.New("comment").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("invalid issue comment template: %w", err)
+	}
+
+	issues, err := fixedIssues(f, tag, previousTag)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range issues {
+		var body bytes.Buffer
+		if err := t.Execute(&body, issueCommentData{Tag: tag, URL: releaseURL, Issue: n}); err != nil {
+			return fmt.Errorf("rendering issue comment: %w", err)
+		}
+		if err := ic.commentOnIssue(n, body.String()); err != nil {
+			return fmt.Errorf("failed to comment on issue #%d: %w", n, err)
+		}
+	}
+
+	slog.Info("Commented on fixed issues", "count", len(issues))
+	return nil
+}
+
+// fixedIssues returns the issues closed by the commit messages in the
+// release and, where the forge supports it, by the descriptions of the
+// released pull requests.
+func fixedIssues(f forge, tag, previousTag string) ([]int, error) {
+	rng := commitRange(tag, previousTag)
+	cmd := newCommand("git", "log", "--pretty=format:%B", rng)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits for %s: %w", rng, err)
+	}
+	texts := []string{string(output)}
+
+	l, canList := f.(pullRequestLabeler)
+	d, canDescribe := f.(pullRequestDescriber)
+	if canList && canDescribe {
+		prs, err := releasedPullRequests(l, tag, previousTag)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range prs {
+			body, err := d.pullRequestBody(n)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get pull request #%d: %w", n, err)
+			}
+			texts = append(texts, body)
+		}
+	}
+
+	seen := make(map[int]bool)
+	for _, text := range texts {
+		for _, m := range closingKeywords.FindAllStringSubmatch(text, -1) {
+			n, _ := strconv.Atoi(m[1])
+			seen[n] = true
+		}
+	}
+
+	issues := make([]int, 0, len(seen))
+	for n := range seen {
+		issues = append(issues, n)
+	}
+	sort.Ints(issues)
+	return issues, nil
+}
+
+// previousVersionTag returns the highest local version tag below tag.
+func previousVersionTag(tag string) (string, error) {
+	current, err := parseVersion(tag)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := newCommand("git", "tag", "-l").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	var previous version
+	for _, t := range strings.Fields(string(output)) {
+		v, err := parseVersion(t)
+		if err == nil && v.less(current) && previous.less(v) {
+			previous = v
+		}
+	}
+	return previous.String(), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// releasedLabelColor is the color of the "released: <tag>" labels.
+const releasedLabelColor = "0e8a16"
+
+// pullRequestLabeler is implemented by forges that can find the pull
+// requests a commit came from and label them.
+type pullRequestLabeler interface {
+	pullRequestsForCommit(commit string) ([]int, error)
+	ensureLabel(name, color string) error
+	addLabel(number int, name string) error
+}
+
+// labelReleasedPRs applies a "released: <tag>" label to every pull request
+// merged in the commits between previousTag and tag.
+func labelReleasedPRs(f forge, tag, previousTag string) error {
+	l, ok := f.(pullRequestLabeler)
+	if !ok {
+		slog.Warn("Labeling pull requests is not supported, skipping", "forge", f.name())
+		return nil
+	}
+
+	numbers, err := releasedPullRequests(l, tag, previousTag)
+	if err != nil {
+		return err
+	}
+	if len(numbers) == 0 {
+		slog.Info("No pull requests found", "tag", tag)
+		return nil
+	}
+
+	label := "released: " + tag
+	if err := l.ensureLabel(label, releasedLabelColor); err != nil {
+		return fmt.Errorf("failed to create label %q: %w", label, err)
+	}
+
+	for _, n := range numbers {
+		if err := l.addLabel(n, label); err != nil {
+			return fmt.Errorf("failed to label pull request #%d: %w", n, err)
+		}
+	}
+
+	slog.Info("Labeled pull requests", "count", len(numbers), "label", label)
+	return nil
+}
+
+// releasedPullRequests returns the numbers of the merged pull requests that
+// contributed commits to tag, in ascending order.
+func releasedPullRequests(l pullRequestLabeler, tag, previousTag string) ([]int, error) {
+	commits, err := releasedCommits(tag, previousTag)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	for _, commit := range commits {
+		prs, err := l.pullRequestsForCommit(commit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find pull requests for %s: %w", commit, err)
+		}
+		for _, n := range prs {
+			seen[n] = true
+		}
+	}
+
+	numbers := make([]int, 0, len(seen))
+	for n := range seen {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
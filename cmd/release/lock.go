@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// lockFile is the file in the git directory held by a running release, so
+// that releases of the repository do not race on the next version.
+const lockFile = "release.lock"
+
+// lockRef is the ref on the remote held by a running release with
+// -remote-lock, which excludes releases from other clones, e.g. CI jobs.
+const lockRef = "refs/locks/release"
+
+// acquireLock takes the local lock and, with remote, the remote one,
+// returning the function releasing them.
+func acquireLock(remote bool) (func(), error) {
+	output, err := newCommand("git", "rev-parse", "--git-path", lockFile).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the git directory: %w", err)
+	}
+	path := strings.TrimSpace(string(output))
+
+	host, _ := os.Hostname()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		holder, _ := os.ReadFile(path)
+		return nil, fmt.Errorf("another release is running (%s); if it is not, delete %s", strings.TrimSpace(string(holder)), path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lock file: %w", err)
+	}
+	fmt.Fprintf(f, "pid %d on %s since %s\n", os.Getpid(), host, time.Now().UTC().Format(time.RFC3339))
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+	unlockLocal := func() {
+		if err := os.Remove(path); err != nil {
+			slog.Warn("Failed to remove lock file", "file", path, "err", err)
+		}
+	}
+	if !remote {
+		return unlockLocal, nil
+	}
+
+	// The lock is a commit unique to this release, as pushing the value the
+	// ref has already succeeds.
+	holder := fmt.Sprintf("Release lock held by pid %d on %s since %s", os.Getpid(), host, time.Now().UTC().Format(time.RFC3339))
+	output, err = newCommand("git", "commit-tree", "HEAD^{tree}", "-p", "HEAD", "-m", holder).Output()
+	if err != nil {
+		unlockLocal()
+		return nil, fmt.Errorf("failed to create the lock commit: %w", err)
+	}
+	commit := strings.TrimSpace(string(output))
+
+	// The empty lease only lets the push create the ref, so it fails while
+	// another release holds it.
+	p := startProgress("Taking the remote lock", "ref", lockRef, "remote", remoteName)
+	err = newCommand("git", "push", "--force-with-lease="+lockRef+":", remoteName, commit+":"+lockRef).Run()
+	p.stop()
+	if err != nil {
+		unlockLocal()
+		return nil, fmt.Errorf("failed to take the remote lock %s: %v; if no other release is running, delete it with 'git push %s --delete %s'", lockRef, err, remoteName, lockRef)
+	}
+
+	return func() {
+		err := newCommand("git", "push", "--force-with-lease="+lockRef+":"+commit, remoteName, ":"+lockRef).Run()
+		if err != nil {
+			slog.Warn("Failed to release the remote lock", "ref", lockRef, "remote", remoteName, "err", err)
+		}
+		unlockLocal()
+	}, nil
+}
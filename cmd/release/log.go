@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Log levels besides slog's: levelTrace is below slog.LevelDebug and
+// enabled by -vv, for output such as every forge API request; levelSuccess
+// marks the completion of a step.
+const (
+	levelTrace   = slog.LevelDebug - 4
+	levelSuccess = slog.LevelInfo + 1
+)
+
+// ANSI escape sequences of the colors used in the text log format.
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorDim    = "\x1b[2m"
+)
+
+// messages receives the log, the progress indicator, and the prompts:
+// stdout, or stderr when the run prints its result on stdout.
+var messages io.Writer = os.Stdout
+
+// Log settings set by the global flags.
+var (
+	verbose     bool
+	veryVerbose bool
+	quiet       bool
+	noColor     bool
+	logFormat   = "text"
+)
+
+// setupLogging installs the default logger for the log settings.
+func setupLogging() error {
+	level := slog.LevelInfo
+	switch {
+	case quiet:
+		level = slog.LevelError
+	case veryVerbose:
+		level = levelTrace
+	case verbose:
+		level = slog.LevelDebug
+	}
+
+	var h slog.Handler
+	switch logFormat {
+	case "text":
+		h = &lineHandler{level: level, color: colorEnabled(), annotate: annotate()}
+	case "json":
+		h = slog.NewJSONHandler(messageWriter{}, &slog.HandlerOptions{Level: level, ReplaceAttr: replaceAttr})
+	default:
+		return fmt.Errorf("invalid log format '%s', must be 'text' or 'json'", logFormat)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// success logs the completion of a step.
+func success(msg string, args ...any) {
+	slog.Log(context.Background(), levelSuccess, msg, args...)
+}
+
+// colorEnabled reports whether the text log format may be colored: not
+// with -no-color, $NO_COLOR, or a dumb terminal.
+func colorEnabled() bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
+
+// replaceAttr names the custom levels and redacts secrets in the JSON log
+// format.
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	switch v := a.Value.Resolve(); {
+	case v.Kind() == slog.KindString:
+		a.Value = slog.StringValue(redact(v.String()))
+	case v.Kind() == slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			a.Value = slog.StringValue(redact(err.Error()))
+		}
+	}
+	return levelNames(groups, a)
+}
+
+// levelNames names the custom levels in the JSON log format.
+func levelNames(groups []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.LevelKey || len(groups) > 0 {
+		return a
+	}
+	switch a.Value.Any().(slog.Level) {
+	case levelTrace:
+		a.Value = slog.StringValue("TRACE")
+	case levelSuccess:
+		a.Value = slog.StringValue("SUCCESS")
+	}
+	return a
+}
+
+// messageWriter writes to messages, which the run may change after the
+// logger is installed.
+type messageWriter struct{}
+
+func (messageWriter) Write(p []byte) (int, error) {
+	return messages.Write(p)
+}
+
+// lineHandler writes records as plain lines: the message followed by the
+// attributes as key=value pairs, with errors and warnings marked and
+// secrets redacted. With
+// color, which only applies when stdout is a terminal, the levels are
+// colored and the attribute keys dimmed. With annotate, errors and
+// warnings are written as GitHub Actions annotations instead.
+type lineHandler struct {
+	level    slog.Level
+	color    bool
+	annotate bool
+	attrs    []slog.Attr
+	prefix   string // of attribute keys, from groups
+}
+
+func (h *lineHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *lineHandler) Handle(_ context.Context, r slog.Record) error {
+	color := h.color && isTerminalWriter(messages)
+
+	var b strings.Builder
+	var reset bool
+	var command string
+	switch {
+	case r.Level >= slog.LevelError && h.annotate:
+		command = "error"
+	case r.Level >= slog.LevelWarn && h.annotate:
+		command = "warning"
+	case r.Level >= slog.LevelError:
+		b.WriteString(paint(color, colorRed, "Error: "))
+	case r.Level >= slog.LevelWarn:
+		b.WriteString(paint(color, colorYellow, "Warning: "))
+	case r.Level >= levelSuccess && color:
+		b.WriteString(colorGreen + "✓ ")
+		reset = true
+	case r.Level < slog.LevelInfo && color:
+		b.WriteString(colorDim)
+		reset = true
+	}
+	b.WriteString(r.Message)
+	if reset {
+		b.WriteString(colorReset)
+	}
+
+	for _, a := range h.attrs {
+		writeAttr(&b, "", a, color)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.prefix, a, color)
+		return true
+	})
+	line := redact(b.String())
+	if command != "" {
+		line = "::" + command + "::" + escapeWorkflowData(line)
+	}
+
+	activeLine.Lock()
+	defer activeLine.Unlock()
+	clearActiveLine()
+	_, err := io.WriteString(messages, line+"\n")
+	return err
+}
+
+func paint(color bool, code, s string) string {
+	if !color {
+		return s
+	}
+	return code + s + colorReset
+}
+
+func (h *lineHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+func (h *lineHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr, color bool) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			writeAttr(b, prefix+a.Key+".", ga, color)
+		}
+		return
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	s := v.String()
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		s = strconv.Quote(s)
+	}
+	fmt.Fprintf(b, " %s%s", paint(color, colorDim, prefix+a.Key+"="), s)
+}
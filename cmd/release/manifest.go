@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// manifestFile is the name of the version manifest written by -manifest,
+// for installers and self-update logic to query, e.g. on GitHub at
+// https://github.com/<owner>/<repo>/releases/latest/download/latest.json.
+const manifestFile = "latest.json"
+
+// stableChannel is the channel of the releases that are not prereleases.
+const stableChannel = "stable"
+
+// manifest is the content of manifestFile. Versions are without "v".
+type manifest struct {
+	Module string `json:"module"`
+	// Latest is the latest stable version.
+	Latest string `json:"latest,omitempty"`
+	// LatestPrerelease is the latest version, prerelease or not, for
+	// installers that opt into prereleases.
+	LatestPrerelease string `json:"latest_prerelease,omitempty"`
+	// Channels are the latest releases by channel: stableChannel, and the
+	// first identifier of the prereleases, e.g. "rc" for 2.0.0-rc.1.
+	Channels map[string]manifestRelease `json:"channels"`
+	Updated  time.Time                  `json:"updated"`
+}
+
+type manifestRelease struct {
+	Version string `json:"version"`
+	Tag     string `json:"tag"`
+	// Artifacts are listed for the release the manifest was written with
+	// only.
+	Artifacts []manifestArtifact `json:"artifacts,omitempty"`
+}
+
+type manifestArtifact struct {
+	Name   string `json:"name"`
+	OS     string `json:"os,omitempty"`
+	Arch   string `json:"arch,omitempty"`
+	SHA256 string `json:"sha256"`
+	// URL is the download URL of the artifact, if it is attached to a forge
+	// release.
+	URL string `json:"url,omitempty"`
+}
+
+// channel returns the manifest channel of v.
+func (v version) channel() string {
+	if v.Pre == "" {
+		return stableChannel
+	}
+	id, _, _ := strings.Cut(v.Pre, ".")
+	return strings.TrimRight(id, "0123456789")
+}
+
+// writeManifest writes the manifest of the local version tags to
+// manifestFile in dist, listing the artifacts of the release of tag, and
+// returns it as an artifact. With f, the artifacts are linked to the
+// assets of the forge release of tag.
+func writeManifest(f assetUploader, tag string, artifacts []artifact, dist string) (artifact, error) {
+	tags, err := getVersionTags()
+	if err != nil {
+		return artifact{}, err
+	}
+	m := manifest{Channels: make(map[string]manifestRelease), Updated: time.Now().UTC()}
+	if module, err := newCommand("go", "list", "-m").Output(); err == nil {
+		m.Module = strings.TrimSpace(string(module))
+	}
+
+	latest := make(map[string]version)
+	var newest version
+	for _, t := range tags {
+		v, err := parseVersion(t)
+		if err != nil || v.String() != t {
+			continue
+		}
+		if l, ok := latest[v.channel()]; !ok || l.less(v) {
+			latest[v.channel()] = v
+		}
+		if newest.less(v) {
+			newest = v
+		}
+	}
+	for channel, v := range latest {
+		m.Channels[channel] = manifestRelease{Version: versionNumber(v.String()), Tag: v.String()}
+	}
+	if stable, ok := latest[stableChannel]; ok {
+		m.Latest = versionNumber(stable.String())
+	}
+	if newest != (version{}) {
+		m.LatestPrerelease = versionNumber(newest.String())
+	}
+
+	if v, err := parseVersion(tag); err == nil {
+		r := m.Channels[v.channel()]
+		for _, a := range artifacts {
+			sum, err := sha256File(a.Path)
+			if err != nil {
+				return artifact{}, err
+			}
+			ma := manifestArtifact{Name: a.Name, OS: a.OS, Arch: a.Arch, SHA256: sum}
+			if f != nil {
+				ma.URL = f.assetURL(tag, a.Name)
+			}
+			r.Artifacts = append(r.Artifacts, ma)
+		}
+		// The release may not be the latest of its channel, e.g. a patch
+		// of an older major version.
+		if r.Tag == tag {
+			m.Channels[v.channel()] = r
+		}
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return artifact{}, err
+	}
+	if err := os.MkdirAll(dist, 0o755); err != nil {
+		return artifact{}, err
+	}
+	path := filepath.Join(dist, manifestFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return artifact{}, err
+	}
+	return artifact{Path: path, Name: manifestFile}, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// autoMerger is implemented by forges that can merge a pull request once its
+// requirements are met.
+type autoMerger interface {
+	forge
+	enableAutoMerge(number int) error
+	// mergeCommit reports whether pull request number is merged and, if so,
+	// the commit it was merged as. It fails if the pull request was closed
+	// without being merged.
+	mergeCommit(number int) (string, bool, error)
+}
+
+// mergePollInterval is how often the pull request is checked while waiting
+// for auto-merge to land it.
+const mergePollInterval = 20 * time.Second
+
+func waitForMerge(m autoMerger, number int, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+
+	var p *progress
+	defer func() {
+		if p != nil {
+			p.stop()
+		}
+	}()
+
+	for {
+		commit, merged, err := m.mergeCommit(number)
+		if err != nil {
+			return "", err
+		}
+		if merged {
+			return commit, nil
+		}
+
+		if !time.Now().Add(mergePollInterval).Before(deadline) {
+			return "", fmt.Errorf("pull request #%d not merged after %s", number, timeout)
+		}
+
+		if p == nil {
+			p = startProgress(fmt.Sprintf("Waiting for pull request #%d to be merged", number))
+		}
+		if err := sleep(mergePollInterval); err != nil {
+			return "", fmt.Errorf("pull request #%d not merged: %w", number, err)
+		}
+	}
+}
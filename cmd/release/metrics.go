@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricsTargets are where the metrics of a run are pushed when it
+// finishes.
+type metricsTargets struct {
+	pushgateway string // base URL of a Prometheus Pushgateway
+	statsd      string // host:port of a StatsD server, over UDP
+}
+
+// metric is a sample of the metrics of a run.
+type metric struct {
+	name   string
+	kind   string // "gauge" or "counter"
+	value  float64
+	labels map[string]string
+}
+
+// result returns the outcome of the finished run r for the metrics.
+func (r *runResult) result() string {
+	if len(r.Errors) > 0 {
+		return "failure"
+	}
+	return "success"
+}
+
+// metrics returns the metrics of the finished run r for StatsD, which sums
+// the counters over the runs: release_duration_seconds and releases_total
+// by bump type and result, and gate_failures_total by gate.
+func (r *runResult) metrics() []metric {
+	labels := map[string]string{"bump_type": r.bump, "result": r.result()}
+	ms := []metric{
+		{"release_duration_seconds", "gauge", r.Seconds, labels},
+		{"releases_total", "counter", 1, labels},
+	}
+	for _, g := range r.Gates {
+		if !g.Passed {
+			ms = append(ms, metric{"gate_failures_total", "counter", 1, map[string]string{"gate": g.Name}})
+		}
+	}
+	return ms
+}
+
+// gatewayMetrics returns the gauges of the run r finished at now for the
+// Pushgateway, which keeps the last value of each instead of summing them:
+// release_duration_seconds by bump type and result, and the times of the
+// last run, of the last success or failure, and of the last failure of each
+// gate, e.g. to alert when release_last_success_timestamp_seconds gets old.
+// The counters are pushed separately; see gatewayCounters.
+func (r *runResult) gatewayMetrics(now time.Time) []metric {
+	ts := float64(now.Unix())
+	result := r.result()
+	ms := []metric{
+		{"release_duration_seconds", "gauge", r.Seconds, map[string]string{"bump_type": r.bump, "result": result}},
+		{"release_last_run_timestamp_seconds", "gauge", ts, map[string]string{"bump_type": r.bump, "result": result}},
+		{"release_last_" + result + "_timestamp_seconds", "gauge", ts, nil},
+	}
+	for _, g := range r.Gates {
+		if !g.Passed {
+			ms = append(ms, metric{"gate_last_failure_timestamp_seconds", "gauge", ts, map[string]string{"gate": g.Name}})
+		}
+	}
+	return ms
+}
+
+// gatewayCounters returns prev, the counters of the Pushgateway group of
+// the bump type of r, incremented for the finished run r: releases_total by
+// result and gate_failures_total by gate. The Pushgateway keeps the last
+// value pushed instead of summing them, so the counters are read back and
+// pushed whole; concurrent runs may lose an increment.
+func (r *runResult) gatewayCounters(prev []metric) []metric {
+	ms := append([]metric(nil), prev...)
+	inc := func(name string, labels map[string]string) {
+		for i := range ms {
+			if ms[i].name == name && maps.Equal(ms[i].labels, labels) {
+				ms[i].value++
+				return
+			}
+		}
+		ms = append(ms, metric{name, "counter", 1, labels})
+	}
+	inc("releases_total", map[string]string{"result": r.result()})
+	for _, g := range r.Gates {
+		if !g.Passed {
+			inc("gate_failures_total", map[string]string{"gate": g.Name})
+		}
+	}
+	return ms
+}
+
+// pushMetrics pushes the metrics of the finished run r to targets.
+func (r *runResult) pushMetrics(targets metricsTargets) {
+	if targets.pushgateway != "" {
+		if err := pushGateway(targets.pushgateway, nil, r.gatewayMetrics(time.Now())); err != nil {
+			slog.Warn("Failed to push metrics to the Pushgateway", "err", err)
+		}
+		if err := r.pushGatewayCounters(targets.pushgateway); err != nil {
+			slog.Warn("Failed to push counters to the Pushgateway", "err", err)
+		}
+	}
+	if targets.statsd != "" {
+		if err := sendStatsD(targets.statsd, r.metrics()); err != nil {
+			slog.Warn("Failed to send metrics to StatsD", "err", err)
+		}
+	}
+}
+
+// pushGatewayCounters increments the counters of the group of the bump
+// type of r on the Pushgateway at base. Each bump type has a group of its
+// own, so that pushing the counters of one does not replace the others'.
+func (r *runResult) pushGatewayCounters(base string) error {
+	group := map[string]string{"bump_type": r.bump}
+	prev, err := gatewayCounterValues(base, group)
+	if err != nil {
+		return err
+	}
+	return pushGateway(base, group, r.gatewayCounters(prev))
+}
+
+// gatewayCounterValues returns the counters pushed by gatewayCounters to
+// the group of job "release" with the labels of group on the Pushgateway at
+// base, without the grouping labels.
+func gatewayCounterValues(base string, group map[string]string) ([]metric, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	u := strings.TrimSuffix(base, "/") + "/api/v1/metrics"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+
+	var out struct {
+		Data []map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("GET %s: %w", u, err)
+	}
+
+	want := map[string]string{"job": program}
+	maps.Copy(want, group)
+	var ms []metric
+	for _, g := range out.Data {
+		var labels map[string]string
+		if err := json.Unmarshal(g["labels"], &labels); err != nil || !maps.Equal(labels, want) {
+			continue
+		}
+		for _, name := range []string{"releases_total", "gate_failures_total"} {
+			var family struct {
+				Metrics []struct {
+					Labels map[string]string `json:"labels"`
+					Value  string            `json:"value"`
+				} `json:"metrics"`
+			}
+			if raw, ok := g[name]; !ok || json.Unmarshal(raw, &family) != nil {
+				continue
+			}
+			for _, m := range family.Metrics {
+				v, err := strconv.ParseFloat(m.Value, 64)
+				if err != nil {
+					continue
+				}
+				maps.DeleteFunc(m.Labels, func(k, _ string) bool { return k == "instance" || want[k] != "" })
+				ms = append(ms, metric{name, "counter", v, m.Labels})
+			}
+		}
+	}
+	return ms, nil
+}
+
+// pushGateway pushes ms to the group of job "release", with the additional
+// grouping labels of group, of the Pushgateway at base, in the text
+// exposition format. They are POSTed, which replaces the metrics of the
+// group with their names only, so that the time of the last success
+// outlives a failed run, and the one of a gate's last failure the runs it
+// passes.
+func pushGateway(base string, group map[string]string, ms []metric) error {
+	var b bytes.Buffer
+	typed := make(map[string]bool)
+	for _, m := range ms {
+		if !typed[m.name] {
+			typed[m.name] = true
+			fmt.Fprintf(&b, "# TYPE %s %s\n", m.name, m.kind)
+		}
+		if len(m.labels) == 0 {
+			fmt.Fprintf(&b, "%s %g\n", m.name, m.value)
+			continue
+		}
+		fmt.Fprintf(&b, "%s{%s} %g\n", m.name, promLabels(m.labels), m.value)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	u := strings.TrimSuffix(base, "/") + "/metrics/job/" + url.PathEscape(program)
+	for _, k := range sortedKeys(group) {
+		u += "/" + url.PathEscape(k) + "/" + url.PathEscape(group[k])
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", u, resp.Status)
+	}
+	slog.Debug("Pushed metrics", "pushgateway", base)
+	return nil
+}
+
+// sendStatsD sends ms to the StatsD server at addr, with their labels as
+// DogStatsD tags.
+func sendStatsD(addr string, ms []metric) error {
+	conn, err := net.DialTimeout("udp", addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var b bytes.Buffer
+	for _, m := range ms {
+		kind := "g"
+		if m.kind == "counter" {
+			kind = "c"
+		}
+		var tags []string
+		for _, k := range sortedKeys(m.labels) {
+			tags = append(tags, k+":"+m.labels[k])
+		}
+		fmt.Fprintf(&b, "%s:%g|%s|#%s\n", m.name, m.value, kind, strings.Join(tags, ","))
+	}
+	if _, err := conn.Write(b.Bytes()); err != nil {
+		return err
+	}
+	slog.Debug("Sent metrics", "statsd", addr)
+	return nil
+}
+
+// promLabels renders labels in the Prometheus text format.
+func promLabels(labels map[string]string) string {
+	var pairs []string
+	for _, k := range sortedKeys(labels) {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[k])
+		pairs = append(pairs, k+`="`+v+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
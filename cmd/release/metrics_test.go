@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPushGatewayCounters(t *testing.T) {
+	pushed := make(map[string]string) // by path
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/metrics":
+			// The group of minor releases and one of another job.
+			fmt.Fprint(w, `{"status": "success", "data": [
+				{"labels": {"job": "release", "bump_type": "minor"},
+				 "releases_total": {"type": "COUNTER", "metrics": [
+					{"labels": {"job": "release", "bump_type": "minor", "instance": "", "result": "success"}, "value": "4"},
+					{"labels": {"job": "release", "bump_type": "minor", "instance": "", "result": "failure"}, "value": "1"}]},
+				 "gate_failures_total": {"type": "COUNTER", "metrics": [
+					{"labels": {"job": "release", "bump_type": "minor", "instance": "", "gate": "semver-gate"}, "value": "2"}]}},
+				{"labels": {"job": "other", "bump_type": "minor"},
+				 "releases_total": {"type": "COUNTER", "metrics": [
+					{"labels": {"job": "other", "bump_type": "minor", "instance": "", "result": "failure"}, "value": "9"}]}}]}`)
+		case r.Method == "POST":
+			body, _ := io.ReadAll(r.Body)
+			pushed[r.URL.Path] = string(body)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	r := &runResult{
+		bump:   "minor",
+		Errors: []string{"failed"},
+		Gates:  []gateResult{{Name: "check-gate"}, {Name: "semver-gate"}, {Name: "files-gate", Passed: true}},
+	}
+	if err := r.pushGatewayCounters(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := pushed["/metrics/job/release/bump_type/minor"]
+	if !ok {
+		t.Fatalf("pushed to %q, want the group of minor releases", pushed)
+	}
+	for _, want := range []string{
+		`releases_total{result="success"} 4`,
+		`releases_total{result="failure"} 2`,
+		`gate_failures_total{gate="semver-gate"} 3`,
+		`gate_failures_total{gate="check-gate"} 1`,
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("pushed %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "files-gate") {
+		t.Errorf("pushed %q, want no failure of the passed gate", got)
+	}
+}
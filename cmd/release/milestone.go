@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// milestone is an open forge milestone.
+type milestone struct {
+	ID    int
+	Title string
+	URL   string
+}
+
+// milestoneManager is implemented by forges with milestones.
+type milestoneManager interface {
+	openMilestones() ([]milestone, error)
+	closeMilestone(m *milestone) error
+}
+
+// findVersionMilestone returns the open milestone titled after tag, with or
+// without the "v" prefix, or nil if there is none or the forge has no
+// milestones.
+func findVersionMilestone(f forge, tag string) (*milestone, error) {
+	mm, ok := f.(milestoneManager)
+	if !ok {
+		slog.Warn("Milestones are not supported, skipping", "forge", f.name())
+		return nil, nil
+	}
+
+	milestones, err := mm.openMilestones()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list milestones: %w", err)
+	}
+
+	for i, m := range milestones {
+		if m.Title == tag || m.Title == versionNumber(tag) {
+			return &milestones[i], nil
+		}
+	}
+
+	slog.Info("No open milestone found", "tag", tag)
+	return nil, nil
+}
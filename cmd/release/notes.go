@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Release notes modes.
+const (
+	// notesBuiltin renders the notes from the commit log.
+	notesBuiltin = "builtin"
+	// notesGitHub uses GitHub's generate-notes API.
+	notesGitHub = "github"
+)
+
+// releaseNotes renders a plain list of the commits between the previous tag
+// and the released tag, for forges that cannot generate notes themselves.
+// Pull request references such as "(#12)" are turned into links, unless f
+// is nil for an unknown forge.
+func releaseNotes(f forge, r releaseRequest) (string, error) {
+	tag, previousTag := r.Tag, r.PreviousTag
+	rng := commitRange(tag, previousTag)
+
+	cmd := newCommand("git", "log", "--no-merges", "--pretty=format:%s", rng)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list commits for %s: %w", rng, err)
+	}
+
+	var b strings.Builder
+	if err := writeNotesHeader(&b, f, r); err != nil {
+		return "", err
+	}
+
+	b.WriteString("## Changes\n\n")
+	for _, subject := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if subject == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s\n", linkPullRequests(f, subject))
+	}
+
+	if f != nil && tagExists(previousTag) {
+		fmt.Fprintf(&b, "\n**Full changelog**: %s\n", f.compareURL(previousTag, tag))
+	}
+
+	return b.String(), nil
+}
+
+// writeNotesHeader writes the parts of the release notes that precede the
+// list of changes: the request's own notes and the breaking changes.
+func writeNotesHeader(b *strings.Builder, f forge, r releaseRequest) error {
+	if r.Notes != "" {
+		b.WriteString(r.Notes + "\n\n")
+	}
+
+	breaking, err := breakingChanges(commitRange(r.Tag, r.PreviousTag))
+	if err != nil {
+		return err
+	}
+	if len(breaking) > 0 {
+		b.WriteString("## ⚠ Breaking changes\n\n")
+		for _, change := range breaking {
+			fmt.Fprintf(b, "- %s\n", linkPullRequests(f, change))
+		}
+		b.WriteString("\n")
+	}
+	return nil
+}
+
+var prRef = regexp.MustCompile(`\(#(\d+)\)$`)
+
+func linkPullRequests(f forge, s string) string {
+	if f == nil {
+		return s
+	}
+	return prRef.ReplaceAllStringFunc(s, func(ref string) string {
+		var n int
+		fmt.Sscanf(ref, "(#%d)", &n)
+		return fmt.Sprintf("([#%d](%s))", n, f.pullRequestURL(n))
+	})
+}
+
+var (
+	breakingSubject = regexp.MustCompile(`^\w+(\([^)]*\))?!:`)
+	breakingFooter  = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:\s*(.+)$`)
+)
+
+// breakingChanges lists the breaking changes in rng as marked by the
+// Conventional Commits spec: a "!" after the commit type, or a
+// "BREAKING CHANGE:" footer, whose description is preferred when present.
+func breakingChanges(rng string) ([]string, error) {
+	cmd := newCommand("git", "log", "--no-merges", "--pretty=format:%s%x00%b%x1e", rng)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits for %s: %w", rng, err)
+	}
+
+	var changes []string
+	for _, entry := range strings.Split(string(output), "\x1e") {
+		subject, body, _ := strings.Cut(strings.TrimSpace(entry), "\x00")
+		if subject == "" {
+			continue
+		}
+
+		if m := breakingFooter.FindStringSubmatch(body); m != nil {
+			changes = append(changes, strings.TrimSpace(m[1]))
+		} else if breakingSubject.MatchString(subject) {
+			changes = append(changes, subject)
+		}
+	}
+	return changes, nil
+}
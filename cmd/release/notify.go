@@ -0,0 +1,498 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kinds of notifiers, announcing a release once it is published. They are
+// configured in the notify section of the config file, a mapping of names
+// to notifiers:
+//
+//	notify:
+//	  slack:
+//	    url: ${SLACK_WEBHOOK_URL}
+//	    channel: "#releases"
+//	    changes: 5
+//	  team-chat:
+//	    type: teams
+//	    url: ${TEAMS_WEBHOOK_URL}
+//
+// The kind of a notifier is its type option, or else is inferred from its
+// URL, or else is its name. Values may refer to environment variables as
+// $VAR or ${VAR}, expanded when the notification is sent, to keep secrets
+// such as webhook URLs out of the file.
+const (
+	// notifySlack posts to a Slack incoming webhook.
+	notifySlack = "slack"
+	// notifyDiscord posts to a Discord webhook.
+	notifyDiscord = "discord"
+	// notifyTeams posts an Adaptive Card to a Microsoft Teams incoming
+	// webhook or workflow.
+	notifyTeams = "teams"
+	// notifyWebhook posts the whole release as JSON, a webhookPayload,
+	// signed with the secret option: the X-Release-Signature-256 header is
+	// "sha256=" followed by the hex HMAC-SHA256 of the body with the
+	// secret, like GitHub's webhook signatures.
+	notifyWebhook = "webhook"
+	// notifyEmail sends an email through the SMTP server of its URL,
+	// smtp://[user:password@]host[:port] (STARTTLS when the server offers
+	// it) or smtps:// (TLS), from the from option to the to option, a list
+	// of addresses. The subject option is a template like the message.
+	notifyEmail = "email"
+	// notifyMatrix posts to the room option, an ID or an alias, through the
+	// client-server API of the homeserver at its URL, with the access
+	// token of the token option.
+	notifyMatrix = "matrix"
+)
+
+// notifyOptions are the options of each kind of notifier, beyond the
+// common ones: type, url, message, and changes.
+var notifyOptions = map[string][]string{
+	notifySlack:   {"channel", "username", "icon-emoji"},
+	notifyDiscord: {"username", "avatar-url"},
+	notifyTeams:   {},
+	notifyWebhook: {"secret"},
+	notifyEmail:   {"from", "to", "subject"},
+	notifyMatrix:  {"room", "token"},
+}
+
+// notifyCommonOptions are the options of every kind of notifier.
+var notifyCommonOptions = []string{"type", "url", "message", "changes"}
+
+// defaultNotifyChanges is how many changes an announcement lists by
+// default.
+const defaultNotifyChanges = 5
+
+// defaultNotifyMessage is the default template of announcements; see
+// announcement for its fields.
+const defaultNotifyMessage = `Released {{.Module}} {{.Tag}}{{with .ReleaseURL}}: {{.}}{{end}}
+{{range .Changes}}
+• {{.}}{{end}}{{if .MoreChanges}}
+…and {{.MoreChanges}} more{{end}}{{with .CompareURL}}
+
+Full changelog: {{.}}{{end}}`
+
+// defaultEmailMessage and defaultEmailSubject are the default templates of
+// email announcements, whose body has the whole release notes.
+const (
+	defaultEmailMessage = `{{.Module}} {{.Tag}} has been released.{{with .ReleaseURL}}
+
+{{.}}{{end}}
+
+{{.Notes}}`
+	defaultEmailSubject = "[ANN] {{.Module}} {{.Tag}} released"
+)
+
+// notifier is a notifier of the config file.
+type notifier struct {
+	name    string
+	kind    string
+	message *template.Template
+	subject *template.Template // of email notifiers
+	changes int
+	// options are the values of the config file, which option expands.
+	options map[string]string
+}
+
+// configNotifiers are the notifiers of the config file, by name.
+var configNotifiers []notifier
+
+// parseNotify converts the notify section, a mapping of names to
+// notifiers.
+func parseNotify(v any) ([]notifier, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("notify: expected a mapping of notifier names")
+	}
+
+	var notifiers []notifier
+	for name, value := range m {
+		options, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("notify.%s: expected a mapping of options", name)
+		}
+		n := notifier{name: name, changes: defaultNotifyChanges, options: make(map[string]string)}
+		for option, value := range options {
+			if _, ok := value.(map[string]any); ok {
+				return nil, fmt.Errorf("notify.%s.%s: options cannot be nested further", name, option)
+			}
+			n.options[option] = configValue(value)
+		}
+
+		n.kind = notifierKind(name, n.options["type"], n.options["url"])
+		known, ok := notifyOptions[n.kind]
+		if !ok {
+			return nil, fmt.Errorf("notify.%s: unknown type %q, must be one of %s", name, n.kind, strings.Join(sortedNames(notifyOptions), ", "))
+		}
+		for option := range n.options {
+			if !slices.Contains(notifyCommonOptions, option) && !slices.Contains(known, option) {
+				return nil, fmt.Errorf("notify.%s: unknown option %q for %s", name, option, n.kind)
+			}
+		}
+		if n.options["url"] == "" {
+			return nil, fmt.Errorf("notify.%s: url is required", name)
+		}
+		if s, ok := n.options["changes"]; ok {
+			changes, err := strconv.Atoi(s)
+			if err != nil || changes < 0 {
+				return nil, fmt.Errorf("notify.%s.changes: expected a number of changes, got %q", name, s)
+			}
+			n.changes = changes
+		}
+		message, subject := defaultNotifyMessage, defaultEmailSubject
+		if n.kind == notifyEmail {
+			message = defaultEmailMessage
+			if n.options["from"] == "" || n.options["to"] == "" {
+				return nil, fmt.Errorf("notify.%s: from and to are required", name)
+			}
+		}
+		if s, ok := n.options["message"]; ok {
+			message = s
+		}
+		if s, ok := n.options["subject"]; ok {
+			subject = s
+		}
+		var err error
+		if n.message, err = template.New(name).Parse(message); err != nil {
+			return nil, fmt.Errorf("notify.%s.message: %w", name, err)
+		}
+		if n.subject, err = template.New(name).Parse(subject); err != nil {
+			return nil, fmt.Errorf("notify.%s.subject: %w", name, err)
+		}
+		notifiers = append(notifiers, n)
+	}
+	sort.Slice(notifiers, func(i, j int) bool { return notifiers[i].name < notifiers[j].name })
+	return notifiers, nil
+}
+
+// notifierKind returns the kind of notifier name, with the type and URL
+// options of the config file.
+func notifierKind(name, typ, rawURL string) string {
+	if typ != "" {
+		return typ
+	}
+	// The URL may not parse before its variables are expanded, e.g. with a
+	// password from one.
+	if scheme, _, _ := strings.Cut(rawURL, "://"); scheme == "smtp" || scheme == "smtps" {
+		return notifyEmail
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		switch host := u.Hostname(); {
+		case host == "hooks.slack.com":
+			return notifySlack
+		case host == "discord.com" || host == "discordapp.com":
+			return notifyDiscord
+		case strings.HasSuffix(host, ".webhook.office.com") || strings.HasSuffix(host, ".logic.azure.com"):
+			return notifyTeams
+		}
+	}
+	return name
+}
+
+// step returns the name of the plan step sending the notification of n.
+func (n notifier) step() string {
+	return "notify-" + n.name
+}
+
+// option returns the value of option, with the environment variables it
+// refers to expanded.
+func (n notifier) option(option string) string {
+	return os.ExpandEnv(n.options[option])
+}
+
+// announcement describes a published release to notifiers, as the fields
+// of their message templates.
+type announcement struct {
+	Module      string
+	Tag         string
+	Commit      string
+	Version     string // the tag without "v", e.g. 1.2.3
+	PreviousTag string // empty for the first release
+	Prerelease  bool
+	// ReleaseURL is the web URL of the forge release, if one was created.
+	ReleaseURL string
+	// CompareURL links to the changes since the previous release, if the
+	// forge is known.
+	CompareURL string
+	// Changes are the subjects of the latest commits of the release, at
+	// most the changes option of the notifier, newest first.
+	Changes []string
+	// MoreChanges is the number of commits not in Changes.
+	MoreChanges int
+	// Notes are the release notes in the builtin format, in Markdown.
+	Notes string
+}
+
+// newAnnouncement describes the release of tag at commit after
+// previousTag, whose forge release, if any, is at releaseURL.
+func newAnnouncement(fc forgeConfig, previousTag, tag, commit, releaseURL string) (announcement, error) {
+	a := announcement{
+		Tag:        tag,
+		Commit:     commit,
+		Version:    versionNumber(tag),
+		ReleaseURL: releaseURL,
+	}
+	if v, err := parseVersion(tag); err == nil {
+		a.Prerelease = v.Pre != ""
+	}
+	if module, err := newCommand("go", "list", "-m").Output(); err == nil {
+		a.Module = strings.TrimSpace(string(module))
+	}
+
+	rng := commitRange(tag, previousTag)
+	output, err := newCommand("git", "log", "--no-merges", "--pretty=format:%s", rng).Output()
+	if err != nil {
+		return a, fmt.Errorf("failed to list commits for %s: %w", rng, err)
+	}
+	for _, subject := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if subject != "" {
+			a.Changes = append(a.Changes, subject)
+		}
+	}
+
+	// Links need the forge's URLs but no API access; without them, the
+	// notes have none.
+	f, err := newLinkForge(fc)
+	if err != nil {
+		f = nil
+	}
+	if tagExists(previousTag) {
+		a.PreviousTag = previousTag
+		if f != nil {
+			a.CompareURL = f.compareURL(previousTag, tag)
+		}
+	}
+	a.Notes, err = releaseNotes(f, releaseRequest{Tag: tag, PreviousTag: previousTag})
+	if err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// notify sends the announcement a with n.
+func (n notifier) notify(a announcement) error {
+	listed := a
+	if len(a.Changes) > n.changes {
+		listed.Changes, listed.MoreChanges = a.Changes[:n.changes], len(a.Changes)-n.changes
+	}
+	var b strings.Builder
+	if err := n.message.Execute(&b, listed); err != nil {
+		return fmt.Errorf("rendering the message: %w", err)
+	}
+	message := strings.TrimSpace(b.String())
+
+	target := n.option("url")
+	if target == "" {
+		return fmt.Errorf("the url of %s is empty", n.name)
+	}
+	// Webhook URLs are credentials.
+	addSecret(target)
+
+	switch n.kind {
+	case notifySlack:
+		return postWebhook(target, slackPayload(n, message))
+	case notifyDiscord:
+		return postWebhook(target, discordPayload(n, message))
+	case notifyTeams:
+		return postWebhook(target, teamsPayload(message))
+	case notifyWebhook:
+		secret := n.option("secret")
+		if secret == "" {
+			return fmt.Errorf("the secret of %s is empty", n.name)
+		}
+		addSecret(secret)
+		return postSignedWebhook(target, secret, newWebhookPayload(a, message))
+	case notifyEmail:
+		var b strings.Builder
+		if err := n.subject.Execute(&b, listed); err != nil {
+			return fmt.Errorf("rendering the subject: %w", err)
+		}
+		return sendEmail(target, n.option("from"), splitList(n.option("to")), strings.TrimSpace(b.String()), message)
+	case notifyMatrix:
+		token := n.option("token")
+		if token == "" || n.option("room") == "" {
+			return fmt.Errorf("the room and token of %s are required", n.name)
+		}
+		addSecret(token)
+		return sendMatrix(target, token, n.option("room"), a.Tag, message)
+	}
+	return fmt.Errorf("unknown notifier type %q", n.kind)
+}
+
+// slackPayload returns the Slack webhook payload posting message.
+func slackPayload(n notifier, message string) map[string]any {
+	payload := map[string]any{"text": message}
+	for option, field := range map[string]string{"channel": "channel", "username": "username", "icon-emoji": "icon_emoji"} {
+		if v := n.option(option); v != "" {
+			payload[field] = v
+		}
+	}
+	return payload
+}
+
+// notifyClient is the HTTP client of the notifiers, bounding each request
+// so that an unresponsive endpoint cannot stall the end of a release.
+var notifyClient = &http.Client{Timeout: 30 * time.Second}
+
+// sendMatrix posts message as a notice to room, an ID such as
+// "!abc:example.org" or an alias such as "#releases:example.org", on the
+// homeserver at base. The transaction ID is derived from tag, so that the
+// homeserver ignores a repeated notification of the release.
+func sendMatrix(base, token, room, tag, message string) error {
+	c := newRESTClient(strings.TrimSuffix(base, "/")+"/_matrix/client/v3", map[string]string{"Authorization": "Bearer " + token}, notifyClient)
+	if strings.HasPrefix(room, "#") {
+		var out struct {
+			RoomID string `json:"room_id"`
+		}
+		if err := c.do(http.MethodGet, "/directory/room/"+url.PathEscape(room), nil, &out); err != nil {
+			return fmt.Errorf("resolving room %s: %w", room, err)
+		}
+		room = out.RoomID
+	}
+	path := fmt.Sprintf("/rooms/%s/send/m.room.message/%s", url.PathEscape(room), url.PathEscape("release-"+tag))
+	return c.do(http.MethodPut, path, map[string]any{"msgtype": "m.notice", "body": message}, nil)
+}
+
+// maxDiscordContent is the maximum length of a Discord message, in
+// characters.
+const maxDiscordContent = 2000
+
+// discordPayload returns the Discord webhook payload posting message,
+// truncated to the maximum length of a message.
+func discordPayload(n notifier, message string) map[string]any {
+	if r := []rune(message); len(r) > maxDiscordContent {
+		message = string(r[:maxDiscordContent-1]) + "…"
+	}
+	payload := map[string]any{"content": message}
+	for option, field := range map[string]string{"username": "username", "avatar-url": "avatar_url"} {
+		if v := n.option(option); v != "" {
+			payload[field] = v
+		}
+	}
+	return payload
+}
+
+// teamsPayload returns the Teams webhook payload posting message, as an
+// Adaptive Card with a text block per line, since Teams joins the lines of
+// a text block.
+func teamsPayload(message string) map[string]any {
+	var body []map[string]any
+	for _, line := range strings.Split(message, "\n") {
+		if line == "" {
+			continue
+		}
+		body = append(body, map[string]any{"type": "TextBlock", "text": line, "wrap": true, "spacing": "None"})
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{map[string]any{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// webhookPayload is the body of the notifications of webhook notifiers.
+type webhookPayload struct {
+	Event           string    `json:"event"` // "release"
+	Time            time.Time `json:"time"`
+	Module          string    `json:"module"`
+	Tag             string    `json:"tag"`
+	Version         string    `json:"version"`
+	PreviousTag     string    `json:"previous_tag,omitempty"`
+	PreviousVersion string    `json:"previous_version,omitempty"`
+	Prerelease      bool      `json:"prerelease"`
+	Commit          string    `json:"commit"`
+	ReleaseURL      string    `json:"release_url,omitempty"`
+	CompareURL      string    `json:"compare_url,omitempty"`
+	// Changes are the subjects of all the commits of the release.
+	Changes []string `json:"changes"`
+	Notes   string   `json:"notes"`
+	// Message is the rendered message template of the notifier.
+	Message string `json:"message"`
+}
+
+func newWebhookPayload(a announcement, message string) webhookPayload {
+	p := webhookPayload{
+		Event:       "release",
+		Time:        time.Now().UTC(),
+		Module:      a.Module,
+		Tag:         a.Tag,
+		Version:     a.Version,
+		PreviousTag: a.PreviousTag,
+		Prerelease:  a.Prerelease,
+		Commit:      a.Commit,
+		ReleaseURL:  a.ReleaseURL,
+		CompareURL:  a.CompareURL,
+		Changes:     a.Changes,
+		Notes:       a.Notes,
+		Message:     message,
+	}
+	if a.PreviousTag != "" {
+		p.PreviousVersion = versionNumber(a.PreviousTag)
+	}
+	if p.Changes == nil {
+		p.Changes = []string{}
+	}
+	return p
+}
+
+// signatureHeader is the header of the HMAC-SHA256 signature of webhook
+// notifications.
+const signatureHeader = "X-Release-Signature-256"
+
+// postSignedWebhook posts payload as JSON to the webhook at target, signed
+// with secret in signatureHeader. X-Release-Delivery identifies the
+// notification, the same across retries of a rate-limited delivery, for
+// receivers to deduplicate.
+func postSignedWebhook(target, secret string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding the payload: %w", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	headers := map[string]string{
+		signatureHeader:      "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+		"X-Release-Event":    "release",
+		"X-Release-Delivery": randomHex(16),
+	}
+	c := newRESTClient("", headers, notifyClient)
+	return c.send(http.MethodPost, target, "application/json", body, nil)
+}
+
+// postWebhook posts payload as JSON to the webhook at target, retrying
+// rate-limited and failed requests like forge ones.
+func postWebhook(target string, payload any) error {
+	c := newRESTClient("", nil, notifyClient)
+	return c.doURL(http.MethodPost, target, payload, nil)
+}
+
+// describeNotifiers returns the plan steps sending the notifications of
+// the config file.
+func describeNotifiers() []planStep {
+	var steps []planStep
+	for _, n := range configNotifiers {
+		steps = append(steps, planStep{n.step(), n.name, fmt.Sprintf("Announce the release with the %s notifier %s", n.kind, n.name)})
+	}
+	return steps
+}
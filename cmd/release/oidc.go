@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// sigstoreAudience is the audience Sigstore's Fulcio CA expects in OIDC
+// tokens.
+const sigstoreAudience = "sigstore"
+
+// ambientIdentityToken returns an OIDC token for keyless signing from the
+// CI environment, and the name of the identity provider. It returns an
+// empty token outside of CI or when the job has no OIDC token configured.
+//
+// GitHub Actions jobs with the "id-token: write" permission can request a
+// token; GitLab CI jobs expose one through an "id_tokens" entry named
+// SIGSTORE_ID_TOKEN.
+func ambientIdentityToken() (token, provider string, err error) {
+	if token := os.Getenv("SIGSTORE_ID_TOKEN"); token != "" {
+		provider = "SIGSTORE_ID_TOKEN"
+		if os.Getenv("GITLAB_CI") != "" {
+			provider = "GitLab CI"
+		}
+		return token, provider, nil
+	}
+
+	reqURL, reqToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if reqURL != "" && reqToken != "" {
+		token, err := githubActionsIDToken(reqURL, reqToken)
+		if err != nil {
+			return "", "", err
+		}
+		return token, "GitHub Actions", nil
+	}
+
+	return "", "", nil
+}
+
+func githubActionsIDToken(reqURL, reqToken string) (string, error) {
+	u, err := url.Parse(reqURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid ACTIONS_ID_TOKEN_REQUEST_URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("audience", sigstoreAudience)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(runCtx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+reqToken)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("requesting OIDC token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting OIDC token: %s", resp.Status)
+	}
+
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decoding OIDC token: %w", err)
+	}
+	return out.Value, nil
+}
+
+// inCI reports whether the tool runs in a CI environment, where interactive
+// browser-based signing flows are impossible.
+func inCI() bool {
+	return os.Getenv("CI") != "" || os.Getenv("GITHUB_ACTIONS") != "" || os.Getenv("GITLAB_CI") != ""
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// runParallel calls fn for 0 to n-1, running up to limit calls at once, or
+// one per CPU if limit is not positive. It returns the error of the lowest
+// failed index, once every started call has returned; after a failure, the
+// calls not started yet are skipped.
+func runParallel(n, limit int, fn func(i int) error) error {
+	if limit <= 0 {
+		limit = runtime.NumCPU()
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+		errs   = make([]error, n)
+		sem    = make(chan struct{}, limit)
+	)
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			if err := fn(i); err != nil {
+				mu.Lock()
+				errs[i], failed = err, true
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// planFile, set by the plan command, makes the release command write its
+// plan to this file instead of releasing.
+var planFile string
+
+// plannedVersion, set by the apply command, is the version the applied plan
+// releases; the release command fails if it computes another.
+var plannedVersion string
+
+// planExcluded are the flags not recorded in a plan: secrets, the plan's own
+// output, and settings of how the run is displayed.
+var planExcluded = map[string]bool{
+	"token": true, "out": true, "config": true, "profile": true, "yes": true, "non-interactive": true,
+	"v": true, "verbose": true, "vv": true, "quiet": true, "log-format": true, "no-color": true,
+}
+
+// releasePlan is the plan of a release written by the plan command: the
+// options of the release command, the versions it computed, and its steps,
+// for the commit it was computed at.
+type releasePlan struct {
+	Created         time.Time  `json:"created"`
+	Commit          string     `json:"commit"`
+	PreviousVersion string     `json:"previous_version"`
+	NewVersion      string     `json:"new_version"`
+	Args            []string   `json:"args"`
+	Steps           []planStep `json:"steps"`
+}
+
+// planStep is a step of a release: Command names the step as recorded in
+// the result once it completes, Target is what it acts on, such as a tag or
+// the remote, and Effect describes its outcome.
+type planStep struct {
+	Command string `json:"command"`
+	Target  string `json:"target"`
+	Effect  string `json:"effect"`
+}
+
+// stepEffects returns the effects of steps, to be confirmed.
+func stepEffects(steps []planStep) []string {
+	effects := make([]string, len(steps))
+	for i, s := range steps {
+		effects[i] = s.Effect
+	}
+	return effects
+}
+
+// runPlan computes a release like the release command, with the same
+// options, but writes its plan to a file instead of releasing.
+func runPlan(fs *flag.FlagSet) func() {
+	out := fs.String("out", "release-plan.json", "File the plan is written to")
+	release := runRelease(fs)
+
+	return func() {
+		planFile = *out
+		release()
+	}
+}
+
+// writePlan writes the plan of releasing newVersion after current with the
+// options set in fs, before or after the command name, to path.
+func writePlan(path string, fs *flag.FlagSet, current, newVersion version, steps []planStep) error {
+	commit, err := resolveCommit("HEAD")
+	if err != nil {
+		return err
+	}
+
+	p := releasePlan{
+		Created:         time.Now().UTC(),
+		Commit:          commit,
+		PreviousVersion: current.String(),
+		NewVersion:      newVersion.String(),
+		Args:            flagArgs(fs),
+		Steps:           steps,
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// flagArgs returns the options set in fs, before or after the command name,
+// as arguments, except for those excluded from plans.
+func flagArgs(fs *flag.FlagSet) []string {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var args []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] && !planExcluded[f.Name] {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
+// runApply executes a plan written by the plan command. It refuses if HEAD
+// moved or the next version changed since the plan was computed, and runs
+// the release command with exactly the planned options: the environment
+// and config file are not consulted.
+func runApply(fs *flag.FlagSet) func() {
+	return func() {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			slog.Error("Expected one plan file")
+			os.Exit(exitUsage)
+		}
+
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			slog.Error("Failed to read plan", "err", err)
+			os.Exit(exitUsage)
+		}
+		var p releasePlan
+		if err := json.Unmarshal(data, &p); err != nil {
+			slog.Error("Invalid plan", "file", fs.Arg(0), "err", err)
+			os.Exit(exitUsage)
+		}
+
+		head, err := resolveCommit("HEAD")
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitGit)
+		}
+		if head != p.Commit {
+			slog.Error("The plan is stale: HEAD moved since it was computed", "planned", p.Commit, "head", head)
+			os.Exit(exitPreflight)
+		}
+
+		if err := confirm(fmt.Sprintf("Applying the plan releasing %s -> %s:", p.PreviousVersion, p.NewVersion), stepEffects(p.Steps)); err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitPreflight)
+		}
+		assumeYes = true
+		plannedVersion = p.NewVersion
+
+		c := findCommand("release")
+		rfs := c.flagSet()
+		release := c.run(rfs)
+		if err := rfs.Parse(p.Args); err != nil {
+			os.Exit(exitUsage)
+		}
+		release()
+	}
+}
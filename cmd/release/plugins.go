@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// pluginPrefix is the name prefix of plugin executables, which are found
+// on $PATH like git's subcommands: release-plugin-slack is plugin slack.
+const pluginPrefix = "release-plugin-"
+
+// pluginProtocol is the version of the plugin protocol, sent with every
+// request so that plugins can refuse ones they do not understand.
+const pluginProtocol = 1
+
+// Plugin hooks: each is one run of the plugin, reading a pluginRequest on
+// stdin and writing a pluginResponse on stdout.
+const (
+	// pluginDescribe asks for the name and capabilities of the plugin. It
+	// must not change anything, as dry runs call it too.
+	pluginDescribe = "describe"
+	// pluginGate asks whether the release may be tagged; a response with an
+	// error refuses it.
+	pluginGate = "gate"
+	// pluginNotes asks for sections to add to the release notes.
+	pluginNotes = "notes"
+	// pluginPublish runs the plugin's publish step once the release is out.
+	pluginPublish = "publish"
+)
+
+// plugin is an external executable extending releases.
+type plugin struct {
+	name         string
+	path         string
+	capabilities []string // of pluginGate, pluginNotes, and pluginPublish
+}
+
+// pluginRequest is written to the plugin's stdin.
+type pluginRequest struct {
+	Protocol int           `json:"protocol"`
+	Hook     string        `json:"hook"`
+	Release  pluginRelease `json:"release"`
+}
+
+// pluginRelease describes the release to plugins.
+type pluginRelease struct {
+	PreviousVersion string `json:"previous_version"`
+	NewVersion      string `json:"new_version"`
+	Tag             string `json:"tag"`
+	Commit          string `json:"commit,omitempty"`
+	Remote          string `json:"remote"`
+	URL             string `json:"url,omitempty"` // of the forge release, if created
+	DryRun          bool   `json:"dry_run"`
+}
+
+// pluginResponse is read from the plugin's stdout; the fields used depend
+// on the hook.
+type pluginResponse struct {
+	Capabilities []string       `json:"capabilities,omitempty"` // describe
+	Sections     []notesSection `json:"sections,omitempty"`     // notes
+	Message      string         `json:"message,omitempty"`      // logged
+	Error        string         `json:"error,omitempty"`        // fails the hook
+}
+
+// notesSection is a section of the release notes contributed by a plugin.
+type notesSection struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// discoverPlugins finds the plugin executables on $PATH, the first of each
+// name winning, and asks them for their capabilities.
+func discoverPlugins() ([]plugin, error) {
+	seen := make(map[string]bool)
+	var plugins []plugin
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), pluginPrefix)
+			if !ok || e.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				if name, ok = strings.CutSuffix(name, ".exe"); !ok {
+					continue
+				}
+			} else if info, err := e.Info(); err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, plugin{name: name, path: filepath.Join(dir, e.Name())})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].name < plugins[j].name })
+
+	for i, p := range plugins {
+		resp, err := p.call(pluginDescribe, pluginRelease{})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range resp.Capabilities {
+			if c != pluginGate && c != pluginNotes && c != pluginPublish {
+				return nil, fmt.Errorf("plugin %s: unknown capability %q", p.name, c)
+			}
+		}
+		plugins[i].capabilities = resp.Capabilities
+		slog.Debug("Found plugin", "plugin", p.name, "path", p.path, "capabilities", strings.Join(resp.Capabilities, ","))
+	}
+	return plugins, nil
+}
+
+// can reports whether the plugin implements hook.
+func (p plugin) can(hook string) bool {
+	return slices.Contains(p.capabilities, hook)
+}
+
+// step returns the name of the plan step running hook of the plugin.
+func (p plugin) step(hook string) string {
+	return "plugin-" + p.name + "-" + hook
+}
+
+// call runs hook of the plugin for release r. The plugin's stderr goes to
+// the release's, so that it can log.
+func (p plugin) call(hook string, r pluginRelease) (pluginResponse, error) {
+	in, err := json.Marshal(pluginRequest{pluginProtocol, hook, r})
+	if err != nil {
+		return pluginResponse{}, err
+	}
+
+	var out bytes.Buffer
+	cmd := newCommand(p.path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return pluginResponse{}, fmt.Errorf("plugin %s: %s failed: %w", p.name, hook, err)
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return pluginResponse{}, fmt.Errorf("plugin %s: invalid %s response: %w", p.name, hook, err)
+	}
+	if resp.Message != "" {
+		slog.Info(resp.Message, "plugin", p.name)
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("plugin %s: %s", p.name, resp.Error)
+	}
+	return resp, nil
+}
+
+// describePlugins returns the plan steps running hook of the plugins.
+func describePlugins(plugins []plugin, hook string) []planStep {
+	var steps []planStep
+	for _, p := range plugins {
+		if p.can(hook) {
+			steps = append(steps, planStep{p.step(hook), p.name, fmt.Sprintf("Run the %s step of plugin %s", hook, p.name)})
+		}
+	}
+	return steps
+}
+
+// pluginNotesSections collects the release notes sections of the plugins,
+// rendered as Markdown.
+func pluginNotesSections(plugins []plugin, r pluginRelease) (string, error) {
+	var b strings.Builder
+	for _, p := range plugins {
+		if !p.can(pluginNotes) {
+			continue
+		}
+		resp, err := p.call(pluginNotes, r)
+		if err != nil {
+			return "", err
+		}
+		for _, s := range resp.Sections {
+			if s.Title == "" {
+				return "", errors.New("plugin " + p.name + ": notes section without a title")
+			}
+			fmt.Fprintf(&b, "## %s\n\n%s\n\n", s.Title, strings.TrimSpace(s.Body))
+		}
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// spinnerFrames are drawn in turn by a running progress indicator.
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// activeLine is the line of the running progress indicator, which log
+// records clear before they are written so that they do not interleave.
+var activeLine struct {
+	sync.Mutex
+	text string
+}
+
+// progress indicates that a slow operation, such as a push or polling the
+// forge, is running: as a spinner with the elapsed time on a terminal, and
+// as a plain log line otherwise.
+type progress struct {
+	msg   string
+	start time.Time
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// startProgress shows msg until stop is called. args are logged with msg
+// when no spinner is shown.
+func startProgress(msg string, args ...any) *progress {
+	p := &progress{msg: msg, start: time.Now(), done: make(chan struct{})}
+	if logFormat != "text" || quiet || !isTerminalWriter(messages) {
+		slog.Info(msg+"...", args...)
+		return p
+	}
+
+	p.wg.Add(1)
+	go p.spin()
+	return p
+}
+
+func (p *progress) spin() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for i := 0; ; i++ {
+		activeLine.Lock()
+		activeLine.text = fmt.Sprintf("%c %s (%s)", spinnerFrames[i%len(spinnerFrames)], p.msg, time.Since(p.start).Truncate(time.Second))
+		fmt.Fprint(messages, "\r\x1b[K"+activeLine.text)
+		activeLine.Unlock()
+
+		select {
+		case <-p.done:
+			activeLine.Lock()
+			activeLine.text = ""
+			fmt.Fprint(messages, "\r\x1b[K")
+			activeLine.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop removes the indicator.
+func (p *progress) stop() {
+	close(p.done)
+	p.wg.Wait()
+}
+
+// clearActiveLine erases the progress indicator, if any, before a log
+// record is written; it is redrawn on its next tick. The caller holds
+// activeLine.
+func clearActiveLine() {
+	if activeLine.text != "" {
+		fmt.Fprint(messages, "\r\x1b[K")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// provenanceFile is the name of the provenance attestation artifact.
+const provenanceFile = "provenance.intoto.jsonl"
+
+// provenanceBuildType identifies how this tool builds releases, so verifiers
+// know how to interpret the build parameters.
+const provenanceBuildType = "https://github.com/raducristianpopa/test-go-pkg/release@v1"
+
+type inTotoStatement struct {
+	Type          string          `json:"_type"`
+	Subject       []inTotoSubject `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     slsaProvenance  `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// slsaProvenance is the SLSA v1 provenance predicate.
+type slsaProvenance struct {
+	BuildDefinition struct {
+		BuildType            string             `json:"buildType"`
+		ExternalParameters   map[string]any     `json:"externalParameters"`
+		InternalParameters   map[string]any     `json:"internalParameters,omitempty"`
+		ResolvedDependencies []slsaResourceDesc `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Metadata struct {
+			InvocationID string `json:"invocationId,omitempty"`
+			StartedOn    string `json:"startedOn"`
+			FinishedOn   string `json:"finishedOn"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+type slsaResourceDesc struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+// writeProvenance writes an in-toto statement carrying SLSA v1 provenance
+// for artifacts, built from tag, and returns it as an artifact. params are
+// the build parameters recorded as external parameters.
+func writeProvenance(artifacts []artifact, tag string, params map[string]any, started time.Time, dist string) (artifact, error) {
+	commit, err := tagCommit(tag)
+	if err != nil {
+		return artifact{}, err
+	}
+
+	remote, err := parseRemote(remoteName)
+	if err != nil {
+		return artifact{}, err
+	}
+
+	st := inTotoStatement{
+		Type:          "https://in-toto.io/Statement/v1",
+		PredicateType: "https://slsa.dev/provenance/v1",
+	}
+
+	for _, a := range artifacts {
+		sum, err := sha256File(a.Path)
+		if err != nil {
+			return artifact{}, err
+		}
+		st.Subject = append(st.Subject, inTotoSubject{Name: a.Name, Digest: map[string]string{"sha256": sum}})
+	}
+
+	p := &st.Predicate
+	p.BuildDefinition.BuildType = provenanceBuildType
+	p.BuildDefinition.ExternalParameters = params
+	p.BuildDefinition.InternalParameters = map[string]any{"goVersion": runtime.Version()}
+	p.BuildDefinition.ResolvedDependencies = []slsaResourceDesc{{
+		URI:    fmt.Sprintf("git+%s@refs/tags/%s", remote.WebURL(), tag),
+		Digest: map[string]string{"gitCommit": commit},
+	}}
+	p.RunDetails.Builder.ID, p.RunDetails.Metadata.InvocationID = builderIdentity()
+	p.RunDetails.Metadata.StartedOn = started.UTC().Format(time.RFC3339)
+	p.RunDetails.Metadata.FinishedOn = time.Now().UTC().Format(time.RFC3339)
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return artifact{}, err
+	}
+
+	out := filepath.Join(dist, provenanceFile)
+	if err := os.WriteFile(out, append(data, '\n'), 0644); err != nil {
+		return artifact{}, err
+	}
+
+	slog.Info("Wrote provenance", "path", out)
+	return artifact{Path: out, Name: provenanceFile}, nil
+}
+
+// builderIdentity identifies the environment running the build: the
+// workflow and run under GitHub Actions or GitLab CI, the local host
+// otherwise.
+func builderIdentity() (id, invocation string) {
+	if ref := os.Getenv("GITHUB_WORKFLOW_REF"); ref != "" {
+		server := os.Getenv("GITHUB_SERVER_URL")
+		invocation = fmt.Sprintf("%s/%s/actions/runs/%s/attempts/%s",
+			server, os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"), os.Getenv("GITHUB_RUN_ATTEMPT"))
+		return server + "/" + ref, invocation
+	}
+
+	if job := os.Getenv("CI_JOB_URL"); job != "" {
+		return os.Getenv("CI_PROJECT_URL") + "/-/blob/" + os.Getenv("CI_COMMIT_SHA") + "/.gitlab-ci.yml", job
+	}
+
+	host, _ := os.Hostname()
+	return "local://" + host, ""
+}
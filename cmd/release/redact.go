@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// secretEnvs are the environment variables holding secrets the release
+// tool reads, whose values are redacted from its output.
+var secretEnvs = []string{
+	"GITHUB_TOKEN", "GH_TOKEN", "GITEA_TOKEN", "BITBUCKET_TOKEN", envName("token"),
+	"DOCKER_PASSWORD", "GPG_PASSPHRASE", "SIGSTORE_ID_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_TOKEN",
+}
+
+// redacted replaces secrets in output.
+const redacted = "***"
+
+// secrets are the known secret values, such as the resolved forge token.
+var secrets struct {
+	sync.Mutex
+	once   sync.Once
+	values []string
+}
+
+// urlCredentials matches the user info of URLs, such as a remote URL with
+// an embedded token, e.g. https://x-access-token:<token>@github.com/o/r.
+var urlCredentials = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/@\s]+@`)
+
+// addSecret registers a secret value to be redacted from all output. Very
+// short values are ignored, as redacting them would mangle the output.
+func addSecret(s string) {
+	s = strings.TrimSpace(s)
+	if len(s) < 4 {
+		return
+	}
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.values = append(secrets.values, s)
+}
+
+// redact removes the known secrets and URL credentials from s. Every log
+// record and reported error goes through it.
+func redact(s string) string {
+	secrets.once.Do(func() {
+		for _, env := range secretEnvs {
+			addSecret(os.Getenv(env))
+		}
+	})
+
+	s = urlCredentials.ReplaceAllString(s, "${1}"+redacted+"@")
+	secrets.Lock()
+	defer secrets.Unlock()
+	for _, secret := range secrets.values {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
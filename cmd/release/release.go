@@ -0,0 +1,1612 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/raducristianpopa/test-go-pkg/v4/release"
+)
+
+type BumpType string
+
+const (
+	patch BumpType = "patch"
+	minor BumpType = "minor"
+	major BumpType = "major"
+)
+
+func (b BumpType) IsValid() bool {
+	return b == patch || b == minor || b == major
+}
+
+const program = "release"
+
+func runRelease(fs *flag.FlagSet) func() {
+	var (
+		bt = fs.String("type", "", "Version bump type: major, minor, or patch")
+		dr = fs.Bool("dry-run", false, "Show what would be done without making changes")
+		cr = fs.Bool("create-release", false, "Create a forge release for the new tag (requires a forge API token)")
+		df = fs.Bool("draft", false, "Create the release as a draft, to be published later with the 'publish' command")
+		cg = fs.Bool("check-gate", false, "Refuse to tag unless the CI checks of the released commit are green")
+		rc = fs.String("required-checks", "", "Comma-separated check names required by -check-gate (default: all reported checks)")
+		ct = fs.Duration("checks-timeout", 0, "How long -check-gate waits for pending checks (e.g. 10m); 0 fails immediately")
+		cm = fs.Bool("close-milestone", false, "Close the milestone named after the new version and link it from the release notes")
+		pr = fs.String("prerelease", "", "Release a prerelease with this identifier, e.g. 'rc' for v2.0.0-rc.1; prereleases are never marked latest")
+		ba = fs.Bool("build-artifacts", false, "Cross-compile the module's cmd/ binaries at the new tag")
+		pl = fs.String("platforms", defaultPlatforms, "Comma-separated GOOS/GOARCH pairs for -build-artifacts")
+		rp = fs.Bool("verify-reproducible", false, "Rebuild the binaries and fail unless they are byte-identical")
+		pa = fs.Int("parallel", 0, "Maximum number of binaries -build-artifacts builds at once (default: one per CPU)")
+		an = fs.String("archive-name", defaultArchiveName, "Template for archive names; fields: .Binary, .Tag, .Version, .OS, .Arch (empty publishes bare binaries)")
+		dd = fs.String("dist", "dist", "Directory release artifacts are written to")
+		sb = fs.String("sbom", "", "Attach an SBOM of the module graph in this format: spdx or cyclonedx")
+		pv = fs.Bool("provenance", false, "Attach a SLSA provenance attestation for the release artifacts")
+		cs = fs.Bool("cosign", false, "Sign the release artifacts with cosign")
+		ck = fs.String("cosign-key", "", "Key (file or KMS URI) for -cosign; empty signs keyless through Sigstore")
+		gk = fs.String("gpg-key", "", "Create detached GPG signatures (.asc) of the release artifacts with this key")
+		di = fs.String("docker-image", "", "Build and push a Docker image to this repository, e.g. ghcr.io/owner/name")
+		dk = fs.String("dockerfile", "Dockerfile", "Dockerfile used by -docker-image")
+		vs = fs.String("version-source", "git", "Where the current version is read from: git (local tags) or forge (the forge's tags API)")
+		nf = fs.String("notes-file", "", "File with an introduction prepended to the release notes")
+		nm = fs.String("notes", "auto", "Release notes source: auto (forge default), builtin (commit log), or github (generate-notes API)")
+		dc = fs.String("discussion-category", "", "Open a GitHub Discussion for the release in this category")
+		ci = fs.Bool("comment-issues", true, "With -create-release, comment on the issues fixed in the release once it is published")
+		it = fs.String("issue-comment-template", defaultIssueCommentTemplate, "Template of the fixed-issue comment; fields: .Tag, .URL, .Issue")
+		lp = fs.Bool("label-prs", false, "Label the pull requests included in the release with 'released: <version>'")
+		vp = fs.Bool("go-mod-pr", false, "Open a pull request with the 'go.mod' changes instead of pushing to the current branch")
+		am = fs.Bool("auto-merge", false, "With -go-mod-pr, enable auto-merge on the pull request, wait for it to land, and tag the merge commit")
+		mt = fs.Duration("merge-timeout", 30*time.Minute, "How long -auto-merge waits for the pull request to be merged")
+		of = fs.String("output", outputText, "Output format: text, or json to print the result as a JSON object on stdout")
+		de = fs.String("dotenv", "", "Also write the result to this file as a dotenv report, e.g. for GitLab CI's artifacts:reports:dotenv")
+		br = fs.String("branch", "", "Only release from this branch, e.g. main (default: any branch)")
+		np = fs.Bool("no-push", false, "Commit and tag locally only, never contacting the remote or forge, e.g. to push later from elsewhere")
+		rl = fs.Bool("remote-lock", false, "Also hold the ref "+lockRef+" on the remote while releasing, excluding releases from other clones, e.g. CI jobs")
+		nc = fs.Bool("no-cleanup", false, "Keep the commit and tag of a release failing before its tag is pushed, e.g. to resume it, instead of undoing them")
+		na = fs.Bool("no-audit", false, "Do not record the run in the audit log ("+auditRef+"), which is pushed with the release")
+		nx = fs.Bool("no-plugins", false, "Do not run the "+pluginPrefix+"* plugins found on $PATH")
+		ap = fs.String("approval", "", "Wait for approval after showing the plan and before any change: prompt, file:<path> (until the file exists), or github-deployment:<environment> (until a deployment of the commit is marked successful)")
+		ab = fs.String("approved-by", "", "Approve the release up front as this approver, e.g. from a CI input, instead of waiting for -approval")
+		at = fs.Duration("approval-timeout", time.Hour, "How long -approval waits")
+		mg = fs.String("metrics-pushgateway", "", "Push the run's metrics (duration, release and gate failure counts, and times of the last run, success, failure, and gate failures) to this Prometheus Pushgateway, e.g. http://pushgateway:9091")
+		md = fs.String("metrics-statsd", "", "Send the run's metrics to this StatsD server over UDP, e.g. localhost:8125")
+		ev = fs.String("events", "", "Write the run's events (steps started and finished, version computed, tag pushed) to this file as JSON lines, e.g. to drive a UI")
+		fd = fs.Bool("feed", false, "Write "+feedFile+", an Atom feed of the releases, into -dist and attach it to the forge release")
+		mf = fs.Bool("manifest", false, "Write "+manifestFile+", a manifest of the latest version of each channel with the release's artifacts, into -dist and attach it to the forge release")
+		fg = fs.Bool("files-gate", false, "Refuse the release unless HEAD has a non-empty file matching each of -required-files")
+		rf = fs.String("required-files", defaultRequiredFiles, "Comma-separated file name patterns required by -files-gate, matched regardless of case")
+		sg = fs.Bool("semver-gate", false, "Refuse a patch or minor release when apidiff finds incompatible API changes since the previous version")
+		sv = fs.String("allow-semver-violation", "", "Release despite the incompatible API changes found by -semver-gate, for this justification, which is recorded in the audit log")
+		mc = fs.Float64("min-coverage", 0, "Refuse the release if the total test coverage is below this percentage (0: no minimum)")
+		cd = fs.Float64("max-coverage-drop", 0, "Refuse the release if the total test coverage dropped by more than this many points since the previous version (0: no limit)")
+		bn = fs.String("bench", "", "Run the benchmarks matching this regexp at the previous version and HEAD, refuse the release if one regressed, and add the comparison to the release notes")
+		bh = fs.Float64("bench-threshold", 10, "Slowdown in percent of the time per operation beyond which -bench reports a regression")
+		bk = fs.Int("bench-count", 6, fmt.Sprintf("Number of runs of each benchmark for -bench, %d or more", minBenchCount))
+		bw = fs.Bool("bench-warn", false, "Only warn about the regressions found by -bench instead of refusing the release")
+		sx = fs.Bool("sandbox", false, "Rehearse the release for real in a temporary clone of the repository, pushing to a throwaway remote instead of "+remoteName)
+	)
+	fc := &forgeFlags
+
+	return func() {
+		res, err := newRunResult(*of, os.Stdout, os.Stderr)
+		if err != nil {
+			res.fail(exitUsage, "%v", err)
+		}
+		defer res.finish()
+		res.DryRun = *dr
+		res.dotenv = *de
+		if res.trace = startTracing(); res.trace != nil {
+			res.observe(res.trace.observe)
+		}
+		if *ev != "" {
+			f, err := os.Create(*ev)
+			if err != nil {
+				res.fail(exitUsage, "Cannot write events: %v", err)
+			}
+			res.observe(eventLog(f))
+		}
+
+		if *bt == "" {
+			fs.Usage()
+			res.fail(exitUsage, "-type flag is required")
+		}
+
+		bump := BumpType(*bt)
+		if !bump.IsValid() {
+			res.fail(exitUsage, "Invalid bump type '%s'. Must be 'major', 'minor', or 'patch'", *bt)
+		}
+
+		res.trace.set("release.bump_type", string(bump))
+		res.bump = string(bump)
+		res.metricsTo = metricsTargets{pushgateway: *mg, statsd: *md}
+
+		if *am && !*vp {
+			res.fail(exitUsage, "-auto-merge requires -go-mod-pr")
+		}
+
+		// -no-push never contacts the remote or forge, and -sandbox only
+		// its throwaway remote.
+		remoteFlags := []struct {
+			name string
+			set  bool
+			git  bool // only contacts the git remote
+		}{
+			{"create-release", *cr, false}, {"check-gate", *cg, false}, {"go-mod-pr", *vp, false}, {"label-prs", *lp, false},
+			{"docker-image", *di != "", false}, {"version-source", *vs == "forge", false}, {"remote-lock", *rl, true},
+			{"approval", strings.HasPrefix(*ap, approvalDeployment), false},
+		}
+		for _, f := range remoteFlags {
+			switch {
+			case !f.set:
+			case *np:
+				res.fail(exitUsage, "-no-push cannot be combined with -%s, which contacts the remote or forge", f.name)
+			case *sx && !f.git:
+				res.fail(exitUsage, "-sandbox cannot be combined with -%s, which contacts the forge", f.name)
+			}
+		}
+		coverage := coverageThresholds{Min: *mc, MaxDrop: *cd}
+		if coverage.Min < 0 || coverage.Min > 100 || coverage.MaxDrop < 0 {
+			res.fail(exitUsage, "Invalid coverage thresholds. -min-coverage must be 0 to 100, and -max-coverage-drop 0 or more")
+		}
+
+		bench := benchOptions{Pattern: *bn, Threshold: *bh, Count: *bk, WarnOnly: *bw}
+		if bench.Pattern != "" && (bench.Count < minBenchCount || bench.Threshold < 0) {
+			res.fail(exitUsage, "Invalid benchmark options. -bench-count must be %d or more, and -bench-threshold 0 or more", minBenchCount)
+		}
+
+		if *sv != "" && !*sg {
+			res.fail(exitUsage, "-allow-semver-violation requires -semver-gate")
+		}
+		if *sx && *dr {
+			res.fail(exitUsage, "-sandbox cannot be combined with -dry-run; it makes the changes, in a clone")
+		}
+
+		if *dr {
+			slog.Info("DRY RUN MODE - No changes will be made")
+		}
+
+		if *br != "" {
+			if err := checkBranch(*br); err != nil {
+				res.fail(exitPreflight, "%v", err)
+			}
+		}
+
+		if *ap != "" && !validApproval(*ap) {
+			res.fail(exitUsage, "Invalid approval mode '%s'. Must be 'prompt', 'file:<path>', or 'github-deployment:<environment>'", *ap)
+		}
+
+		if *pa < 0 {
+			res.fail(exitUsage, "Invalid -parallel %d. Must be 0 or more", *pa)
+		}
+
+		if *sb != "" && *sb != sbomSPDX && *sb != sbomCycloneDX {
+			res.fail(exitUsage, "Invalid SBOM format '%s'. Must be 'spdx' or 'cyclonedx'", *sb)
+		}
+
+		switch *nm {
+		case "auto":
+			*nm = ""
+		case notesBuiltin, notesGitHub:
+		default:
+			res.fail(exitUsage, "Invalid notes source '%s'. Must be 'auto', 'builtin', or 'github'", *nm)
+		}
+
+		if *sx {
+			// The files given to write or read are outside the clone, unlike
+			// -dist and -dockerfile, which are of the repository.
+			paths := []*string{&res.dotenv, nf, &planFile, &fc.CACert, &fc.ClientCert, &fc.ClientKey}
+			if _, err := os.Stat(*ck); err == nil { // not a KMS URI
+				paths = append(paths, ck)
+			}
+			for _, path := range paths {
+				if *path != "" {
+					*path, _ = filepath.Abs(*path)
+				}
+			}
+			if path, ok := strings.CutPrefix(*ap, approvalFile); ok {
+				path, _ = filepath.Abs(path)
+				*ap = approvalFile + path
+			}
+			dir, err := enterSandbox()
+			if err != nil {
+				res.fail(exitGit, "%v", err)
+			}
+			res.Sandbox = dir
+			slog.Info("Releasing in a sandbox", "sandbox", dir)
+		}
+
+		// The lock is held from computing the version to the end of the
+		// release, so that concurrent releases do not pick the same one.
+		if !*dr && planFile == "" {
+			unlock, err := acquireLock(*rl)
+			if err != nil {
+				res.fail(exitPreflight, "%v", err)
+			}
+			res.unlock = unlock
+		}
+
+		tags, err := listVersionTags(*vs, *fc)
+		if err != nil {
+			res.fail(versionSourceExitCode(*vs), "Could not retrieve current version: %v", err)
+		}
+
+		// A re-run of a completed release has nothing to do.
+		if resumeState == nil {
+			if v, ok := releasedAtHead(tags, bump, *pr); ok {
+				res.NewVersion, res.Tag = versionNumber(v.String()), v.String()
+				success("Already released; HEAD is tagged with the version this release would create", "tag", v.String())
+				return
+			}
+		}
+
+		currentVersion := latestVersion(tags)
+		newVersion := nextVersion(tags, bump, *pr)
+		if resumeState != nil {
+			// The resumed release may have tagged its version already.
+			currentVersion, err = parseVersion(resumeState.PreviousVersion)
+			if err == nil {
+				newVersion, err = parseVersion(resumeState.NewVersion)
+			}
+			if err != nil {
+				res.fail(exitUsage, "Invalid release state: %v", err)
+			}
+			res.Steps = append(res.Steps, resumeState.Completed...)
+		}
+
+		slog.Info("Current version", "version", currentVersion.String())
+		res.PreviousVersion = versionNumber(currentVersion.String())
+		slog.Info("New version", "version", newVersion.String())
+		res.NewVersion, res.Tag = versionNumber(newVersion.String()), newVersion.String()
+		res.emit(event{Type: eventVersionComputed, PreviousVersion: res.PreviousVersion, NewVersion: res.NewVersion, Tag: res.Tag})
+
+		needsGoModUpdate := bump == major && currentVersion.Major >= 0
+
+		var plugins []plugin
+		if !*nx {
+			plugins, err = discoverPlugins()
+			if err != nil {
+				res.fail(exitPreflight, "%v", err)
+			}
+		}
+		// pluginInfo describes the release at commit to plugins.
+		pluginInfo := func(commit string) pluginRelease {
+			r := pluginRelease{
+				PreviousVersion: res.PreviousVersion,
+				NewVersion:      res.NewVersion,
+				Tag:             res.Tag,
+				Commit:          commit,
+				Remote:          remoteName,
+				DryRun:          *dr,
+			}
+			if state := res.state; state != nil {
+				r.URL = state.ReleaseURL
+			}
+			return r
+		}
+
+		ao := artifactOptions{
+			Build:              *ba,
+			Platforms:          splitList(*pl),
+			ArchiveName:        *an,
+			VerifyReproducible: *rp,
+			Parallel:           *pa,
+			Dist:               *dd,
+			SBOM:               *sb,
+			Provenance:         *pv,
+			Cosign:             *cs,
+			CosignKey:          *ck,
+			GPGKey:             *gk,
+			Params: map[string]any{
+				"bumpType":   string(bump),
+				"platforms":  splitList(*pl),
+				"prerelease": *pr,
+			},
+		}
+
+		var steps []planStep
+		addHook := func(point string) {
+			if step, ok := describeHooks(point); ok {
+				steps = append(steps, step)
+			}
+		}
+		if *fg {
+			steps = append(steps, planStep{"files-gate", "HEAD", "Check that the required files are present"})
+		}
+		if *sg {
+			steps = append(steps, planStep{"semver-gate", currentVersion.String(), fmt.Sprintf("Check that the API changes since %s allow a %s release", currentVersion, bump)})
+		}
+		if coverage.enabled() {
+			steps = append(steps, planStep{"coverage-gate", "HEAD", "Check the test coverage against the thresholds"})
+		}
+		if bench.Pattern != "" {
+			steps = append(steps, planStep{"bench-gate", "HEAD", fmt.Sprintf("Compare the benchmarks with %s", currentVersion)})
+		}
+		addHook(hookPreBump)
+		if needsGoModUpdate {
+			steps = append(steps, planStep{"update-go-mod", "go.mod", fmt.Sprintf("Update the module path for v%d", newVersion.Major)})
+			switch {
+			case *vp && *am:
+				steps = append(steps, planStep{"open-pull-request", remoteName, "Open a pull request with the changes"})
+				steps = append(steps, planStep{"auto-merge", remoteName, "Auto-merge the pull request and wait for it to land"})
+			case *vp:
+				steps = append(steps, planStep{"open-pull-request", remoteName, "Open a pull request with the changes"})
+			case *np:
+				steps = append(steps, planStep{"commit", "HEAD", "Commit the changes locally"})
+			default:
+				steps = append(steps, planStep{"commit-and-push", remoteName, fmt.Sprintf("Commit and push the changes to %s", remoteName)})
+			}
+		}
+		if *cg {
+			steps = append(steps, planStep{"check-gate", "HEAD", "Wait for the CI checks of the release commit to pass"})
+		}
+		steps = append(steps, describePlugins(plugins, pluginGate)...)
+		addHook(hookPreTag)
+		if *np {
+			steps = append(steps, planStep{"tag-local", newVersion.String(), fmt.Sprintf("Create tag %s locally", newVersion)})
+		} else {
+			steps = append(steps, planStep{"tag", newVersion.String(), fmt.Sprintf("Create and push tag %s to %s", newVersion, remoteName)})
+		}
+		addHook(hookPostTag)
+		if ao.enabled() {
+			steps = append(steps, planStep{"artifacts", ao.Dist, fmt.Sprintf("Build the release artifacts into %s", ao.Dist)})
+		}
+		if *fd {
+			steps = append(steps, planStep{"feed", filepath.Join(*dd, feedFile), "Write the Atom feed of the releases"})
+		}
+		if *mf {
+			steps = append(steps, planStep{"manifest", filepath.Join(*dd, manifestFile), "Write the version manifest"})
+		}
+		if *di != "" {
+			steps = append(steps, planStep{"docker-image", *di, fmt.Sprintf("Build and push Docker image %s", *di)})
+		}
+		if *cr {
+			steps = append(steps, planStep{"release", newVersion.String(), fmt.Sprintf("Create the forge release (draft: %t)", *df)})
+			if *ci && !*df {
+				steps = append(steps, planStep{"comment-issues", newVersion.String(), "Comment on the issues fixed in the release"})
+			}
+			if !*df {
+				addHook(hookPostPublish)
+			}
+		}
+		if !*np {
+			steps = append(steps, describePlugins(plugins, pluginPublish)...)
+		}
+		if *lp {
+			steps = append(steps, planStep{"label-prs", "released: " + newVersion.String(), "Label the released pull requests"})
+		}
+		// A release that was not published, or only rehearsed, is not
+		// announced.
+		if !*np && !*sx {
+			steps = append(steps, describeNotifiers()...)
+		}
+		res.Plan = steps
+
+		// begin starts the named step, opening its log group in GitHub
+		// Actions, which res.step closes. It reports false for a step the
+		// resumed release completed already.
+		begin := func(name string) bool {
+			if resumeState.done(name) {
+				slog.Info("Skipping completed step", "step", name)
+				res.skip(name)
+				return false
+			}
+			res.start(name)
+			for _, s := range steps {
+				if s.Command == name {
+					startGroup(s.Effect)
+					break
+				}
+			}
+			return true
+		}
+
+		if planFile != "" {
+			if err := writePlan(planFile, fs, currentVersion, newVersion, steps); err != nil {
+				res.fail(exitFailure, "Failed to write plan: %v", err)
+			}
+			success("Wrote plan; review it, then run the apply command", "file", planFile, "steps", len(steps))
+			return
+		}
+		if plannedVersion != "" && newVersion.String() != plannedVersion {
+			res.fail(exitPreflight, "The plan is stale: it releases %s, but the next version is now %s", plannedVersion, newVersion)
+		}
+
+		// The progress of the release is saved after each step, for the
+		// resume command to continue from a failed one.
+		state := resumeState
+		if state == nil && !*dr {
+			if s, err := loadReleaseState(); err == nil {
+				res.fail(exitPreflight, "An interrupted release of %s exists; continue it with the resume command, or delete %s to start over", s.NewVersion, s.path)
+			}
+			state, err = newReleaseState(fs, currentVersion, newVersion, steps)
+			if err != nil {
+				res.fail(exitGit, "%v", err)
+			}
+		}
+		res.state = state
+
+		// Until the tag is out, a failure undoes the release; a resumed one
+		// is continued instead.
+		cleanup := !*nc && resumeState == nil
+
+		// hook runs the hooks of point, failing with code.
+		hook := func(point string, code int) {
+			step, ok := describeHooks(point)
+			switch {
+			case !ok:
+			case *dr:
+				slog.Info("DRY RUN MODE - Would run the hook", "hook", point)
+				for _, command := range configHooks[point] {
+					showCommand(shellCommand(command)...)
+				}
+			case begin(step.Command):
+				if err := runHooks(point, currentVersion.String(), newVersion.String()); err != nil {
+					res.fail(code, "%v", err)
+				}
+				res.step(step.Command)
+			}
+		}
+
+		// The version commit stages every modified file, so it must not
+		// find changes the release did not make.
+		if needsGoModUpdate && !resumeState.done("update-go-mod") {
+			changed, err := hasChanges()
+			if err == nil && changed {
+				err = ErrDirtyWorktree
+			}
+			if err != nil {
+				res.fail(exitPreflight, "Cannot update 'go.mod': %v; commit or stash the changes first", err)
+			}
+		}
+
+		// Show the changes to be committed so that their content, not just
+		// a description, is approved.
+		if needsGoModUpdate && plannedVersion == "" {
+			diff, err := moduleUpdateDiff(newVersion.Major)
+			if err != nil {
+				res.fail(exitFailure, "Failed to preview the 'go.mod' update: %v", err)
+			}
+			fmt.Fprintf(messages, "\nChanges to be committed:\n\n")
+			printDiff(diff)
+		}
+
+		// An applied plan was confirmed by the apply command.
+		if !*dr && plannedVersion == "" {
+			err = confirm(fmt.Sprintf("Releasing %s -> %s:", currentVersion, newVersion), stepEffects(steps))
+			if err != nil {
+				res.fail(exitPreflight, "%v", err)
+			}
+		}
+		switch {
+		case *ap == "":
+		case *dr:
+			slog.Info("DRY RUN MODE - Would wait for approval", "approval", *ap)
+		case *ab != "":
+			slog.Info("Release approved up front", "approver", *ab)
+			res.gate("approval", nil)
+		case resumeState == nil: // a resumed release was approved before
+			commit, err := resolveCommit("HEAD")
+			if err == nil {
+				err = waitForApproval(*ap, *fc, commit, newVersion.String(), *at)
+			}
+			res.gate("approval", err)
+			if err != nil {
+				res.fail(exitPreflight, "Release not approved: %v", err)
+			}
+		}
+		res.audit, res.auditPush = !*dr && !*na, !*np
+
+		if *fg && begin("files-gate") {
+			err := filesGate(splitList(*rf))
+			res.gate("files-gate", err)
+			if err != nil {
+				res.fail(exitPreflight, "Files gate failed: %v", err)
+			}
+			res.step("files-gate")
+		}
+
+		if *sg && begin("semver-gate") {
+			err := semverGate(bump, currentVersion)
+			switch {
+			case err == nil:
+				res.gate("semver-gate", nil)
+			case errors.Is(err, ErrSemverViolation) && *sv != "":
+				slog.Warn("Releasing despite the incompatible API changes", "justification", *sv)
+				res.overrideGate("semver-gate", err, *sv)
+			default:
+				res.gate("semver-gate", err)
+				res.fail(exitPreflight, "SemVer gate failed: %v", err)
+			}
+			res.step("semver-gate")
+		}
+
+		if coverage.enabled() && begin("coverage-gate") {
+			err := coverageGate(coverage, currentVersion)
+			res.gate("coverage-gate", err)
+			if err != nil {
+				res.fail(exitPreflight, "Coverage gate failed: %v", err)
+			}
+			res.step("coverage-gate")
+		}
+
+		if bench.Pattern != "" && begin("bench-gate") {
+			notes, err := benchmarkGate(bench, currentVersion, newVersion)
+			res.gate("bench-gate", err)
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrBenchmarkRegression) && bench.WarnOnly:
+				slog.Warn("Releasing despite the benchmark regressions", "err", err)
+			default:
+				res.fail(exitPreflight, "Benchmark gate failed: %v", err)
+			}
+			if state != nil {
+				state.Benchmarks = notes
+			}
+			res.step("bench-gate")
+		}
+
+		hook(hookPreBump, exitPreflight)
+
+		if needsGoModUpdate {
+			slog.Info("Major version bump detected - 'go.mod' needs update")
+			if *dr {
+				if _, module, err := modulePaths(newVersion.Major); err == nil {
+					edit, tidy := goModCommands(module)
+					showCommand(edit...)
+					showCommand(tidy...)
+				}
+			} else if begin("update-go-mod") {
+				if cleanup {
+					before, err := modifiedFiles()
+					if err != nil {
+						res.fail(exitGit, "%v", err)
+					}
+					res.onFailure("restore the files updated for the module path", func() error { return restoreFiles(before) })
+				}
+				err = updateGoModAndImports(newVersion.Major)
+				if err != nil {
+					res.fail(exitFailure, "Failed to update 'go.mod': %v", err)
+				}
+				res.step("update-go-mod")
+			}
+		}
+
+		// I am still not sure if this is the correct way of doing this. My though process:
+		//
+		// 1. If we want to release a new major version, update the go.mod file by
+		//    appending/increasing `/v${MAJOR_VERSION}` in the module name.
+		// 2. Push the updated 'go.mod' file to GitHub.
+		// 3. Tag & push
+		//
+		// Protected branches reject the push in step 2, so with -go-mod-pr the
+		// changes go through a pull request instead and the release pauses until
+		// it is merged. Re-running the same command on the merged branch finds
+		// 'go.mod' already updated and continues with tagging. With -auto-merge
+		// the release waits for the merge itself and tags the merge commit.
+		releaseCommit := "HEAD"
+		if state != nil && state.ReleaseCommit != "" {
+			releaseCommit = state.ReleaseCommit
+		}
+		if needsGoModUpdate && *vp {
+			if !*dr {
+				changed, err := hasChanges()
+				if err != nil {
+					res.fail(exitGit, "%v", err)
+				}
+				switch {
+				case state.done("open-pull-request"):
+					slog.Info("Skipping completed step", "step", "open-pull-request")
+					res.skip("open-pull-request")
+				case changed:
+					begin("open-pull-request")
+					number, url, err := openGoModPR(*fc, newVersion.String())
+					if err != nil {
+						res.fail(exitForge, "Failed to open pull request: %v", err)
+					}
+					state.PullRequest = number
+					// The changes left the repository with the pull request.
+					res.seal()
+					res.step("open-pull-request")
+					if !*am {
+						// Re-running the command continues the release.
+						state.remove()
+						slog.Info("Release paused until the pull request is merged", "url", url)
+						slog.Info("After merging, pull the base branch and re-run this command", "tag", newVersion.String())
+						return
+					}
+				default:
+					slog.Info("Module path already updated, continuing with tagging")
+				}
+				if *am && state.PullRequest != 0 && begin("auto-merge") {
+					releaseCommit, err = autoMergePR(*fc, state.PullRequest, *mt)
+					if err != nil {
+						res.fail(exitForge, "Failed to auto-merge pull request: %v", err)
+					}
+					state.ReleaseCommit = releaseCommit
+					res.step("auto-merge")
+				}
+			} else {
+				if *am {
+					slog.Info("DRY RUN MODE - Would open and auto-merge a pull request with the changes", "version", newVersion.String())
+				} else {
+					slog.Info("DRY RUN MODE - Would open a pull request with the changes", "version", newVersion.String())
+				}
+				branch := pullRequestBranch(newVersion.String())
+				add, commit := commitCommands(versionCommitMessage(newVersion.String()))
+				showCommand("git", "checkout", "-b", branch)
+				showCommand(add...)
+				showCommand(commit...)
+				showCommand(pushCommand(branch)...)
+				showRequests(*fc, apiOpenPullRequest, releaseRequest{Tag: newVersion.String()})
+				if *am {
+					showRequests(*fc, apiAutoMerge, releaseRequest{Tag: newVersion.String()})
+					showCommand("git", "fetch", remoteName, "{merge_commit}")
+				}
+			}
+		} else if needsGoModUpdate && *np {
+			if *dr {
+				slog.Info("DRY RUN MODE - Would commit changes locally", "version", newVersion.String())
+				add, commit := commitCommands(versionCommitMessage(newVersion.String()))
+				showCommand(add...)
+				showCommand(commit...)
+			} else if begin("commit") {
+				if cleanup {
+					res.onFailure("undo the version commit", func() error { return undoVersionCommit(newVersion.String()) })
+				}
+				if err := commitChanges(versionCommitMessage(newVersion.String())); err != nil {
+					res.fail(exitGit, "Failed to commit changes: %v", err)
+				}
+				res.step("commit")
+			}
+		} else if needsGoModUpdate {
+			if *dr {
+				slog.Info("DRY RUN MODE - Would commit and push changes", "version", newVersion.String())
+				add, commit := commitCommands(versionCommitMessage(newVersion.String()))
+				showCommand(add...)
+				showCommand(commit...)
+				showCommand(pushCommand("HEAD")...)
+			} else if begin("commit-and-push") {
+				if cleanup {
+					res.onFailure("undo the version commit", func() error { return undoVersionCommit(newVersion.String()) })
+				}
+				err = commitAndPush(versionCommitMessage(newVersion.String()))
+				if err != nil {
+					res.fail(exitGit, "Failed to push commit or push changes: %v", err)
+				}
+				res.step("commit-and-push")
+			}
+		}
+
+		if *cg && begin("check-gate") {
+			err = checkGate(*fc, releaseCommit, splitList(*rc), *ct)
+			res.gate("check-gate", err)
+			if err != nil {
+				res.fail(exitPreflight, "CI check gate failed: %v", err)
+			}
+			res.step("check-gate")
+		}
+
+		for _, p := range plugins {
+			if !p.can(pluginGate) {
+				continue
+			}
+			if *dr {
+				slog.Info("DRY RUN MODE - Would run the gate of plugin", "plugin", p.name)
+				showCommand(p.path)
+			} else if begin(p.step(pluginGate)) {
+				commit, err := resolveCommit(releaseCommit)
+				if err == nil {
+					_, err = p.call(pluginGate, pluginInfo(commit))
+				}
+				res.gate(p.step(pluginGate), err)
+				if err != nil {
+					res.fail(exitPreflight, "Plugin gate failed: %v", err)
+				}
+				res.step(p.step(pluginGate))
+			}
+		}
+
+		hook(hookPreTag, exitFailure)
+		switch {
+		case *dr && *np:
+			slog.Info("DRY RUN MODE - Would create tag locally", "tag", newVersion.String())
+			showCommand(tagCommand(newVersion.String(), releaseCommit)...)
+		case *dr:
+			slog.Info("DRY RUN MODE - Would create and push tag", "tag", newVersion.String())
+			showCommand(tagCommand(newVersion.String(), releaseCommit)...)
+			showCommand(pushCommand(newVersion.String())...)
+		case *np:
+			if begin("tag-local") {
+				if cleanup && !tagExists(newVersion.String()) {
+					res.onFailure("delete the tag", func() error { return deleteTag(newVersion.String()) })
+				}
+				if err := createTag(newVersion.String(), releaseCommit); err != nil {
+					res.fail(exitGit, "%v", err)
+				}
+				res.step("tag-local")
+			}
+			res.Commit, _ = tagCommit(newVersion.String())
+		default:
+			if begin("tag") {
+				if cleanup && !tagExists(newVersion.String()) {
+					res.onFailure("delete the tag", func() error { return deleteTag(newVersion.String()) })
+				}
+				err = createAndPushTag(newVersion.String(), releaseCommit)
+				if err != nil {
+					res.fail(exitGit, "Failed to push tag: %v", err)
+				}
+				res.step("tag")
+			}
+			res.Released = true
+			res.Commit, _ = tagCommit(newVersion.String())
+			res.emit(event{Type: eventTagPushed, Tag: res.Tag, Commit: res.Commit})
+		}
+		res.seal()
+		hook(hookPostTag, exitFailure)
+
+		var artifacts []artifact
+		if state != nil {
+			artifacts = state.Artifacts
+		}
+		if ao.enabled() {
+			if *dr {
+				describeArtifacts(ao, newVersion.String())
+			} else if begin("artifacts") {
+				artifacts, err = produceArtifacts(newVersion.String(), ao)
+				if err != nil {
+					res.fail(exitFailure, "Failed to build artifacts: %v", err)
+				}
+				state.Artifacts = artifacts
+				res.step("artifacts")
+			}
+		}
+
+		if *fd {
+			if *dr {
+				slog.Info("DRY RUN MODE - Would write the Atom feed of the releases", "file", filepath.Join(*dd, feedFile))
+			} else if begin("feed") {
+				feed, err := writeFeed(*fc, *dd)
+				if err != nil {
+					res.fail(exitFailure, "Failed to write the feed: %v", err)
+				}
+				artifacts = append(artifacts, feed)
+				state.Artifacts = artifacts
+				res.step("feed")
+			}
+		}
+
+		if *mf {
+			if *dr {
+				slog.Info("DRY RUN MODE - Would write the version manifest", "file", filepath.Join(*dd, manifestFile))
+			} else if begin("manifest") {
+				// The artifacts are linked to the assets of the forge
+				// release to be created.
+				var u assetUploader
+				if *cr {
+					if f, err := newLinkForge(*fc); err == nil {
+						u, _ = f.(assetUploader)
+					}
+				}
+				manifest, err := writeManifest(u, newVersion.String(), artifacts, *dd)
+				if err != nil {
+					res.fail(exitFailure, "Failed to write the manifest: %v", err)
+				}
+				artifacts = append(artifacts, manifest)
+				state.Artifacts = artifacts
+				res.step("manifest")
+			}
+		}
+
+		if *di != "" {
+			if *dr {
+				refs := dockerImageTags(*di, newVersion)
+				slog.Info("DRY RUN MODE - Would build and push Docker image", "refs", strings.Join(refs, ", "))
+				showCommand(dockerBuildCommand(refs, newVersion, *dk)...)
+				for _, ref := range refs {
+					showCommand(dockerPushCommand(ref)...)
+				}
+			} else if begin("docker-image") {
+				err = buildAndPushImage(newVersion.String(), newVersion, *di, *dk)
+				if err != nil {
+					res.fail(exitFailure, "Failed to build Docker image: %v", err)
+				}
+				res.step("docker-image")
+			}
+		}
+
+		if *cr {
+			if !*dr {
+				var notes []byte
+				if *nf != "" {
+					notes, err = os.ReadFile(*nf)
+					if err != nil {
+						res.fail(exitFailure, "Failed to read release notes: %v", err)
+					}
+				}
+				rr := releaseRequest{
+					Tag:                newVersion.String(),
+					PreviousTag:        currentVersion.String(),
+					Draft:              *df,
+					Prerelease:         newVersion.Pre != "",
+					Assets:             artifacts,
+					Notes:              strings.TrimSpace(strings.ReplaceAll(string(notes), "\r\n", "\n")),
+					NotesMode:          *nm,
+					DiscussionCategory: *dc,
+				}
+				if begin("release") {
+					sections, err := pluginNotesSections(plugins, pluginInfo(res.Commit))
+					if err != nil {
+						res.fail(exitFailure, "Failed to collect release notes sections: %v", err)
+					}
+					if state.Benchmarks != "" {
+						rr.Notes = strings.TrimSpace(rr.Notes + "\n\n" + state.Benchmarks)
+					}
+					if sections != "" {
+						rr.Notes = strings.TrimSpace(rr.Notes + "\n\n" + sections)
+					}
+					url, err := createForgeRelease(*fc, rr, *cm)
+					if err != nil {
+						res.fail(exitForge, "Failed to create release: %v", err)
+					}
+					state.ReleaseURL = url
+					res.step("release")
+				}
+				if !*df {
+					hook(hookPostPublish, exitFailure)
+				}
+				// Drafts are not visible yet; their issues are commented on by
+				// the publish command.
+				if *ci && !*df && begin("comment-issues") {
+					err = commentOnIssues(*fc, rr.Tag, rr.PreviousTag, state.ReleaseURL, *it)
+					if err != nil {
+						res.fail(exitForge, "Failed to comment on fixed issues: %v", err)
+					}
+					res.step("comment-issues")
+				}
+			} else {
+				slog.Info("DRY RUN MODE - Would create release", "tag", newVersion.String(), "draft", *df)
+				rr := releaseRequest{Tag: newVersion.String(), PreviousTag: currentVersion.String(), NotesMode: *nm}
+				showRequests(*fc, apiCreateRelease, rr)
+				if *ci && !*df {
+					slog.Info("DRY RUN MODE - Would comment on the fixed issues", "tag", newVersion.String())
+					showRequests(*fc, apiCommentIssue, rr)
+				}
+			}
+		}
+
+		for _, p := range plugins {
+			if !p.can(pluginPublish) || *np {
+				continue
+			}
+			if *dr {
+				slog.Info("DRY RUN MODE - Would run the publish step of plugin", "plugin", p.name)
+				showCommand(p.path)
+			} else if begin(p.step(pluginPublish)) {
+				if _, err := p.call(pluginPublish, pluginInfo(res.Commit)); err != nil {
+					res.fail(exitFailure, "Plugin publish step failed: %v", err)
+				}
+				res.step(p.step(pluginPublish))
+			}
+		}
+
+		if *lp {
+			if *dr {
+				slog.Info("DRY RUN MODE - Would label released pull requests", "label", "released: "+newVersion.String())
+				showRequests(*fc, apiLabelPR, releaseRequest{Tag: newVersion.String()})
+			} else if begin("label-prs") {
+				err = labelPRs(*fc, newVersion.String(), currentVersion.String())
+				if err != nil {
+					res.fail(exitForge, "Failed to label pull requests: %v", err)
+				}
+				res.step("label-prs")
+			}
+		}
+
+		if !*np && !*sx {
+			var (
+				a         announcement
+				announced bool
+			)
+			for _, n := range configNotifiers {
+				if *dr {
+					slog.Info("DRY RUN MODE - Would announce the release", "notifier", n.name, "type", n.kind)
+				} else if begin(n.step()) {
+					if !announced {
+						a, err = newAnnouncement(*fc, currentVersion.String(), newVersion.String(), res.Commit, state.ReleaseURL)
+						if err != nil {
+							res.fail(exitGit, "Failed to describe the release: %v", err)
+						}
+						announced = true
+					}
+					if err := n.notify(a); err != nil {
+						res.fail(exitFailure, "Failed to announce the release with %s: %v", n.name, err)
+					}
+					res.step(n.step())
+				}
+			}
+		}
+
+		if *dr {
+			success("DRY RUN MODE - Complete!", "version", newVersion.String())
+		}
+		state.remove()
+
+		if needsGoModUpdate && !*dr {
+			slog.Info("Module path updated for major version bump")
+		}
+		if *np && !*dr && !*sx {
+			slog.Info("Nothing was pushed; to publish the release, run", "command", fmt.Sprintf("git push %s HEAD %s", remoteName, newVersion))
+		}
+	}
+}
+
+func runPublish(fs *flag.FlagSet) func() {
+	var (
+		tag    = fs.String("tag", "", "Tag of the draft release to publish (defaults to the latest version tag)")
+		latest = fs.Bool("latest", false, "Mark the published release as the latest release, or with -latest=false not (default: the newest stable release is the latest)")
+		dr     = fs.Bool("dry-run", false, "Show what would be done without making changes")
+		ci     = fs.Bool("comment-issues", true, "Comment on the issues fixed in the release")
+		it     = fs.String("issue-comment-template", defaultIssueCommentTemplate, "Template of the fixed-issue comment; fields: .Tag, .URL, .Issue")
+		of     = fs.String("output", outputText, "Output format: text, or json to print the result as a JSON object on stdout")
+		de     = fs.String("dotenv", "", "Also write the result to this file as a dotenv report, e.g. for GitLab CI's artifacts:reports:dotenv")
+		ev     = fs.String("events", "", "Write the run's events (steps started and finished) to this file as JSON lines, e.g. to drive a UI")
+	)
+	fc := &forgeFlags
+
+	return func() {
+		res, err := newRunResult(*of, os.Stdout, os.Stderr)
+		if err != nil {
+			res.fail(exitUsage, "%v", err)
+		}
+		defer res.finish()
+		res.DryRun = *dr
+		res.dotenv = *de
+		if *ev != "" {
+			f, err := os.Create(*ev)
+			if err != nil {
+				res.fail(exitUsage, "Cannot write events: %v", err)
+			}
+			res.observe(eventLog(f))
+		}
+
+		if *tag == "" {
+			currentVersion, err := getCurrentVersion()
+			if err != nil {
+				res.fail(exitGit, "Could not retrieve current version: %v", err)
+			}
+			*tag = currentVersion.String()
+		}
+		previousTag, err := previousVersionTag(*tag)
+		if err != nil {
+			res.fail(exitUsage, "Invalid tag: %v", err)
+		}
+		res.Tag, res.NewVersion, res.PreviousVersion = *tag, versionNumber(*tag), versionNumber(previousTag)
+
+		// Unless -latest is given, the forge decides.
+		makeLatest := ""
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "latest" {
+				makeLatest = strconv.FormatBool(*latest)
+			}
+		})
+		if v, err := parseVersion(*tag); err == nil && v.Pre != "" && *latest {
+			slog.Info("Release is a prerelease, not marking it as latest", "tag", *tag)
+			makeLatest = "false"
+		}
+
+		if *dr {
+			slog.Info("DRY RUN MODE - Would publish release", "tag", *tag, "latest", makeLatest)
+			showRequests(*fc, apiPublishRelease, releaseRequest{Tag: *tag})
+			if *ci {
+				showRequests(*fc, apiCommentIssue, releaseRequest{Tag: *tag})
+			}
+			return
+		}
+
+		latestEffect := makeLatest
+		if latestEffect == "" {
+			latestEffect = "if newest"
+		}
+		steps := []string{fmt.Sprintf("Publish release %s (latest: %s)", *tag, latestEffect)}
+		if *ci {
+			steps = append(steps, "Comment on the issues fixed in the release")
+		}
+		if err := confirm(fmt.Sprintf("Publishing %s:", *tag), steps); err != nil {
+			res.fail(exitPreflight, "%v", err)
+		}
+
+		f, err := newForge(*fc)
+		if err != nil {
+			res.fail(exitForge, "%v", err)
+		}
+
+		res.start("publish")
+		url, err := f.publishRelease(*tag, makeLatest)
+		if err != nil {
+			res.fail(exitForge, "Failed to publish release: %v", err)
+		}
+		success("Published release", "url", url)
+		res.Released = true
+		res.step("publish")
+
+		if step, ok := describeHooks(hookPostPublish); ok {
+			res.start(step.Command)
+			if err := runHooks(hookPostPublish, previousTag, *tag); err != nil {
+				res.fail(exitFailure, "%v", err)
+			}
+			res.step(step.Command)
+		}
+
+		if *ci {
+			res.start("comment-issues")
+			if err := commentOnFixedIssues(f, *tag, previousTag, url, *it); err != nil {
+				res.fail(exitForge, "Failed to comment on fixed issues: %v", err)
+			}
+			res.step("comment-issues")
+		}
+	}
+}
+
+// releasedAtHead returns the version tag at HEAD if it is the one a release
+// with bump and prerelease would create were it missing, and the module
+// path matches it: the release was completed already.
+func releasedAtHead(tags []string, bump BumpType, prerelease string) (version, bool) {
+	output, err := newCommand("git", "tag", "--points-at", "HEAD").Output()
+	if err != nil {
+		return version{}, false
+	}
+	for _, tag := range strings.Fields(string(output)) {
+		v, err := parseVersion(tag)
+		if err != nil || !slices.Contains(tags, tag) {
+			continue
+		}
+		others := slices.DeleteFunc(slices.Clone(tags), func(t string) bool { return t == tag })
+		if nextVersion(others, bump, prerelease).String() != v.String() {
+			continue
+		}
+		module, err := newCommand("go", "list", "-m").Output()
+		if err != nil || checkModulePath(strings.TrimSpace(string(module)), v.Major) != nil {
+			continue
+		}
+		return v, true
+	}
+	return version{}, false
+}
+
+func getVersionTags() ([]string, error) {
+	cmd := newCommand("git", "tag", "-l")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	return strings.Fields(string(output)), nil
+}
+
+// getCurrentVersion returns the latest version of the local tags, or
+// ErrNoTags if there is none.
+func getCurrentVersion() (version, error) {
+	tags, err := getVersionTags()
+	if err != nil {
+		return version{}, err
+	}
+
+	latest := latestVersion(tags)
+	if latest == (version{}) {
+		return version{}, ErrNoTags
+	}
+	return latest, nil
+}
+
+// listVersionTags lists the tags of source: "git" for the local tags, or
+// "forge" for the forge's.
+func listVersionTags(source string, fc forgeConfig) ([]string, error) {
+	switch source {
+	case "git":
+		return getVersionTags()
+	case "forge":
+		return getForgeVersionTags(fc)
+	default:
+		return nil, fmt.Errorf("invalid version source '%s', must be 'git' or 'forge'", source)
+	}
+}
+
+// versionSourceExitCode is the exit code of a failure of listVersionTags.
+func versionSourceExitCode(source string) int {
+	switch source {
+	case "git":
+		return exitGit
+	case "forge":
+		return exitForge
+	default:
+		return exitUsage
+	}
+}
+
+// getForgeVersionTags lists the tags known to the forge, so that shallow or
+// detached checkouts without the full tag history can be released.
+func getForgeVersionTags(fc forgeConfig) ([]string, error) {
+	f, err := newForge(fc)
+	if err != nil {
+		return nil, err
+	}
+
+	tl, ok := f.(tagLister)
+	if !ok {
+		return nil, fmt.Errorf("listing tags is not supported on %s", f.name())
+	}
+
+	tags, err := tl.listTags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func updateGoModAndImports(newMajor int) error {
+	currentModule, newModule, err := modulePaths(newMajor)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Updating module path", "from", currentModule, "to", newModule)
+
+	edit, tidy := goModCommands(newModule)
+	if err := execCommand(edit).Run(); err != nil {
+		return fmt.Errorf("failed to update go.mod: %w", err)
+	}
+
+	if err := execCommand(tidy).Run(); err != nil {
+		return fmt.Errorf("failed to run go mod tidy: %w", err)
+	}
+
+	files, err := findFilesUsingModule(currentModule)
+	if err != nil {
+		return fmt.Errorf("failed to find files using module %s: %w", currentModule, err)
+	}
+
+	err = updateImportsInFiles(files, currentModule, newModule)
+	if err != nil {
+		return fmt.Errorf("failed to update imports in files: %w", err)
+	}
+
+	return nil
+}
+
+// goModCommands returns the commands updating go.mod for module path
+// module: editing the path, and tidying the requirements.
+func goModCommands(module string) (edit, tidy []string) {
+	return []string{"go", "mod", "edit", "-module=" + module}, []string{"go", "mod", "tidy"}
+}
+
+// modulePaths returns the current module path and the one of major version
+// newMajor.
+func modulePaths(newMajor int) (current, next string, err error) {
+	output, err := newCommand("go", "list", "-m").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get module name: %w", err)
+	}
+	current = strings.TrimSpace(string(output))
+	return current, release.ModulePath(current, newMajor), nil
+}
+
+// moduleUpdateDiff previews updateGoModAndImports as a unified diff of
+// go.mod and the files importing the module. The changes of go mod tidy
+// are not predicted.
+func moduleUpdateDiff(newMajor int) (string, error) {
+	currentModule, newModule, err := modulePaths(newMajor)
+	if err != nil {
+		return "", err
+	}
+	files, err := findFilesUsingModule(currentModule)
+	if err != nil {
+		return "", err
+	}
+
+	moduleLine := regexp.MustCompile(`(?m)^module[ \t]+` + regexp.QuoteMeta(currentModule) + `[ \t]*$`)
+	var b strings.Builder
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		old := string(data)
+		updated := string(release.ReplaceModulePath(data, currentModule, newModule))
+		if filepath.Base(path) == "go.mod" && filepath.Dir(path) == "." {
+			updated = moduleLine.ReplaceAllString(updated, "module "+newModule)
+		}
+		b.WriteString(unifiedDiff(filepath.ToSlash(path), old, updated))
+	}
+	return b.String(), nil
+}
+
+// versionCommitMessage is the message of the commit updating the module
+// path for version, which the rollback command looks for.
+func versionCommitMessage(version string) string {
+	return fmt.Sprintf("chore: update module path and related files for %s", version)
+}
+
+// commitAndPush commits the modified files with message and pushes the
+// commit to the current branch.
+func commitAndPush(message string) error {
+	if err := commitChanges(message); err != nil {
+		return err
+	}
+
+	p := startProgress("Pushing changes", "remote", remoteName)
+	err := runPush(pushCommand("HEAD"))
+	p.stop()
+	if err != nil {
+		return fmt.Errorf("failed to push changes: %w", err)
+	}
+
+	slog.Info("Pushed changes", "remote", remoteName)
+	return nil
+}
+
+// commitChanges commits the modified files with message, if any.
+func commitChanges(message string) error {
+	cmd := newCommand("git", "status", "--porcelain", "--", ":(top,exclude)"+stateFile)
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to check git status: %w", err)
+	}
+
+	if len(output) == 0 {
+		slog.Info("No changes to commit")
+		return nil
+	}
+
+	slog.Info("Detected changes", "files", strings.TrimSpace(string(output)))
+
+	add, commit := commitCommands(message)
+	if err := execCommand(add).Run(); err != nil {
+		return fmt.Errorf("failed to git add modified files: %w", err)
+	}
+
+	// TODO: Double check to make sure there are not new files and exit with an error code?
+
+	if err := execCommand(commit).Run(); err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+
+	slog.Info("Committed changes", "message", message)
+	return nil
+}
+
+// commitCommands returns the commands committing the modified files with
+// message. `git add -u` only stages modified files, not new ones.
+func commitCommands(message string) (add, commit []string) {
+	return []string{"git", "add", "-u"}, []string{"git", "commit", "-m", message}
+}
+
+// pushCommand returns the command pushing ref to the remote.
+func pushCommand(ref string) []string {
+	return []string{"git", "push", remoteName, ref}
+}
+
+// tagCommand returns the command tagging commit as version.
+func tagCommand(version, commit string) []string {
+	return []string{"git", "tag", version, commit}
+}
+
+// execCommand returns the command running args.
+func execCommand(args []string) *exec.Cmd {
+	return newCommand(args[0], args[1:]...)
+}
+
+// checkBranch fails unless the current branch is branch.
+func checkBranch(branch string) error {
+	output, err := newCommand("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return fmt.Errorf("failed to get current branch: %w", err)
+	}
+
+	current := strings.TrimSpace(string(output))
+	if current != branch {
+		return fmt.Errorf("releases must be made from branch %s, not %s", branch, current)
+	}
+	return nil
+}
+
+func hasChanges() (bool, error) {
+	cmd := newCommand("git", "status", "--porcelain", "--", ":(top,exclude)"+stateFile)
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to check git status: %w", err)
+	}
+	return len(output) > 0, nil
+}
+
+// pullRequestBranch returns the branch of the pull request updating the
+// module path for version.
+func pullRequestBranch(version string) string {
+	return "release/" + version
+}
+
+// openGoModPR commits the module path changes to a release branch, pushes
+// it, and opens a pull request against the current branch.
+func openGoModPR(fc forgeConfig, version string) (int, string, error) {
+	f, err := newForge(fc)
+	if err != nil {
+		return 0, "", err
+	}
+
+	cmd := newCommand("git", "rev-parse", "--abbrev-ref", "HEAD")
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to determine current branch: %w", err)
+	}
+	base := strings.TrimSpace(string(output))
+	branch := pullRequestBranch(version)
+
+	cmd = newCommand("git", "checkout", "-b", branch)
+	if err := cmd.Run(); err != nil {
+		return 0, "", fmt.Errorf("failed to create branch %s: %w", branch, err)
+	}
+	defer func() {
+		if err := newCommand("git", "checkout", base).Run(); err != nil {
+			slog.Warn("Failed to check out the original branch", "branch", base, "err", err)
+		}
+	}()
+
+	commitMsg := versionCommitMessage(version)
+	add, commit := commitCommands(commitMsg)
+	if err := execCommand(add).Run(); err != nil {
+		return 0, "", fmt.Errorf("failed to git add modified files: %w", err)
+	}
+
+	if err := execCommand(commit).Run(); err != nil {
+		return 0, "", fmt.Errorf("failed to commit changes: %w", err)
+	}
+
+	if err := runPush(pushCommand(branch)); err != nil {
+		return 0, "", fmt.Errorf("failed to push branch %s: %w", branch, err)
+	}
+
+	slog.Info("Pushed branch", "branch", branch)
+
+	body := fmt.Sprintf("Updates the module path for the %s major release.\n\nThe release is tagged once this pull request is merged.", version)
+	number, url, err := f.openPullRequest(branch, base, commitMsg, body)
+	if err != nil {
+		return 0, "", err
+	}
+
+	slog.Info("Opened pull request", "url", url)
+	return number, url, nil
+}
+
+// autoMergePR enables auto-merge on pull request number, waits until it is
+// merged, and fetches and returns the resulting merge commit.
+func autoMergePR(fc forgeConfig, number int, timeout time.Duration) (string, error) {
+	f, err := newForge(fc)
+	if err != nil {
+		return "", err
+	}
+
+	m, ok := f.(autoMerger)
+	if !ok {
+		return "", fmt.Errorf("auto-merge is not supported on %s", f.name())
+	}
+
+	if err := m.enableAutoMerge(number); err != nil {
+		return "", err
+	}
+
+	slog.Info("Enabled auto-merge", "number", number)
+
+	commit, err := waitForMerge(m, number, timeout)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := newCommand("git", "fetch", remoteName, commit)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to fetch merge commit %s: %w", commit, err)
+	}
+
+	success("Pull request merged", "number", number, "commit", commit)
+	return commit, nil
+}
+
+func createAndPushTag(version, commit string) error {
+	if err := createTag(version, commit); err != nil {
+		return err
+	}
+
+	p := startProgress("Pushing tag", "tag", version)
+	err := runPush(pushCommand(version))
+	p.stop()
+	if err != nil {
+		return fmt.Errorf("failed to push tag: %w", err)
+	}
+
+	success("Pushed tag", "tag", version)
+	return nil
+}
+
+// createTag tags commit as version locally. A tag of version at commit
+// already, e.g. by a resumed release, is kept.
+func createTag(version, commit string) error {
+	if tagExists(version) {
+		existing, err := tagCommit(version)
+		if err != nil {
+			return err
+		}
+		target, err := resolveCommit(commit)
+		if err != nil {
+			return err
+		}
+		if existing != target {
+			return fmt.Errorf("%w at another commit: %s", ErrTagExists, version)
+		}
+		slog.Info("Tag already exists", "tag", version)
+		return nil
+	}
+
+	if err := execCommand(tagCommand(version, commit)).Run(); err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	slog.Info("Created tag", "tag", version)
+	return nil
+}
+
+func checkGate(fc forgeConfig, ref string, required []string, timeout time.Duration) error {
+	f, err := newForge(fc)
+	if err != nil {
+		return err
+	}
+
+	commit, err := resolveCommit(ref)
+	if err != nil {
+		return err
+	}
+
+	return waitForChecks(f, commit, required, timeout)
+}
+
+func labelPRs(fc forgeConfig, tag, previousTag string) error {
+	f, err := newForge(fc)
+	if err != nil {
+		return err
+	}
+	return labelReleasedPRs(f, tag, previousTag)
+}
+
+func createForgeRelease(fc forgeConfig, rr releaseRequest, closeMilestone bool) (string, error) {
+	f, err := newForge(fc)
+	if err != nil {
+		return "", err
+	}
+
+	var ms *milestone
+	if closeMilestone {
+		ms, err = findVersionMilestone(f, rr.Tag)
+		if err != nil {
+			return "", err
+		}
+		if ms != nil {
+			rr.Notes = strings.TrimSpace(fmt.Sprintf("%s\n\nMilestone: [%s](%s)", rr.Notes, ms.Title, ms.URL))
+		}
+	}
+
+	url, err := f.createRelease(rr)
+	if err != nil {
+		return "", err
+	}
+
+	if rr.Draft {
+		success("Created draft release", "forge", f.name(), "url", url)
+	} else {
+		success("Created release", "forge", f.name(), "url", url)
+	}
+
+	if len(rr.Assets) > 0 {
+		u, ok := f.(assetUploader)
+		if !ok {
+			return "", fmt.Errorf("uploading release assets is not supported on %s", f.name())
+		}
+		for _, a := range rr.Assets {
+			p := startProgress("Uploading " + a.Name)
+			err := u.uploadAsset(rr.Tag, a)
+			p.stop()
+			if err != nil {
+				return "", fmt.Errorf("failed to upload %s: %w", a.Name, err)
+			}
+			slog.Info("Uploaded asset", "name", a.Name)
+		}
+	}
+
+	if ms != nil {
+		if err := f.(milestoneManager).closeMilestone(ms); err != nil {
+			return "", fmt.Errorf("failed to close milestone %s: %w", ms.Title, err)
+		}
+		slog.Info("Closed milestone", "url", ms.URL)
+	}
+	return url, nil
+}
+
+func commentOnIssues(fc forgeConfig, tag, previousTag, releaseURL, tmpl string) error {
+	f, err := newForge(fc)
+	if err != nil {
+		return err
+	}
+	return commentOnFixedIssues(f, tag, previousTag, releaseURL, tmpl)
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// findFilesUsingModule lists the files of the module in the current
+// directory that mention oldModule: go.mod, Go files, and other text files
+// such as documentation. Hidden, vendor, and dist directories are skipped,
+// and so are nested modules, which have module paths of their own. It walks
+// the tree itself rather than running grep, which Windows runners lack.
+func findFilesUsingModule(oldModule string) ([]string, error) {
+	var files []string
+	err := release.WalkModule(".", func(path string, d os.DirEntry) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(data, []byte(oldModule)) {
+			return nil
+		}
+		if d.Name() == "go.mod" || filepath.Ext(path) == ".go" || isText(data) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("searching files: %w", err)
+	}
+
+	slog.Info("Found files to update", "count", len(files))
+	return files, nil
+}
+
+// isText reports whether data looks like the content of a text file: UTF-8
+// without NUL bytes, which binaries and archives are full of.
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+func updateImportsInFiles(files []string, oldModule, newModule string) error {
+	for _, path := range files {
+		input, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		if !bytes.Contains(input, []byte(oldModule)) {
+			continue // sanity check
+		}
+
+		output := release.ReplaceModulePath(input, oldModule, newModule)
+		if err := os.WriteFile(path, output, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		slog.Debug("Updated imports", "path", path)
+	}
+	return nil
+}
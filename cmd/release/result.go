@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats of the release command.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// runResult is the outcome of a release run, printed as a single JSON
+// object at the end of the run with -output=json.
+type runResult struct {
+	PreviousVersion string       `json:"previous_version"`
+	NewVersion      string       `json:"new_version"`
+	Tag             string       `json:"tag"`
+	Commit          string       `json:"commit,omitempty"`
+	DryRun          bool         `json:"dry_run"`
+	Released        bool         `json:"released"`
+	Steps           []string     `json:"steps"`
+	Plan            []planStep   `json:"plan"` // steps the run was to make; all a dry run shows
+	Errors          []string     `json:"errors"`
+	CleanedUp       bool         `json:"cleaned_up,omitempty"` // the failed run undid its commit and tag
+	Gates           []gateResult `json:"gates,omitempty"`
+	Timings         []stepTiming `json:"timings"`
+	Seconds         float64      `json:"seconds"`           // duration of the run
+	Sandbox         string       `json:"sandbox,omitempty"` // directory of the -sandbox clones
+
+	format    string
+	stdout    io.Writer // receives the result in JSON mode, and the summary
+	stderr    io.Writer
+	dotenv    string // path of the dotenv report, if any
+	state     *releaseState
+	current   string // step running
+	undo      []compensation
+	unlock    func() // releases the release lock
+	audit     bool   // record the run in the audit log
+	auditPush bool   // and push the record
+	started   time.Time
+	running   bool // the current step is running
+	stepStart time.Time
+	observers []observer
+	trace     *tracer // exported when the run finishes
+	bump      string
+	metricsTo metricsTargets
+}
+
+// Results of a step in its timing.
+const (
+	stepOK      = "ok"
+	stepFailed  = "failed"
+	stepSkipped = "skipped" // completed by the resumed run
+)
+
+// stepTiming is the result and duration of a step of the run.
+type stepTiming struct {
+	Name    string  `json:"name"`
+	Result  string  `json:"result"`
+	Seconds float64 `json:"seconds"`
+}
+
+// newRunResult returns the result of a run printed in format on stdout.
+// With JSON output the messages of the run are written to stderr, so that
+// stdout carries only the result.
+func newRunResult(format string, stdout, stderr io.Writer) (*runResult, error) {
+	r := &runResult{Steps: []string{}, Plan: []planStep{}, Errors: []string{}, Timings: []stepTiming{}, format: format, stdout: stdout, stderr: stderr, started: time.Now()}
+	switch format {
+	case outputText:
+		messages = stdout
+	case outputJSON:
+		messages = stderr
+	default:
+		return r, fmt.Errorf("invalid output format '%s', must be 'text' or 'json'", format)
+	}
+	return r, nil
+}
+
+// start records that the named step started, or fails the run if it was
+// interrupted, so that no step starts after SIGINT or SIGTERM.
+func (r *runResult) start(name string) {
+	r.current, r.running, r.stepStart = name, true, time.Now()
+	r.emit(event{Type: eventStepStarted, Step: name})
+	if interrupted() != nil {
+		r.fail(exitInterrupted, "Stopped before the %s step", name)
+	}
+}
+
+// skip records that the named step is skipped, as the resumed release
+// completed it.
+func (r *runResult) skip(name string) {
+	r.Timings = append(r.Timings, stepTiming{Name: name, Result: stepSkipped})
+	r.emit(event{Type: eventStepFinished, Step: name, Result: stepSkipped})
+}
+
+// endStep records the timing of the running step with result.
+func (r *runResult) endStep(result string) {
+	if r.running {
+		r.running = false
+		t := stepTiming{r.current, result, time.Since(r.stepStart).Seconds()}
+		r.Timings = append(r.Timings, t)
+		r.emit(event{Type: eventStepFinished, Step: t.Name, Result: t.Result, Seconds: t.Seconds})
+	}
+}
+
+// step records that the named step completed, closing its log group, and
+// saves the progress of the release.
+func (r *runResult) step(name string) {
+	endGroup()
+	r.endStep(stepOK)
+	r.Steps = append(r.Steps, name)
+	if r.state != nil {
+		r.state.Completed = append(r.state.Completed, name)
+		r.state.Failed, r.state.Error = "", ""
+		r.state.save()
+	}
+}
+
+// fail reports an error and exits with code after printing the result.
+// A failure during the commit, tag, and push sequence undoes it. Otherwise,
+// once a step has pushed commits but not the tag, the code is exitPartial
+// instead, with a hint to roll the release back; once the tag is pushed,
+// the release is out and the code is exitIncomplete, with a hint to resume
+// it.
+func (r *runResult) fail(code int, format string, args ...any) {
+	msg := redact(fmt.Sprintf(format, args...))
+	if err := interrupted(); err != nil {
+		msg += " (" + err.Error() + ")"
+		code = exitInterrupted
+	} else if timedOut() {
+		msg += fmt.Sprintf(" (the run timed out after %s)", runTimeout)
+	}
+	liftCancel()
+	endGroup()
+	r.endStep(stepFailed)
+	slog.Error(msg)
+	r.Errors = append(r.Errors, msg)
+	if len(r.undo) > 0 && r.compensate() {
+		r.CleanedUp = true
+		r.state.remove()
+		slog.Info("Cleaned up the failed release; the repository is as before it", "tag", r.Tag)
+		r.finish()
+		os.Exit(code)
+	}
+	resumable := r.state != nil && r.current != ""
+	if resumable {
+		r.state.Failed, r.state.Error = r.current, msg
+		r.state.save()
+	}
+	switch {
+	case r.Released:
+		if resumable {
+			slog.Error("The release is published but incomplete; finish it with the resume command", "tag", r.Tag, "step", r.current)
+		} else {
+			slog.Error("The release is published, but a later step failed", "tag", r.Tag)
+		}
+		code = exitIncomplete
+	case r.pushed():
+		slog.Error("The release is incomplete; undo it with the rollback command", "tag", r.Tag)
+		code = exitPartial
+	case resumable:
+		slog.Info("Continue the release from the failed step with the resume command", "step", r.current)
+	}
+	r.finish()
+	os.Exit(code)
+}
+
+// gate records the outcome of the named gate.
+func (r *runResult) gate(name string, err error) {
+	g := gateResult{Name: name, Passed: err == nil}
+	if err != nil {
+		g.Error = redact(err.Error())
+	}
+	r.Gates = append(r.Gates, g)
+}
+
+// overrideGate records the failure of the named gate, which the release
+// proceeds despite for justification.
+func (r *runResult) overrideGate(name string, err error, justification string) {
+	r.gate(name, err)
+	r.Gates[len(r.Gates)-1].Justification = justification
+}
+
+// pushed reports whether a completed step pushed commits to the remote.
+// Once the tag is pushed too, the run is Released instead.
+func (r *runResult) pushed() bool {
+	for _, s := range r.Steps {
+		switch s {
+		case "commit-and-push", "auto-merge":
+			return true
+		}
+	}
+	return false
+}
+
+// finish records the run in the audit log, releases the release lock,
+// pushes the metrics, exports the trace, and publishes the result: as GitHub Actions step outputs when running in
+// Actions, as a dotenv report with -dotenv, and on stdout in JSON mode.
+func (r *runResult) finish() {
+	liftCancel()
+	if r.audit {
+		r.audit = false
+		if err := r.recordAudit(); err != nil {
+			slog.Warn("Failed to record the run in the audit log", "err", err)
+		}
+	}
+	if r.unlock != nil {
+		r.unlock()
+		r.unlock = nil
+	}
+	r.Seconds = time.Since(r.started).Seconds()
+	if !r.DryRun && r.metricsTo != (metricsTargets{}) {
+		r.pushMetrics(r.metricsTo)
+	}
+	if r.trace != nil {
+		if err := r.trace.export(r); err != nil {
+			slog.Warn("Failed to export the trace", "err", err)
+		}
+		r.trace = nil
+	}
+	if r.Sandbox != "" {
+		slog.Info("Rehearsed the release in a sandbox; the repository and its remote are untouched", "sandbox", r.Sandbox)
+	}
+	if r.format == outputText && !quiet && !r.DryRun && len(r.Timings) > 0 {
+		r.printSummary()
+	}
+	if path := os.Getenv("GITHUB_OUTPUT"); path != "" {
+		if err := r.writeGitHubOutput(path); err != nil {
+			slog.Warn("Failed to write step outputs", "err", err)
+		}
+	}
+	if r.dotenv != "" {
+		if err := r.writeDotenv(r.dotenv); err != nil {
+			slog.Warn("Failed to write dotenv report", "err", err)
+		}
+	}
+
+	if r.format != outputJSON {
+		return
+	}
+	enc := json.NewEncoder(r.stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(r)
+}
+
+// printSummary prints the result and duration of each step.
+func (r *runResult) printSummary() {
+	fmt.Fprintf(r.stdout, "\nSummary:\n\n")
+	w := tabwriter.NewWriter(r.stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  STEP\tRESULT\tDURATION")
+	for _, t := range r.Timings {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", t.Name, t.Result, formatSeconds(t.Seconds))
+	}
+	fmt.Fprintf(w, "  total\t\t%s\n", formatSeconds(r.Seconds))
+	w.Flush()
+}
+
+// formatSeconds formats a duration in seconds, rounded for display.
+func formatSeconds(s float64) string {
+	d := time.Duration(s * float64(time.Second))
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// writeGitHubOutput appends the result to the GitHub Actions output file
+// at path, for later steps to read as steps.<id>.outputs.<name>.
+func (r *runResult) writeGitHubOutput(path string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(f, "new_version=%s\n", r.NewVersion)
+	fmt.Fprintf(f, "previous_version=%s\n", r.PreviousVersion)
+	fmt.Fprintf(f, "tag=%s\n", r.Tag)
+	fmt.Fprintf(f, "released=%t\n", r.Released)
+	return f.Close()
+}
+
+// writeDotenv writes the result to the dotenv file at path, which GitLab
+// CI passes on to later jobs as variables when it is declared as an
+// artifacts:reports:dotenv report.
+func (r *runResult) writeDotenv(path string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "RELEASE_NEW_VERSION=%s\n", r.NewVersion)
+	fmt.Fprintf(&b, "RELEASE_PREVIOUS_VERSION=%s\n", r.PreviousVersion)
+	fmt.Fprintf(&b, "RELEASE_TAG=%s\n", r.Tag)
+	fmt.Fprintf(&b, "RELEASE_RELEASED=%t\n", r.Released)
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
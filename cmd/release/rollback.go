@@ -0,0 +1,214 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// runRollback undoes a release that failed partway: it deletes the tag
+// locally and on the remote and reverts the commit updating the module
+// path, so the release can be retried from scratch.
+func runRollback(fs *flag.FlagSet) func() {
+	var (
+		tag  = fs.String("tag", "", "Tag to delete")
+		keep = fs.Bool("keep-commit", false, "Do not revert the commit updating the module path for the tag")
+		dr   = fs.Bool("dry-run", false, "Show what would be done without making changes")
+	)
+
+	return func() {
+		if *tag == "" {
+			fs.Usage()
+			slog.Error("-tag flag is required")
+			os.Exit(exitUsage)
+		}
+
+		remote, err := remoteTagExists(*tag)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitGit)
+		}
+		local := tagExists(*tag)
+
+		var vc versionCommit
+		if !*keep {
+			vc, err = findVersionCommit(*tag)
+			if err != nil {
+				slog.Error(err.Error())
+				os.Exit(exitGit)
+			}
+		}
+
+		if !local && !remote && vc.sha == "" {
+			slog.Info("Neither the tag nor its version commit exist, nothing to roll back", "tag", *tag)
+			if !*dr {
+				removeReleaseState(*tag)
+			}
+			return
+		}
+
+		var steps []string
+		if remote {
+			steps = append(steps, fmt.Sprintf("Delete tag %s from %s", *tag, remoteName))
+		}
+		if local {
+			steps = append(steps, fmt.Sprintf("Delete local tag %s", *tag))
+		}
+		if vc.sha != "" {
+			steps = append(steps, vc.describe())
+		}
+
+		if *dr {
+			for _, s := range steps {
+				slog.Info("DRY RUN MODE - Would " + strings.ToLower(s[:1]) + s[1:])
+			}
+			return
+		}
+
+		if err := confirm(fmt.Sprintf("Rolling back %s:", *tag), steps); err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitPreflight)
+		}
+
+		if remote {
+			if err := newCommand("git", "push", remoteName, "--delete", "refs/tags/"+*tag).Run(); err != nil {
+				slog.Error("Failed to delete remote tag", "err", err)
+				os.Exit(exitGit)
+			}
+			slog.Info("Deleted remote tag", "tag", *tag, "remote", remoteName)
+		}
+
+		if local {
+			if err := newCommand("git", "tag", "-d", *tag).Run(); err != nil {
+				slog.Error("Failed to delete local tag", "err", err)
+				os.Exit(exitGit)
+			}
+			slog.Info("Deleted local tag", "tag", *tag)
+		}
+
+		if vc.sha != "" {
+			if err := vc.undo(); err != nil {
+				slog.Error("Failed to revert the version commit", "commit", vc.sha, "err", err)
+				os.Exit(exitGit)
+			}
+		}
+
+		removeReleaseState(*tag)
+		success("Rolled back; delete its forge release too if one was created", "tag", *tag)
+	}
+}
+
+func remoteTagExists(tag string) (bool, error) {
+	output, err := newCommand("git", "ls-remote", "--tags", remoteName, "refs/tags/"+tag).Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to list remote tags: %w", err)
+	}
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// versionCommit is the commit of a release updating the module path, and
+// how it is undone: reset away if it is the unpushed HEAD, and reverted
+// otherwise, with the revert pushed if the commit was.
+type versionCommit struct {
+	sha    string
+	head   bool
+	pushed bool
+}
+
+// findVersionCommit finds the commit updating the module path for tag in
+// the history of HEAD, if any and not reverted yet.
+func findVersionCommit(tag string) (versionCommit, error) {
+	msg := versionCommitMessage(tag)
+	output, err := newCommand("git", "log", "--format=%H %s", "--fixed-strings", "--grep", msg, "HEAD").Output()
+	if err != nil {
+		return versionCommit{}, fmt.Errorf("failed to search the version commit: %w", err)
+	}
+	// The grep also matches the reverts of the commit.
+	var vc versionCommit
+	for _, line := range strings.Split(string(output), "\n") {
+		if sha, subject, _ := strings.Cut(line, " "); subject == msg {
+			vc.sha = sha
+			break
+		}
+	}
+	if vc.sha == "" {
+		return vc, nil
+	}
+
+	// A commit reverted by an earlier rollback is done with.
+	output, err = newCommand("git", "log", "-n", "1", "--format=%H", "--fixed-strings", "--grep", "This reverts commit "+vc.sha, "HEAD").Output()
+	if err != nil {
+		return versionCommit{}, fmt.Errorf("failed to search reverts of the version commit: %w", err)
+	}
+	if strings.TrimSpace(string(output)) != "" {
+		return versionCommit{}, nil
+	}
+
+	head, err := resolveCommit("HEAD")
+	if err != nil {
+		return versionCommit{}, err
+	}
+	vc.head = head == vc.sha
+
+	// Fetch so that the remote branches are current; offline, the
+	// remote-tracking branches of the last fetch or push are used.
+	if err := newCommand("git", "fetch", "--quiet", "--no-tags", remoteName).Run(); err != nil {
+		slog.Warn("Failed to fetch, using the last known remote branches", "remote", remoteName, "err", err)
+	}
+	output, err = newCommand("git", "branch", "--remotes", "--contains", vc.sha, "--list", remoteName+"/*").Output()
+	if err != nil {
+		return versionCommit{}, fmt.Errorf("failed to check whether %s was pushed: %w", vc.sha, err)
+	}
+	vc.pushed = strings.TrimSpace(string(output)) != ""
+	return vc, nil
+}
+
+func (vc versionCommit) describe() string {
+	short := vc.sha[:min(len(vc.sha), 12)]
+	switch {
+	case vc.pushed:
+		return fmt.Sprintf("Revert version commit %s and push the revert to %s", short, remoteName)
+	case vc.head:
+		return fmt.Sprintf("Reset away the unpushed version commit %s", short)
+	default:
+		return fmt.Sprintf("Revert the unpushed version commit %s locally", short)
+	}
+}
+
+func (vc versionCommit) undo() error {
+	if vc.head && !vc.pushed {
+		// --keep refuses rather than discard uncommitted changes.
+		if err := newCommand("git", "reset", "--keep", "HEAD~1").Run(); err != nil {
+			return fmt.Errorf("failed to reset: %w", err)
+		}
+		slog.Info("Reset away the version commit", "commit", vc.sha)
+		return nil
+	}
+
+	if err := newCommand("git", "revert", "--no-edit", vc.sha).Run(); err != nil {
+		return fmt.Errorf("failed to revert: %w", err)
+	}
+	slog.Info("Reverted the version commit", "commit", vc.sha)
+	if !vc.pushed {
+		return nil
+	}
+
+	p := startProgress("Pushing the revert", "remote", remoteName)
+	err := runPush(pushCommand("HEAD"))
+	p.stop()
+	if err != nil {
+		return fmt.Errorf("failed to push the revert: %w", err)
+	}
+	success("Pushed the revert", "remote", remoteName)
+	return nil
+}
+
+// removeReleaseState removes the state of an interrupted release of tag,
+// which cannot be resumed once it is rolled back.
+func removeReleaseState(tag string) {
+	if s, err := loadReleaseState(); err == nil && s.NewVersion == tag {
+		s.remove()
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// runTimeout bounds the whole run of a command, set by -timeout; zero does
+// not.
+var runTimeout time.Duration
+
+// runCtx is the context of the run's subprocesses and forge requests, done
+// once the run passes its timeout or is interrupted.
+var runCtx = context.Background()
+
+// cancelRun releases the resources of runCtx.
+var cancelRun context.CancelFunc = func() {}
+
+// errInterrupted is the cause of runCtx once the run receives SIGINT or
+// SIGTERM.
+var errInterrupted = errors.New("interrupted")
+
+// startRun starts the run's timeout, if any, and the handling of SIGINT
+// and SIGTERM: the first one cancels runCtx, killing the running commands
+// and failing the release, which cleans up like for any failure; a second
+// one exits at once.
+func startRun() {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	runCtx, cancelRun = ctx, func() { cancel(context.Canceled) }
+	if runTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		runCtx, cancelTimeout = context.WithTimeout(ctx, runTimeout)
+		cancelRun = func() { cancelTimeout(); cancel(context.Canceled) }
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		name := "SIGTERM"
+		if sig == os.Interrupt {
+			name = "SIGINT"
+		}
+		slog.Warn("Received " + name + ", stopping; send it again to exit without cleaning up")
+		cancel(fmt.Errorf("%w by %s", errInterrupted, name))
+	}()
+}
+
+// timedOut reports whether the run has passed its timeout.
+func timedOut() bool {
+	return errors.Is(runCtx.Err(), context.DeadlineExceeded)
+}
+
+// interrupted returns the error describing the signal that interrupted the
+// run, or nil if none did.
+func interrupted() error {
+	if err := context.Cause(runCtx); errors.Is(err, errInterrupted) {
+		return err
+	}
+	return nil
+}
+
+// liftCancel detaches the run from its timeout and signals, so that the
+// cleanup of a run that was canceled, such as undoing its tag or releasing
+// its lock, can still run git.
+func liftCancel() {
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
+	cancelRun()
+	runCtx = context.Background()
+}
+
+// sleep pauses for d, returning early with an error once the run is
+// canceled.
+func sleep(d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-runCtx.Done():
+		return runCtx.Err()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// enterSandbox clones the repository into a temporary directory and moves
+// into the clone, so that a release run there commits, tags, and pushes
+// for real without touching the repository or its remote. The clone's
+// remote is a bare clone next to it, which receives the pushes. It returns
+// the directory holding both, which is kept for inspection.
+//
+// Only the committed HEAD is released, as uncommitted changes are not
+// cloned.
+func enterSandbox() (string, error) {
+	output, err := newCommand("git", "rev-parse", "--show-toplevel", "--show-prefix").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the repository root: %w", err)
+	}
+	root, prefix, _ := strings.Cut(strings.TrimRight(string(output), "\n"), "\n")
+
+	dir, err := os.MkdirTemp("", "release-sandbox-")
+	if err != nil {
+		return "", err
+	}
+	remote := filepath.Join(dir, "remote.git")
+	clone := filepath.Join(dir, "repo")
+
+	// Both clones have the branches and tags of the repository, and the
+	// work clone checks out its current branch.
+	if out, err := newCommand("git", "clone", "--quiet", "--bare", root, remote).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to create the sandbox remote: %w: %s", err, out)
+	}
+	if out, err := newCommand("git", "clone", "--quiet", "--origin", remoteName, remote, clone).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to clone the repository into the sandbox: %w: %s", err, out)
+	}
+	head, err := resolveCommit("HEAD")
+	if err != nil {
+		return "", err
+	}
+	// Commits made in the sandbox are authored like in the repository,
+	// whose own config the clone does not inherit.
+	var identity [][2]string
+	for _, key := range []string{"user.name", "user.email"} {
+		if v, err := newCommand("git", "config", key).Output(); err == nil {
+			identity = append(identity, [2]string{key, strings.TrimSpace(string(v))})
+		}
+	}
+
+	if err := os.Chdir(filepath.Join(clone, prefix)); err != nil {
+		return "", err
+	}
+	for _, kv := range identity {
+		if err := newCommand("git", "config", kv[0], kv[1]).Run(); err != nil {
+			return "", fmt.Errorf("failed to configure the sandbox: %w", err)
+		}
+	}
+	// A detached HEAD is not cloned.
+	if current, err := resolveCommit("HEAD"); err != nil || current != head {
+		if out, err := newCommand("git", "checkout", "--quiet", "--detach", head).CombinedOutput(); err != nil {
+			return "", fmt.Errorf("failed to check out %s in the sandbox: %w: %s", head, err, out)
+		}
+	}
+	return dir, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SBOM formats.
+const (
+	sbomSPDX      = "spdx"
+	sbomCycloneDX = "cyclonedx"
+)
+
+// goModule is a module of the build list as reported by "go list -m -json".
+type goModule struct {
+	Path    string
+	Version string
+	Main    bool
+	Replace *goModule
+}
+
+func (m goModule) purl() string {
+	if m.Version == "" {
+		return "pkg:golang/" + m.Path
+	}
+	return "pkg:golang/" + m.Path + "@" + m.Version
+}
+
+// writeSBOM writes an SBOM of the module graph of the module in dir, in
+// the given format, and returns it as an artifact.
+func writeSBOM(dir, tag, format, dist string) (artifact, error) {
+	modules, err := buildList(dir)
+	if err != nil {
+		return artifact{}, err
+	}
+
+	var main goModule
+	var deps []goModule
+	for _, m := range modules {
+		if m.Main {
+			main = m
+			main.Version = tag
+			continue
+		}
+		if m.Replace != nil {
+			m = goModule{Path: m.Replace.Path, Version: m.Replace.Version}
+		}
+		deps = append(deps, m)
+	}
+
+	var doc any
+	var name string
+	switch format {
+	case sbomSPDX:
+		doc, name = spdxDocument(main, deps), "sbom.spdx.json"
+	case sbomCycloneDX:
+		doc, name = cycloneDXDocument(main, deps), "sbom.cdx.json"
+	default:
+		return artifact{}, fmt.Errorf("unsupported SBOM format %q", format)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return artifact{}, err
+	}
+
+	if err := os.MkdirAll(dist, 0755); err != nil {
+		return artifact{}, err
+	}
+	out := filepath.Join(dist, name)
+	if err := os.WriteFile(out, append(data, '\n'), 0644); err != nil {
+		return artifact{}, err
+	}
+
+	slog.Info("Wrote SBOM", "path", out)
+	return artifact{Path: out, Name: name}, nil
+}
+
+// buildList returns the modules of the build list of the module in dir.
+func buildList(dir string) ([]goModule, error) {
+	cmd := commandIn(dir, "go", "list", "-m", "-json", "all")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modules: %w", err)
+	}
+
+	var modules []goModule
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var m goModule
+		if err := dec.Decode(&m); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decoding module list: %w", err)
+		}
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
+
+func spdxDocument(main goModule, deps []goModule) map[string]any {
+	pkg := func(id string, m goModule) map[string]any {
+		return map[string]any{
+			"SPDXID":           id,
+			"name":             m.Path,
+			"versionInfo":      m.Version,
+			"downloadLocation": "NOASSERTION",
+			"externalRefs": []map[string]string{{
+				"referenceCategory": "PACKAGE-MANAGER",
+				"referenceType":     "purl",
+				"referenceLocator":  m.purl(),
+			}},
+		}
+	}
+
+	packages := []map[string]any{pkg("SPDXRef-Package-main", main)}
+	relationships := []map[string]string{{
+		"spdxElementId":      "SPDXRef-DOCUMENT",
+		"relationshipType":   "DESCRIBES",
+		"relatedSpdxElement": "SPDXRef-Package-main",
+	}}
+	for i, m := range deps {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		packages = append(packages, pkg(id, m))
+		relationships = append(relationships, map[string]string{
+			"spdxElementId":      "SPDXRef-Package-main",
+			"relationshipType":   "DEPENDS_ON",
+			"relatedSpdxElement": id,
+		})
+	}
+
+	return map[string]any{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              main.Path + "@" + main.Version,
+		"documentNamespace": "https://spdx.org/spdxdocs/" + main.Path + "-" + main.Version + "-" + newUUID(),
+		"creationInfo": map[string]any{
+			"created":  time.Now().UTC().Format(time.RFC3339),
+			"creators": []string{"Tool: " + program},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	}
+}
+
+func cycloneDXDocument(main goModule, deps []goModule) map[string]any {
+	component := func(m goModule) map[string]any {
+		return map[string]any{
+			"type":    "library",
+			"bom-ref": m.purl(),
+			"name":    m.Path,
+			"version": m.Version,
+			"purl":    m.purl(),
+		}
+	}
+
+	components := make([]map[string]any, 0, len(deps))
+	dependsOn := make([]string, 0, len(deps))
+	for _, m := range deps {
+		components = append(components, component(m))
+		dependsOn = append(dependsOn, m.purl())
+	}
+
+	return map[string]any{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.5",
+		"serialNumber": "urn:uuid:" + newUUID(),
+		"version":      1,
+		"metadata": map[string]any{
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"component": component(main),
+		},
+		"components": components,
+		"dependencies": []map[string]any{{
+			"ref":       main.purl(),
+			"dependsOn": dependsOn,
+		}},
+	}
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// selfRepo is the GitHub repository the release tool itself is released
+// from, with its binaries built by "release -build-artifacts".
+const selfRepo = "raducristianpopa/test-go-pkg"
+
+// runSelfUpdate replaces the running release tool with the binary of the
+// latest release of selfRepo for this platform, after checking the
+// downloaded archive against the release checksums and, when the release
+// is signed, its cosign signature.
+func runSelfUpdate(fs *flag.FlagSet) func() {
+	var (
+		check      = fs.Bool("check", false, "Only report whether an update is available")
+		force      = fs.Bool("force", false, "Update even if the installed version is current or unknown")
+		requireSig = fs.Bool("require-signature", false, "Fail unless the archive's cosign signature is verified")
+		cosignKey  = fs.String("cosign-key", "", "Public key verifying the signature (default: keyless, with the identity of this repository's workflows)")
+	)
+
+	return func() {
+		// The tool's own tags have no prefix, whatever the current
+		// repository uses.
+		tagPrefix = ""
+
+		hc, err := forgeFlags.httpClient()
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitUsage)
+		}
+		headers := map[string]string{
+			"Accept":               "application/vnd.github+json",
+			"X-GitHub-Api-Version": "2022-11-28",
+		}
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			headers["Authorization"] = "Bearer " + token
+		}
+		api := newRESTClient("https://api.github.com", headers, hc)
+
+		var latest githubRelease
+		if err := api.do(http.MethodGet, "/repos/"+selfRepo+"/releases/latest", nil, &latest); err != nil {
+			slog.Error("Failed to look up the latest release", "err", err)
+			os.Exit(exitForge)
+		}
+
+		installed := installedVersion()
+		current, err := parseVersion(installed)
+		upToDate := err == nil && !current.less(mustParseVersion(latest.TagName))
+		fmt.Printf("Installed: %s\nLatest: %s\n", installed, latest.TagName)
+		switch {
+		case *check:
+			if !upToDate {
+				fmt.Printf("Update available: run '%s self-update'\n", program)
+			}
+			return
+		case upToDate && !*force:
+			success("Already up to date", "version", installed)
+			return
+		case err != nil && !*force:
+			slog.Error("Unknown installed version, e.g. of a development build; pass -force to update anyway", "version", installed)
+			os.Exit(exitPreflight)
+		}
+
+		if err := confirm(fmt.Sprintf("Replace release %s with %s", installed, latest.TagName), nil); err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitPreflight)
+		}
+
+		exe, err := os.Executable()
+		if err == nil {
+			exe, err = filepath.EvalSymlinks(exe)
+		}
+		if err != nil {
+			slog.Error("Failed to locate the running executable", "err", err)
+			os.Exit(exitFailure)
+		}
+
+		bin, err := downloadSelf(hc, latest, *cosignKey, *requireSig)
+		if err != nil {
+			slog.Error("Failed to download the update", "err", err)
+			os.Exit(exitForge)
+		}
+		if err := replaceExecutable(exe, bin); err != nil {
+			slog.Error("Failed to replace the executable", "path", exe, "err", err)
+			os.Exit(exitFailure)
+		}
+		success("Updated release", "version", latest.TagName, "path", exe)
+	}
+}
+
+func mustParseVersion(tag string) version {
+	v, _ := parseVersion(tag)
+	return v
+}
+
+// downloadSelf downloads the archive of release for this platform into a
+// temporary directory, verifies it, and returns the binary's contents.
+func downloadSelf(hc *http.Client, release githubRelease, cosignKey string, requireSig bool) ([]byte, error) {
+	ext := ".tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = ".zip"
+	}
+	name := fmt.Sprintf("release_%s_%s_%s%s", versionNumber(release.TagName), runtime.GOOS, runtime.GOARCH, ext)
+
+	assets := make(map[string]string)
+	for _, a := range release.Assets {
+		assets[a.Name] = a.BrowserDownloadURL
+	}
+	if assets[name] == "" {
+		return nil, fmt.Errorf("release %s has no archive %s for %s/%s", release.TagName, name, runtime.GOOS, runtime.GOARCH)
+	}
+	if assets[checksumsFile] == "" {
+		return nil, fmt.Errorf("release %s has no %s", release.TagName, checksumsFile)
+	}
+
+	dir, err := os.MkdirTemp("", "release-update-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{name, checksumsFile}
+	for _, f := range []string{name + ".sig", name + ".pem"} {
+		if assets[f] != "" {
+			files = append(files, f)
+		}
+	}
+	for _, f := range files {
+		p := startProgress("Downloading " + f)
+		err := downloadFile(hc, assets[f], filepath.Join(dir, f))
+		p.stop()
+		if err != nil {
+			return nil, fmt.Errorf("downloading %s: %w", f, err)
+		}
+	}
+
+	archive := filepath.Join(dir, name)
+	if err := verifyChecksum(dir, name); err != nil {
+		return nil, err
+	}
+	success("Checksum OK", "name", name)
+
+	switch {
+	case assets[name+".sig"] == "":
+		if requireSig {
+			return nil, fmt.Errorf("release %s is not signed", release.TagName)
+		}
+		slog.Warn("Release is not signed; only its checksum was verified", "tag", release.TagName)
+	default:
+		// A signature that fails verification always aborts; only a
+		// missing cosign may be skipped.
+		err := verifySignature(archive, cosignKey)
+		switch {
+		case err == nil:
+			success("Signature OK", "name", name)
+		case errors.Is(err, errNoCosign) && !requireSig:
+			slog.Warn("Signature not verified", "err", err)
+		default:
+			return nil, err
+		}
+	}
+
+	return extractBinary(archive, "release")
+}
+
+func downloadFile(hc *http.Client, url, path string) error {
+	req, err := http.NewRequestWithContext(runCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// verifyChecksum checks name in dir against its entry in the checksums file
+// of dir.
+func verifyChecksum(dir, name string) error {
+	f, err := os.Open(filepath.Join(dir, checksumsFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		want, file, ok := strings.Cut(strings.TrimSuffix(s.Text(), "\r"), "  ")
+		if !ok || file != name {
+			continue
+		}
+		got, err := sha256File(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		if got != want {
+			return fmt.Errorf("checksum mismatch: %s", name)
+		}
+		return nil
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s is not listed in %s", name, checksumsFile)
+}
+
+// errNoCosign is returned by verifySignature if cosign is not installed.
+var errNoCosign = errors.New("cosign is not installed")
+
+// verifySignature verifies the cosign signature of path, stored next to it,
+// with key or, if empty, keylessly against the certificate stored next to
+// it, which must have been issued to a GitHub Actions workflow of selfRepo.
+func verifySignature(path, key string) error {
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return errNoCosign
+	}
+
+	args := []string{"verify-blob", "--signature", path + ".sig"}
+	if key != "" {
+		args = append(args, "--key", key)
+	} else {
+		args = append(args,
+			"--certificate", path+".pem",
+			"--certificate-identity-regexp", "^https://github.com/"+selfRepo+"/",
+			"--certificate-oidc-issuer", "https://token.actions.githubusercontent.com")
+	}
+	args = append(args, path)
+
+	if output, err := newCommand("cosign", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("signature verification failed: %v: %s", err, output)
+	}
+	return nil
+}
+
+// extractBinary returns the contents of the named binary in the .tar.gz or
+// .zip archive at path.
+func extractBinary(path, binary string) ([]byte, error) {
+	if strings.HasSuffix(path, ".zip") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		for _, f := range zr.File {
+			if filepath.Base(f.Name) == binary+".exe" {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+		return nil, fmt.Errorf("%s not found in %s", binary, filepath.Base(path))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in %s", binary, filepath.Base(path))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Base(h.Name) == binary {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// replaceExecutable atomically replaces the executable at exe with bin. A
+// running executable cannot be overwritten on Windows, but it can be
+// renamed, so it is moved aside first.
+func replaceExecutable(exe string, bin []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".release-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bin); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), exe)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// cosignArtifacts signs each artifact with cosign, returning the signatures
+// (and, for keyless signing, the signing certificates) as new artifacts.
+// An empty key selects keyless signing through Sigstore's Fulcio CA, using
+// the CI job's OIDC identity when one is available; a key may be a file
+// path or any KMS URI cosign understands.
+func cosignArtifacts(artifacts []artifact, key string) ([]artifact, error) {
+	if _, err := exec.LookPath("cosign"); err != nil {
+		return nil, fmt.Errorf("cosign is not installed")
+	}
+
+	env := os.Environ()
+	if key == "" {
+		token, provider, err := ambientIdentityToken()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case token != "":
+			slog.Info("Signing keyless with the CI identity", "provider", provider)
+			env = append(env, "SIGSTORE_ID_TOKEN="+token)
+		case inCI():
+			return nil, fmt.Errorf("keyless signing in CI needs an OIDC token: grant 'id-token: write' (GitHub Actions) or configure an 'id_tokens' entry named SIGSTORE_ID_TOKEN (GitLab CI)")
+		}
+	}
+
+	var signed []artifact
+	for _, a := range artifacts {
+		sig := artifact{Path: a.Path + ".sig", Name: a.Name + ".sig"}
+		args := []string{"sign-blob", "--yes", "--output-signature", sig.Path}
+
+		var cert artifact
+		if key != "" {
+			args = append(args, "--key", key)
+		} else {
+			cert = artifact{Path: a.Path + ".pem", Name: a.Name + ".pem"}
+			args = append(args, "--output-certificate", cert.Path)
+		}
+		args = append(args, a.Path)
+
+		cmd := newCommand("cosign", args...)
+		cmd.Env = env
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("failed to sign %s: %v: %s", a.Name, err, output)
+		}
+
+		slog.Info("Signed artifact", "name", a.Name)
+		signed = append(signed, sig)
+		if cert.Path != "" {
+			signed = append(signed, cert)
+		}
+	}
+
+	return signed, nil
+}
+
+// gpgSignArtifacts creates an ASCII-armored detached signature (.asc) for
+// each artifact with the GPG key key. A passphrase-protected key is unlocked
+// with $GPG_PASSPHRASE.
+func gpgSignArtifacts(artifacts []artifact, key string) ([]artifact, error) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		return nil, fmt.Errorf("gpg is not installed")
+	}
+
+	passphrase := os.Getenv("GPG_PASSPHRASE")
+
+	var signed []artifact
+	for _, a := range artifacts {
+		sig := artifact{Path: a.Path + ".asc", Name: a.Name + ".asc"}
+		args := []string{"--batch", "--yes", "--armor", "--detach-sign", "--local-user", key, "--output", sig.Path}
+		if passphrase != "" {
+			args = append(args, "--pinentry-mode", "loopback", "--passphrase-fd", "0")
+		}
+		args = append(args, a.Path)
+
+		cmd := newCommand("gpg", args...)
+		if passphrase != "" {
+			cmd.Stdin = strings.NewReader(passphrase + "\n")
+		}
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("failed to sign %s: %v: %s", a.Name, err, output)
+		}
+
+		slog.Info("Signed artifact with GPG", "name", a.Name)
+		signed = append(signed, sig)
+	}
+
+	return signed, nil
+}
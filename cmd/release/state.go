@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// stateFile is the file in the repository root a release records its
+// progress in, until it completes, for the resume command.
+const stateFile = ".release-state.json"
+
+// releaseState is the progress of a release: its options and versions, the
+// steps it completed, and what they produced that later steps need.
+type releaseState struct {
+	Created         time.Time  `json:"created"`
+	PreviousVersion string     `json:"previous_version"`
+	NewVersion      string     `json:"new_version"`
+	Args            []string   `json:"args"`
+	Steps           []planStep `json:"steps"`
+	Completed       []string   `json:"completed"`
+	Failed          string     `json:"failed,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	ReleaseCommit   string     `json:"release_commit,omitempty"`
+	PullRequest     int        `json:"pull_request,omitempty"`
+	ReleaseURL      string     `json:"release_url,omitempty"`
+	Artifacts       []artifact `json:"artifacts,omitempty"`
+	// Benchmarks is the section of the release notes comparing the
+	// benchmarks of -bench.
+	Benchmarks string `json:"benchmarks,omitempty"`
+
+	path string
+}
+
+// resumeState, set by the resume command, is the state of the release it
+// continues; its completed steps are skipped.
+var resumeState *releaseState
+
+// statePath returns the path of the state file of the repository.
+func statePath() (string, error) {
+	output, err := newCommand("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the repository root: %w", err)
+	}
+	return filepath.Join(strings.TrimSpace(string(output)), stateFile), nil
+}
+
+// newReleaseState returns the state of a release of newVersion after
+// current with steps and the options set in fs, which is saved once the
+// first step completes.
+func newReleaseState(fs *flag.FlagSet, current, newVersion version, steps []planStep) (*releaseState, error) {
+	path, err := statePath()
+	if err != nil {
+		return nil, err
+	}
+	return &releaseState{
+		Created:         time.Now().UTC(),
+		PreviousVersion: current.String(),
+		NewVersion:      newVersion.String(),
+		Args:            flagArgs(fs),
+		Steps:           steps,
+		Completed:       []string{},
+		path:            path,
+	}, nil
+}
+
+// loadReleaseState reads the state file of the repository.
+func loadReleaseState() (*releaseState, error) {
+	path, err := statePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("no interrupted release to resume")
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &releaseState{path: path}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// done reports whether the named step completed.
+func (s *releaseState) done(name string) bool {
+	return s != nil && slices.Contains(s.Completed, name)
+}
+
+// remaining returns the effects of the steps not completed yet.
+func (s *releaseState) remaining() []string {
+	var effects []string
+	for _, step := range s.Steps {
+		if !s.done(step.Command) {
+			effects = append(effects, step.Effect)
+		}
+	}
+	return effects
+}
+
+// save writes the state file. Failing to is only logged, as the release
+// itself is unaffected.
+func (s *releaseState) save() {
+	if s == nil {
+		return
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		err = os.WriteFile(s.path, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		slog.Warn("Failed to save the release state", "file", s.path, "err", err)
+	}
+}
+
+// remove deletes the state file once the release completed.
+func (s *releaseState) remove() {
+	if s == nil {
+		return
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to remove the release state", "file", s.path, "err", err)
+	}
+}
+
+// runResume continues the release recorded in the state file from its
+// first step not completed, with the same options and versions, so that a
+// failed step can be retried without redoing, e.g. tagging twice.
+func runResume(fs *flag.FlagSet) func() {
+	return func() {
+		s, err := loadReleaseState()
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitPreflight)
+		}
+		if s.Failed != "" {
+			slog.Info("Resuming the release", "version", s.NewVersion, "failed", s.Failed, "err", s.Error)
+		}
+
+		remaining := s.remaining()
+		if len(remaining) == 0 {
+			slog.Info("The release completed; removing its state", "version", s.NewVersion)
+			s.remove()
+			return
+		}
+		if err := confirm(fmt.Sprintf("Resuming the release %s -> %s:", s.PreviousVersion, s.NewVersion), remaining); err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitPreflight)
+		}
+		assumeYes = true
+		plannedVersion = s.NewVersion
+		resumeState = s
+
+		c := findCommand("release")
+		rfs := c.flagSet()
+		release := c.run(rfs)
+		if err := rfs.Parse(s.Args); err != nil {
+			os.Exit(exitUsage)
+		}
+		release()
+	}
+}
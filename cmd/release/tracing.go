@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tracer records a release run as an OpenTelemetry trace: a root span for
+// the run with a child span per step. The trace is exported over OTLP/HTTP
+// with JSON encoding, configured by the standard variables:
+// $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or $OTEL_EXPORTER_OTLP_ENDPOINT,
+// $OTEL_EXPORTER_OTLP_HEADERS, and $OTEL_SERVICE_NAME. With $TRACEPARENT
+// set, e.g. by the CI system, the run is a child of its span.
+//
+// A nil tracer, as without an endpoint, records nothing.
+type tracer struct {
+	endpoint string
+	headers  map[string]string
+	traceID  string
+	parentID string // of $TRACEPARENT, if any
+	root     otlpSpan
+	steps    map[string]otlpSpan // started and not finished yet
+	spans    []otlpSpan
+}
+
+// otlpSpan is a span in the OTLP JSON encoding.
+type otlpSpan struct {
+	TraceID      string     `json:"traceId"`
+	SpanID       string     `json:"spanId"`
+	ParentSpanID string     `json:"parentSpanId,omitempty"`
+	Name         string     `json:"name"`
+	Kind         int        `json:"kind"`
+	Start        string     `json:"startTimeUnixNano"`
+	End          string     `json:"endTimeUnixNano"`
+	Attributes   []otlpAttr `json:"attributes,omitempty"`
+	Status       otlpStatus `json:"status"`
+}
+
+type otlpAttr struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 1 ok, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+// OTLP span kind and status codes.
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+// startTracing returns the tracer of the run, or nil if no OTLP endpoint is
+// configured. The root span starts now.
+func startTracing() *tracer {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return nil
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+
+	t := &tracer{
+		endpoint: endpoint,
+		headers:  make(map[string]string),
+		traceID:  randomHex(16),
+		steps:    make(map[string]otlpSpan),
+	}
+	for _, kv := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if u, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = u
+		}
+		t.headers[strings.TrimSpace(k)] = v
+	}
+	// traceparent: version-traceid-parentid-flags
+	if parts := strings.Split(os.Getenv("TRACEPARENT"), "-"); len(parts) == 4 && len(parts[1]) == 32 && len(parts[2]) == 16 {
+		t.traceID, t.parentID = parts[1], parts[2]
+	}
+
+	t.root = t.span("release", t.parentID)
+	if module, err := newCommand("go", "list", "-m").Output(); err == nil {
+		t.set("release.module", strings.TrimSpace(string(module)))
+	}
+	return t
+}
+
+// span returns a span named name with parent, starting now.
+func (t *tracer) span(name, parent string) otlpSpan {
+	return otlpSpan{
+		TraceID:      t.traceID,
+		SpanID:       randomHex(8),
+		ParentSpanID: parent,
+		Name:         name,
+		Kind:         spanKindInternal,
+		Start:        unixNano(time.Now()),
+	}
+}
+
+// set sets the attribute key of the run's span.
+func (t *tracer) set(key, value string) {
+	if t == nil {
+		return
+	}
+	t.root.Attributes = append(t.root.Attributes, attr(key, value))
+}
+
+// observe records the events of the run as spans.
+func (t *tracer) observe(e event) {
+	switch e.Type {
+	case eventVersionComputed:
+		t.set("release.previous_version", e.PreviousVersion)
+		t.set("release.version", e.NewVersion)
+		t.set("release.tag", e.Tag)
+	case eventStepStarted:
+		t.steps[e.Step] = t.span(e.Step, t.root.SpanID)
+	case eventStepFinished:
+		s, ok := t.steps[e.Step]
+		if !ok {
+			// A skipped step has no start.
+			s = t.span(e.Step, t.root.SpanID)
+		}
+		delete(t.steps, e.Step)
+		s.End = unixNano(e.Time)
+		s.Attributes = append(s.Attributes, attr("release.step.result", e.Result))
+		s.Status.Code = statusOK
+		if e.Result == stepFailed {
+			s.Status.Code = statusError
+		}
+		t.spans = append(t.spans, s)
+	}
+}
+
+// export ends the run's span with the outcome of r and sends the trace.
+func (t *tracer) export(r *runResult) error {
+	t.root.End = unixNano(time.Now())
+	t.root.Status.Code = statusOK
+	if len(r.Errors) > 0 {
+		t.root.Status = otlpStatus{statusError, r.Errors[0]}
+	}
+	t.set("release.dry_run", strconv.FormatBool(r.DryRun))
+
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = program
+	}
+	payload := map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": []otlpAttr{attr("service.name", service)}},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": program, "version": buildVersion()},
+				"spans": append([]otlpSpan{t.root}, t.spans...),
+			}},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("exporting the trace: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("exporting the trace: %s", resp.Status)
+	}
+	slog.Debug("Exported the trace", "endpoint", t.endpoint, "trace_id", t.traceID)
+	return nil
+}
+
+func attr(key, value string) otlpAttr {
+	var a otlpAttr
+	a.Key, a.Value.StringValue = key, value
+	return a
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// randomHex returns n random bytes in hex.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// runVerify checks that a released tag is consistent: it exists, its module
+// path carries the major version suffix the go command requires, and, with
+// -dist, the artifacts in dist match their checksums.
+func runVerify(fs *flag.FlagSet) func() {
+	var (
+		tag  = fs.String("tag", "", "Tag to verify (defaults to the latest version tag)")
+		dist = fs.String("dist", "", "Directory with the downloaded release artifacts and their "+checksumsFile)
+	)
+
+	return func() {
+		if *tag == "" {
+			currentVersion, err := getCurrentVersion()
+			if err != nil {
+				slog.Error("Could not retrieve current version", "err", err)
+				os.Exit(exitGit)
+			}
+			*tag = currentVersion.String()
+		}
+
+		if err := verifyTag(*tag); err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitFailure)
+		}
+		slog.Info("Tag is consistent with its module path", "tag", *tag)
+
+		if *dist != "" {
+			if err := verifyChecksums(*dist); err != nil {
+				slog.Error(err.Error())
+				os.Exit(exitFailure)
+			}
+		}
+
+		success("Verified", "tag", *tag)
+	}
+}
+
+var moduleDirective = regexp.MustCompile(`(?m)^module\s+"?([^"\s]+)"?`)
+
+// verifyTag checks that tag exists and that the module path in its go.mod
+// matches its major version.
+func verifyTag(tag string) error {
+	v, err := parseVersion(tag)
+	if err != nil {
+		return err
+	}
+	if !tagExists(tag) {
+		return fmt.Errorf("tag %s does not exist", tag)
+	}
+
+	output, err := newCommand("git", "show", tag+":go.mod").Output()
+	if err != nil {
+		return fmt.Errorf("failed to read go.mod at %s: %w", tag, err)
+	}
+	m := moduleDirective.FindSubmatch(output)
+	if m == nil {
+		return fmt.Errorf("go.mod at %s has no module directive", tag)
+	}
+
+	return checkModulePath(string(m[1]), v.Major)
+}
+
+var majorSuffix = regexp.MustCompile(`/v(\d+)$`)
+
+// checkModulePath reports whether module has the "/vN" suffix required for
+// major version major: none for v0 and v1, and a matching one from v2 on.
+func checkModulePath(module string, major int) error {
+	suffix := majorSuffix.FindString(module)
+	switch {
+	case major < 2 && suffix != "":
+		return fmt.Errorf("module path %s must not have a major version suffix for v%d", module, major)
+	case major >= 2 && suffix != fmt.Sprintf("/v%d", major):
+		return fmt.Errorf("module path %s must end in /v%d for v%d", module, major, major)
+	}
+	return nil
+}
+
+// verifyChecksums checks every file listed in the checksums file of dist.
+func verifyChecksums(dist string) error {
+	f, err := os.Open(filepath.Join(dist, checksumsFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var mismatched []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		want, name, ok := strings.Cut(strings.TrimSuffix(s.Text(), "\r"), "  ")
+		if !ok {
+			continue
+		}
+		got, err := sha256File(filepath.Join(dist, name))
+		if err != nil {
+			return err
+		}
+		if got != want {
+			mismatched = append(mismatched, name)
+			continue
+		}
+		slog.Info("Checksum OK", "name", name)
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	if len(mismatched) > 0 {
+		return fmt.Errorf("checksum mismatch: %s", strings.Join(mismatched, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/raducristianpopa/test-go-pkg/v4/release"
+)
+
+// tagPrefix precedes the "v" of version tags, for modules in a
+// subdirectory of the repository such as "tools/" for tools/v1.2.3.
+var tagPrefix string
+
+type version struct {
+	Major, Minor, Patch int
+	// Pre is the prerelease suffix without the leading "-", e.g. "rc.1".
+	Pre string
+}
+
+func (v version) String() string {
+	s := fmt.Sprintf("%sv%d.%d.%d", tagPrefix, v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// less orders versions by SemVer precedence, as release.Version.Less.
+func (v version) less(o version) bool {
+	return release.Version(v).Less(release.Version(o))
+}
+
+// latestVersion returns the highest version among tags, ignoring tags that
+// are not versions.
+func latestVersion(tags []string) version {
+	return version(release.Latest(tags, tagPrefix, true))
+}
+
+// latestStableVersion is like latestVersion but ignores prereleases.
+func latestStableVersion(tags []string) version {
+	return version(release.Latest(tags, tagPrefix, false))
+}
+
+// parseVersion parses tag, a version with the tag prefix.
+func parseVersion(tag string) (version, error) {
+	v, err := release.ParseVersion(tag, tagPrefix)
+	return version(v), err
+}
+
+// versionNumber returns tag without the tag prefix and "v", e.g. "1.2.3"
+// for v1.2.3.
+func versionNumber(tag string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(tag, tagPrefix), "v")
+}
+
+// nextVersion computes the version to release, as release.Next: bumps are
+// relative to the latest stable version, so a series of prereleases
+// (v2.0.0-rc.1, v2.0.0-rc.2, ...) all target the same release, and a bump
+// without prerelease finalizes it.
+func nextVersion(tags []string, bumpType BumpType, prerelease string) version {
+	return version(release.Next(tags, tagPrefix, release.Bump(bumpType), prerelease))
+}
+
+// runVersion prints the current version, or with -type the version the
+// next release would get.
+func runVersion(fs *flag.FlagSet) func() {
+	var (
+		bt = fs.String("type", "", "Print the next version for this bump type: major, minor, or patch")
+		pr = fs.String("prerelease", "", "With -type, print the next prerelease with this identifier, e.g. 'rc'")
+		vs = fs.String("version-source", "git", "Where the current version is read from: git (local tags) or forge (the forge's tags API)")
+	)
+
+	return func() {
+		tags, err := listVersionTags(*vs, forgeFlags)
+		if err != nil {
+			slog.Error("Could not retrieve current version", "err", err)
+			os.Exit(versionSourceExitCode(*vs))
+		}
+
+		if *bt == "" {
+			fmt.Println(latestVersion(tags))
+			return
+		}
+
+		bump := BumpType(*bt)
+		if !bump.IsValid() {
+			slog.Error("Invalid bump type. Must be 'major', 'minor', or 'patch'", "type", *bt)
+			os.Exit(exitUsage)
+		}
+		fmt.Println(nextVersion(tags, bump, *pr))
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import "testing"
+
+// setTagPrefix sets tagPrefix for the test.
+func setTagPrefix(t *testing.T, prefix string) {
+	t.Helper()
+	old := tagPrefix
+	tagPrefix = prefix
+	t.Cleanup(func() { tagPrefix = old })
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		prefix, tag string
+		want        version
+		wantErr     bool
+	}{
+		{tag: "v1.2.3", want: version{Major: 1, Minor: 2, Patch: 3}},
+		{tag: "1.2.3", want: version{Major: 1, Minor: 2, Patch: 3}},
+		{tag: "v2.0.0-rc.1", want: version{Major: 2, Pre: "rc.1"}},
+		{tag: "v1.2.3+build.5", want: version{Major: 1, Minor: 2, Patch: 3}},
+		{tag: "v1.2", wantErr: true},
+		{tag: "latest", wantErr: true},
+		{prefix: "tools/", tag: "tools/v0.3.0", want: version{Minor: 3}},
+		{prefix: "tools/", tag: "v0.3.0", wantErr: true},
+		{prefix: "tools/", tag: "other/v0.3.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		setTagPrefix(t, tt.prefix)
+		got, err := parseVersion(tt.tag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseVersion(%q) with prefix %q error = %v, want error %t", tt.tag, tt.prefix, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseVersion(%q) with prefix %q = %+v, want %+v", tt.tag, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestVersionString(t *testing.T) {
+	setTagPrefix(t, "tools/")
+	v := version{Major: 1, Minor: 2, Patch: 3, Pre: "rc.1"}
+	if got, want := v.String(), "tools/v1.2.3-rc.1"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got, want := versionNumber(v.String()), "1.2.3-rc.1"; got != want {
+		t.Errorf("versionNumber(%q) = %q, want %q", v, got, want)
+	}
+}
+
+func TestVersionLess(t *testing.T) {
+	// In SemVer precedence order, which orders prereleases by
+	// comparePrerelease.
+	ordered := []version{
+		{Major: 1, Pre: "1"},
+		{Major: 1, Pre: "alpha"},
+		{Major: 1, Pre: "alpha.1"},
+		{Major: 1, Pre: "alpha.beta"},
+		{Major: 1, Pre: "beta.2"},
+		{Major: 1, Pre: "beta.11"},
+		{Major: 1, Pre: "rc.1"},
+		{Major: 1},
+		{Major: 1, Patch: 1},
+		{Major: 1, Minor: 1},
+	}
+	for i := range ordered {
+		for j := range ordered {
+			if got := ordered[i].less(ordered[j]); got != (i < j) {
+				t.Errorf("%s.less(%s) = %t, want %t", ordered[i], ordered[j], got, i < j)
+			}
+		}
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct {
+		prefix     string
+		tags       []string
+		bump       BumpType
+		prerelease string
+		want       string
+	}{
+		{"", nil, patch, "", "v0.0.1"},
+		{"", []string{"v0.3.0", "v0.2.1"}, patch, "", "v0.3.1"},
+		{"", []string{"v0.3.0", "v0.2.1"}, minor, "", "v0.4.0"},
+		{"", []string{"v0.3.0", "v0.2.1"}, major, "", "v1.0.0"},
+		{"", []string{"v1.4.0", "v2.0.0-rc.1"}, major, "rc", "v2.0.0-rc.2"},
+		{"", []string{"v1.4.0", "v2.0.0-rc.1"}, major, "", "v2.0.0"},
+		{"", []string{"v1.4.0", "v1.5.0-beta.3", "v1.5.0-rc.1"}, minor, "beta", "v1.5.0-beta.4"},
+		{"", []string{"v1.4.0", "not-a-version"}, patch, "", "v1.4.1"},
+		{"tools/", []string{"v3.0.0", "tools/v0.1.0"}, minor, "", "tools/v0.2.0"},
+	}
+	for _, tt := range tests {
+		setTagPrefix(t, tt.prefix)
+		if got := nextVersion(tt.tags, tt.bump, tt.prerelease).String(); got != tt.want {
+			t.Errorf("nextVersion(%q, %s, %q) with prefix %q = %s, want %s", tt.tags, tt.bump, tt.prerelease, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	setTagPrefix(t, "")
+	tags := []string{"v1.0.0", "v1.1.0-rc.1", "v0.9.0"}
+	if got, want := latestVersion(tags).String(), "v1.1.0-rc.1"; got != want {
+		t.Errorf("latestVersion = %s, want %s", got, want)
+	}
+	if got, want := latestStableVersion(tags).String(), "v1.0.0"; got != want {
+		t.Errorf("latestStableVersion = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+var featureSubject = regexp.MustCompile(`^feat(\([^)]*\))?!?:`)
+
+// runWizard guides a maintainer through a release: it shows the unreleased
+// commits and suggests a bump type from them, previews the release notes
+// and lets them be introduced in $EDITOR, shows the CI checks of HEAD, and
+// then runs the release command with the chosen options.
+func runWizard(fs *flag.FlagSet) func() {
+	return func() {
+		if !isTerminal(os.Stdin) {
+			slog.Error("The wizard needs a terminal; use the release command instead")
+			os.Exit(exitPreflight)
+		}
+		in := bufio.NewReader(os.Stdin)
+		// ask and askYes exit when the input is closed, aborting the wizard.
+		ask := func(question, def string) string {
+			answer, err := prompt(in, question, def)
+			if err != nil {
+				os.Exit(exitPreflight)
+			}
+			return answer
+		}
+		askYes := func(question string, def bool) bool {
+			answer, err := yes(in, question, def)
+			if err != nil {
+				os.Exit(exitPreflight)
+			}
+			return answer
+		}
+
+		tags, err := getVersionTags()
+		if err != nil {
+			slog.Error("Could not retrieve current version", "err", err)
+			os.Exit(exitGit)
+		}
+		current := latestVersion(tags)
+		rng := commitRange("HEAD", current.String())
+
+		output, err := newCommand("git", "log", "--no-merges", "--pretty=format:%h %s", rng).Output()
+		if err != nil {
+			slog.Error("Failed to list commits", "err", err)
+			os.Exit(exitGit)
+		}
+		commits := strings.TrimSpace(string(output))
+		if commits == "" {
+			fmt.Printf("Nothing to release since %s\n", current)
+			return
+		}
+		fmt.Printf("Commits since %s:\n\n%s\n\n", current, indent(commits))
+
+		suggested, err := suggestBump(rng, commits)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitGit)
+		}
+		for _, b := range []BumpType{major, minor, patch} {
+			fmt.Printf("  %-5s -> %s\n", b, nextVersion(tags, b, ""))
+		}
+
+		var bump BumpType
+		for !bump.IsValid() {
+			bump = BumpType(ask("Bump type", string(suggested)))
+		}
+		pre := ask("Prerelease identifier, e.g. rc (empty for a stable release)", "")
+		fmt.Printf("\nReleasing %s -> %s\n", current, nextVersion(tags, bump, pre))
+
+		args := []string{"-type=" + string(bump)}
+		if pre != "" {
+			args = append(args, "-prerelease="+pre)
+		}
+
+		f, err := newForge(forgeFlags)
+		if err != nil {
+			fmt.Printf("\nForge releases unavailable: %v\n", err)
+		} else if askYes("Create a forge release?", true) {
+			args = append(args, "-create-release")
+
+			lf, _ := newLinkForge(forgeFlags)
+			notes, err := releaseNotes(lf, releaseRequest{Tag: "HEAD", PreviousTag: current.String()})
+			if err != nil {
+				slog.Error("Failed to render release notes", "err", err)
+				os.Exit(exitGit)
+			}
+			fmt.Printf("\nRelease notes:\n\n%s\n", indent(notes))
+
+			if askYes("Write an introduction to the notes in $EDITOR?", false) {
+				file, err := editNotes(notes)
+				if err != nil {
+					slog.Error(err.Error())
+					os.Exit(exitFailure)
+				}
+				defer os.Remove(file)
+				args = append(args, "-notes-file="+file)
+			}
+
+			if askYes("Create it as a draft?", false) {
+				args = append(args, "-draft")
+			}
+
+			if showChecks(f) && askYes("Require the checks to pass before tagging?", true) {
+				args = append(args, "-check-gate")
+			}
+		}
+
+		fmt.Printf("\nRunning: %s release %s\n", program, strings.Join(args, " "))
+		findCommand("release").execute(args)
+	}
+}
+
+// suggestBump proposes the bump type for the commits in rng following
+// Conventional Commits: major for breaking changes, minor for features, and
+// patch otherwise. commits lists them as "<hash> <subject>" lines.
+func suggestBump(rng, commits string) (BumpType, error) {
+	breaking, err := breakingChanges(rng)
+	if err != nil {
+		return "", err
+	}
+	if len(breaking) > 0 {
+		return major, nil
+	}
+
+	for _, line := range strings.Split(commits, "\n") {
+		_, subject, _ := strings.Cut(line, " ")
+		if featureSubject.MatchString(subject) {
+			return minor, nil
+		}
+	}
+	return patch, nil
+}
+
+// showChecks prints the CI checks of HEAD, reporting whether there are any.
+func showChecks(f forge) bool {
+	commit, err := resolveCommit("HEAD")
+	if err != nil {
+		return false
+	}
+	statuses, err := f.checkStatuses(commit)
+	if err != nil {
+		fmt.Printf("\nCould not query the checks of HEAD: %v\n", err)
+		return false
+	}
+	if len(statuses) == 0 {
+		fmt.Printf("\nNo checks reported for HEAD\n")
+		return false
+	}
+
+	fmt.Printf("\nChecks of HEAD:\n")
+	for _, s := range statuses {
+		fmt.Printf("  %-8s %s\n", s.State, s.Name)
+	}
+	return true
+}
+
+// editNotes opens $EDITOR on a file for an introduction to notes, which
+// are shown as comments, and returns the file.
+func editNotes(notes string) (string, error) {
+	f, err := os.CreateTemp("", "release-notes-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "\n# Write an introduction to the release notes above. Lines starting\n# with '#' are ignored. The generated notes follow:\n#\n")
+	for _, line := range strings.Split(strings.TrimSpace(notes), "\n") {
+		fmt.Fprintf(f, "# %s\n", line)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	// $EDITOR may carry arguments, as in "code --wait".
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			editor = []string{"notepad"}
+		}
+	}
+	cmd := newCommand(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	var kept []string
+	// Editors on Windows may save the file with CRLF line endings.
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			kept = append(kept, line)
+		}
+	}
+	return f.Name(), os.WriteFile(f.Name(), []byte(strings.TrimSpace(strings.Join(kept, "\n"))+"\n"), 0644)
+}
+
+// prompt asks question and returns the answer, or def if it is empty. It
+// fails once the input is closed, e.g. by Ctrl-D.
+func prompt(in *bufio.Reader, question, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	answer, err := in.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return "", fmt.Errorf("no answer: %w", err)
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// yes asks a yes/no question, with def as the answer to an empty reply.
+func yes(in *bufio.Reader, question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer, err := prompt(in, question+" ["+hint+"]", "")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n  ")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// traceCommands, set by -x, prints every external command before it runs,
+// like the shell's set -x.
+var traceCommands bool
+
+// newCommand returns the command running name with args under the run's
+// context.
+func newCommand(name string, args ...string) *exec.Cmd {
+	return commandIn("", name, args...)
+}
+
+// commandIn is like newCommand, with the command running in dir; empty is
+// the current directory.
+func commandIn(dir, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(runCtx, name, args...)
+	cmd.Dir = dir
+	return traceCommand(cmd)
+}
+
+// traceCommand prints cmd with its working directory on stderr if
+// traceCommands is set, returning cmd. Callers create their commands right
+// before running them, so this is when the command runs.
+func traceCommand(cmd *exec.Cmd) *exec.Cmd {
+	if !traceCommands {
+		return cmd
+	}
+	dir := cmd.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	fmt.Fprintf(os.Stderr, "+ %s  # in %s\n", redact(shellQuote(cmd.Args)), dir)
+	return cmd
+}
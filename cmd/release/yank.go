@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+)
+
+// runYank retracts a published version by adding a retract directive to
+// go.mod and pushing it. The go command honors the retraction once a
+// version containing it is released.
+func runYank(fs *flag.FlagSet) func() {
+	var (
+		tag    = fs.String("tag", "", "Version to retract")
+		reason = fs.String("reason", "", "Rationale recorded next to the retraction and shown by 'go list -m -retracted'")
+		dr     = fs.Bool("dry-run", false, "Show what would be done without making changes")
+	)
+
+	return func() {
+		if *tag == "" {
+			fs.Usage()
+			slog.Error("-tag flag is required")
+			os.Exit(exitUsage)
+		}
+		if _, err := parseVersion(*tag); err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitUsage)
+		}
+
+		// The retraction commit stages every modified file, so it must not
+		// find changes the command did not make.
+		changed, err := hasChanges()
+		if err == nil && changed {
+			err = ErrDirtyWorktree
+		}
+		if err != nil {
+			slog.Error(fmt.Sprintf("Cannot retract: %v; commit or stash the changes first", err))
+			os.Exit(exitPreflight)
+		}
+
+		if *dr {
+			slog.Info("DRY RUN MODE - Would retract the version in 'go.mod' and push the change", "tag", *tag)
+			return
+		}
+
+		steps := []string{
+			fmt.Sprintf("Add a retract directive for %s to 'go.mod'", *tag),
+			fmt.Sprintf("Commit and push the change to %s", remoteName),
+		}
+		if err := confirm(fmt.Sprintf("Yanking %s:", *tag), steps); err != nil {
+			slog.Error(err.Error())
+			os.Exit(exitPreflight)
+		}
+
+		if err := retractVersion(*tag, *reason); err != nil {
+			slog.Error("Failed to retract", "tag", *tag, "err", err)
+			os.Exit(exitFailure)
+		}
+
+		if err := commitAndPush(fmt.Sprintf("chore: retract %s", *tag)); err != nil {
+			slog.Error("Failed to push retraction", "err", err)
+			os.Exit(exitGit)
+		}
+
+		success("Retracted; the retraction takes effect with the next release", "tag", *tag)
+	}
+}
+
+// retractVersion adds a retract directive for tag to go.mod, with reason as
+// its rationale comment. The directive names the version without the tag
+// prefix of a module in a subdirectory.
+func retractVersion(tag, reason string) error {
+	v := "v" + versionNumber(tag)
+	if err := newCommand("go", "mod", "edit", "-retract="+v).Run(); err != nil {
+		return fmt.Errorf("failed to update go.mod: %w", err)
+	}
+	if reason == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile("go.mod")
+	if err != nil {
+		return err
+	}
+
+	// go mod edit cannot write the rationale, so it is added to the line
+	// it wrote, either "retract vX" or an entry of a retract block.
+	line := regexp.MustCompile(`(?m)^(\s*(?:retract\s+)?` + regexp.QuoteMeta(v) + `)[ \t]*$`)
+	data = line.ReplaceAll(data, []byte("$1 // "+reason))
+	return os.WriteFile("go.mod", data, 0644)
+}
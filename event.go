@@ -0,0 +1,31 @@
+package testgopkg
+
+import "log/slog"
+
+// Event is the event a Greeter logs when it greets.
+type Event struct {
+	Animal string
+	Size   int
+}
+
+// Event returns the event g logs when it greets.
+func (g *Greeter) Event() Event {
+	return Event{Animal: "walrus", Size: 10}
+}
+
+// Message returns the log message of e.
+func (e Event) Message() string {
+	return "A group of " + e.Animal + " emerges from the ocean"
+}
+
+// Args returns the attributes of e as alternating keys and values, as
+// passed to a Logger.
+func (e Event) Args() []any {
+	return []any{"animal", e.Animal, "size", e.Size}
+}
+
+// LogValue implements slog.LogValuer, for e logged as an attribute of
+// another message.
+func (e Event) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("animal", e.Animal), slog.Int("size", e.Size))
+}
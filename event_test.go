@@ -0,0 +1,34 @@
+package testgopkg
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestEvent(t *testing.T) {
+	e := New().Event()
+	if want := (Event{Animal: "walrus", Size: 10}); e != want {
+		t.Errorf("Event() = %+v, want %+v", e, want)
+	}
+	if got, want := e.Message(), "A group of walrus emerges from the ocean"; got != want {
+		t.Errorf("Message() = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name string
+		log  func(*slog.Logger)
+		want string
+	}{
+		{"args", func(l *slog.Logger) { l.Info(e.Message(), e.Args()...) }, "animal=walrus size=10"},
+		{"log value", func(l *slog.Logger) { l.Info("greeted", "event", e) }, "event.animal=walrus event.size=10"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		tt.log(slog.New(slog.NewTextHandler(&b, nil)))
+		if !strings.Contains(b.String(), tt.want) {
+			t.Errorf("%s: logged %q, want %q", tt.name, b.String(), tt.want)
+		}
+	}
+}
@@ -1,10 +1,3 @@
 module github.com/raducristianpopa/test-go-pkg/v4
 
-go 1.20
-
-require (
-	github.com/raducristianpopa/test-go-pkg/v3 v3.1.0
-	github.com/sirupsen/logrus v1.9.3
-)
-
-require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+go 1.21
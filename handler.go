@@ -0,0 +1,80 @@
+package testgopkg
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+)
+
+// modulePath is the path of the module of the package.
+const modulePath = "github.com/raducristianpopa/test-go-pkg/v4"
+
+// Handler returns an http.Handler serving the greeting of a Greeter made
+// with opts at "/", as text/plain, and the version of the package at
+// "/version", as JSON. The query parameters name and locale override those
+// of opts, e.g. "/?name=Gopher&locale=fr". The greeting is written to the
+// response, whatever the writer of opts.
+func Handler(opts ...Option) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		o := append(opts[:len(opts):len(opts)], WithWriter(w))
+		if name := q.Get("name"); name != "" {
+			o = append(o, WithName(name))
+		}
+		if locale := q.Get("locale"); locale != "" {
+			o = append(o, WithLocale(locale))
+		}
+		g := New(o...)
+		if _, err := g.Greeting(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_ = g.GreetContext(r.Context())
+	})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Version())
+	})
+	return mux
+}
+
+// VersionInfo is the build information of the package.
+type VersionInfo struct {
+	// Module is the module path of the package.
+	Module string `json:"module"`
+	// Version is the module version the binary was built with, or
+	// "(devel)" for a build of the module itself.
+	Version string `json:"version"`
+	// GoVersion is the Go version the binary was built with.
+	GoVersion string `json:"go_version"`
+}
+
+// Version returns the build information of the package in the running
+// binary. Its version is unknown, "", in a binary without build
+// information.
+func Version() VersionInfo {
+	v := VersionInfo{Module: modulePath}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	v.GoVersion = bi.GoVersion
+	mods := append([]*debug.Module{&bi.Main}, bi.Deps...)
+	for _, m := range mods {
+		if m.Path != modulePath {
+			continue
+		}
+		v.Version = m.Version
+		if m.Replace != nil && m.Replace.Version != "" {
+			v.Version = m.Replace.Version
+		}
+		break
+	}
+	return v
+}
@@ -0,0 +1,67 @@
+package testgopkg
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(Handler(WithName("Gopher"), WithoutLogging()))
+	defer srv.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"/", http.StatusOK, "Hello, Gopher\n"},
+		{"/?name=Ada", http.StatusOK, "Hello, Ada\n"},
+		{"/?locale=fr", http.StatusOK, "Bonjour, Gopher\n"},
+		{"/?name=Ada&locale=es-MX", http.StatusOK, "Hola, Ada\n"},
+		{"/?locale=xx", http.StatusBadRequest, "unknown locale \"xx\"\n"},
+		{"/hello", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, tt := range tests {
+		resp, err := srv.Client().Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || string(body) != tt.want {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.path, resp.StatusCode, body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestHandlerVersion(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/version", nil))
+
+	var v VersionInfo
+	if err := json.NewDecoder(rec.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v != Version() {
+		t.Errorf("GET /version = %+v, want %+v", v, Version())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	v := Version()
+	if v.Module != modulePath {
+		t.Errorf("Module = %q, want %q", v.Module, modulePath)
+	}
+	// The test binary is a build of the module itself, so its version is
+	// known, if only as "(devel)".
+	if v.Version == "" || v.GoVersion != runtime.Version() {
+		t.Errorf("Version() = %+v, want a version built with %s", v, runtime.Version())
+	}
+}
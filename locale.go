@@ -0,0 +1,69 @@
+package testgopkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Messages are the messages of a locale.
+type Messages struct {
+	// Template is the template of the greeting; see DefaultTemplate.
+	Template string
+	// World is who is greeted by default, e.g. "Mundo".
+	World string
+}
+
+// DefaultLocale is the locale of a Greeter without WithLocale.
+const DefaultLocale = "en"
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = map[string]Messages{
+		"en": {DefaultTemplate, "World"},
+		"es": {"Hola, {{.Name}}", "Mundo"},
+		"fr": {"Bonjour, {{.Name}}", "le monde"},
+		"de": {"Hallo, {{.Name}}", "Welt"},
+		"it": {"Ciao, {{.Name}}", "Mondo"},
+		"pt": {"Olá, {{.Name}}", "Mundo"},
+	}
+)
+
+// RegisterLocale adds the messages of locale to the catalog, replacing
+// those registered before, built-in ones included. Locales are language
+// tags such as "nl" or "pt-BR", matched case-insensitively.
+func RegisterLocale(locale string, m Messages) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	catalog[strings.ToLower(locale)] = m
+}
+
+// Locales returns the locales of the catalog, sorted.
+func Locales() []string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	locales := make([]string, 0, len(catalog))
+	for l := range catalog {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// lookupLocale returns the messages of locale, falling back from a
+// regional locale such as "es-MX" to its language, "es".
+func lookupLocale(locale string) (Messages, error) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if m, ok := catalog[locale]; ok {
+		return m, nil
+	}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		if m, ok := catalog[lang]; ok {
+			return m, nil
+		}
+	}
+	return Messages{}, fmt.Errorf("unknown locale %q", locale)
+}
@@ -0,0 +1,79 @@
+package testgopkg
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// registerLocale registers m for locale for the test, restoring the catalog
+// afterwards.
+func registerLocale(t *testing.T, locale string, m Messages) {
+	t.Helper()
+	key := strings.ToLower(locale)
+	catalogMu.RLock()
+	old, ok := catalog[key]
+	catalogMu.RUnlock()
+	t.Cleanup(func() {
+		catalogMu.Lock()
+		defer catalogMu.Unlock()
+		if ok {
+			catalog[key] = old
+		} else {
+			delete(catalog, key)
+		}
+	})
+	RegisterLocale(locale, m)
+}
+
+func TestLookupLocale(t *testing.T) {
+	registerLocale(t, "pt-BR", Messages{"Oi, {{.Name}}", "Mundo"})
+	tests := []struct {
+		locale  string
+		want    string // template
+		wantErr bool
+	}{
+		{locale: "en", want: DefaultTemplate},
+		{locale: "ES", want: "Hola, {{.Name}}"},
+		{locale: "es-MX", want: "Hola, {{.Name}}"},
+		{locale: "fr_CA", want: "Bonjour, {{.Name}}"},
+		{locale: "pt-BR", want: "Oi, {{.Name}}"},
+		{locale: "pt_br", want: "Oi, {{.Name}}"},
+		{locale: "pt-PT", want: "Olá, {{.Name}}"},
+		{locale: "xx", wantErr: true},
+		{locale: "xx-ES", wantErr: true},
+		{locale: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		m, err := lookupLocale(tt.locale)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("lookupLocale(%q) error = %v, want error %t", tt.locale, err, tt.wantErr)
+			continue
+		}
+		if m.Template != tt.want {
+			t.Errorf("lookupLocale(%q) = %q, want %q", tt.locale, m.Template, tt.want)
+		}
+	}
+}
+
+func TestRegisterLocale(t *testing.T) {
+	registerLocale(t, "NL", Messages{"Hallo, {{.Name}}", "Wereld"})
+	registerLocale(t, "es", Messages{"¡Hola, {{.Name}}!", "Mundo"})
+
+	tests := []struct {
+		locale, want string
+	}{
+		{"nl", "Hallo, Wereld"},
+		{"nl-BE", "Hallo, Wereld"},
+		{"es", "¡Hola, Mundo!"},
+	}
+	for _, tt := range tests {
+		got, err := New(WithLocale(tt.locale)).Greeting()
+		if err != nil || got != tt.want {
+			t.Errorf("greeting in %s = %q, %v, want %q", tt.locale, got, err, tt.want)
+		}
+	}
+	if locales := Locales(); !slices.Contains(locales, "nl") || !slices.IsSorted(locales) {
+		t.Errorf("Locales() = %q, want them sorted with nl", locales)
+	}
+}
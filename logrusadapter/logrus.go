@@ -0,0 +1,36 @@
+// Package logrusadapter logs the events of testgopkg with logrus. It is a
+// module of its own so that the core package has no dependencies:
+//
+//	err := testgopkg.Greet(testgopkg.WithLogger(logrusadapter.New(nil)))
+package logrusadapter
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is a testgopkg.Logger logging with logrus, the args of an event
+// as its fields.
+type Logger struct {
+	l logrus.FieldLogger
+}
+
+// New returns a Logger logging to l, or to the standard logrus logger if l
+// is nil.
+func New(l logrus.FieldLogger) Logger {
+	if l == nil {
+		l = logrus.StandardLogger()
+	}
+	return Logger{l: l}
+}
+
+// Info logs msg at Info level with args, alternating keys and values, as
+// fields.
+func (a Logger) Info(msg string, args ...any) {
+	fields := make(logrus.Fields, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		fields[fmt.Sprint(args[i])] = args[i+1]
+	}
+	a.l.WithFields(fields).Info(msg)
+}
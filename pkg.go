@@ -2,15 +2,281 @@
 package testgopkg
 
 import (
+	"context"
 	"fmt"
-	log "github.com/sirupsen/logrus"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
 )
 
-func HelloWorld() {
-	log.WithFields(log.Fields{
-		"animal": "walrus",
-		"size":   10,
-	}).Info("A group of walrus emerges from the ocean")
+// Logger is the logging backend of the package. Args are alternating keys
+// and values, as with log/slog, so a *slog.Logger is a Logger.
+// The package depends on nothing but the standard library; for logrus, use
+// the logrusadapter module of this repository.
+type Logger interface {
+	Info(msg string, args ...any)
+}
+
+// NopLogger is a Logger that discards everything, to disable logging.
+type NopLogger struct{}
+
+// Info does nothing.
+func (NopLogger) Info(string, ...any) {}
+
+// contextLogger is implemented by Loggers that take the context of the
+// event, such as *slog.Logger, whose handler may add e.g. the trace ID
+// from it.
+type contextLogger interface {
+	InfoContext(ctx context.Context, msg string, args ...any)
+}
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying l, the Logger of greetings with
+// ctx that are not given one with WithLogger.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx, or nil.
+func FromContext(ctx context.Context) Logger {
+	l, _ := ctx.Value(loggerKey{}).(Logger)
+	return l
+}
+
+// DefaultTemplate is the template of the English greeting. Templates are
+// text/templates executed with who is greeted as .Name.
+const DefaultTemplate = "Hello, {{.Name}}"
+
+// Greeter greets someone. The zero Greeter is not usable; create one with
+// New.
+type Greeter struct {
+	name     string // "": the World of the locale
+	locale   string
+	w        io.Writer
+	logger   Logger // nil: from the context, or slog.Default()
+	template string // "": the Template of the locale
+
+	// logFormat and logOutput, if either is set, configure a logger of
+	// its own instead of the default.
+	logFormat LogFormat
+	logOutput io.Writer
+}
+
+// Option configures a Greeter, or Greet.
+type Option func(*Greeter)
+
+// New returns a Greeter greeting "World" in English on stdout, logging to
+// the Logger of the context or slog.Default(), configured by opts.
+func New(opts ...Option) *Greeter {
+	g := &Greeter{
+		locale: DefaultLocale,
+		w:      os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.logger == nil && (g.logFormat != "" || g.logOutput != nil) {
+		g.logger = newSlogLogger(g.logFormat, g.logOutput)
+	}
+	return g
+}
+
+// WithName sets who is greeted.
+func WithName(name string) Option {
+	return func(g *Greeter) {
+		g.name = name
+	}
+}
+
+// WithLocale sets the locale of the greeting, e.g. "es" or "fr"; see
+// RegisterLocale. An unknown locale fails the greeting.
+func WithLocale(locale string) Option {
+	return func(g *Greeter) {
+		g.locale = locale
+	}
+}
+
+// WithWriter sets where the greeting is written.
+func WithWriter(w io.Writer) Option {
+	return func(g *Greeter) {
+		g.w = w
+	}
+}
+
+// WithLogger makes the Greeter log to l, e.g. a *slog.Logger, instead of
+// the Logger of the context or slog.Default(). A nil l disables logging.
+func WithLogger(l Logger) Option {
+	return func(g *Greeter) {
+		if l == nil {
+			l = NopLogger{}
+		}
+		g.logger = l
+	}
+}
+
+// LogFormat is the format of the log lines of a Greeter.
+type LogFormat string
+
+// Log formats, of slog.TextHandler and slog.JSONHandler.
+const (
+	LogText LogFormat = "text"
+	LogJSON LogFormat = "json"
+)
+
+// WithLogFormat makes the Greeter log in format, to the writer of
+// WithLogOutput or stderr. Any format but LogJSON is LogText. WithLogger
+// takes precedence.
+func WithLogFormat(format LogFormat) Option {
+	return func(g *Greeter) {
+		g.logFormat = format
+	}
+}
+
+// WithLogOutput makes the Greeter log to w, in the format of WithLogFormat
+// or LogText. WithLogger takes precedence.
+func WithLogOutput(w io.Writer) Option {
+	return func(g *Greeter) {
+		g.logOutput = w
+	}
+}
+
+// newSlogLogger returns a Logger logging in format to w, or stderr if w is
+// nil.
+func newSlogLogger(format LogFormat, w io.Writer) Logger {
+	if w == nil {
+		w = os.Stderr
+	}
+	if format == LogJSON {
+		return slog.New(slog.NewJSONHandler(w, nil))
+	}
+	return slog.New(slog.NewTextHandler(w, nil))
+}
+
+// WithoutLogging makes the Greeter log nothing, like WithLogger(nil).
+func WithoutLogging() Option {
+	return WithLogger(nil)
+}
+
+// WithTemplate sets the template of the greeting, instead of the locale's;
+// see DefaultTemplate.
+func WithTemplate(tmpl string) Option {
+	return func(g *Greeter) {
+		g.template = tmpl
+	}
+}
+
+// Greeting returns the greeting of g, without a trailing newline. Unlike
+// Greet, it has no side effects.
+func (g *Greeter) Greeting() (string, error) {
+	m, err := lookupLocale(g.locale)
+	if err != nil {
+		return "", err
+	}
+	if g.template != "" {
+		m.Template = g.template
+	}
+	if g.name != "" {
+		m.World = g.name
+	}
+	t, err := template.New("greeting").Parse(m.Template)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, struct{ Name string }{m.World}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Greet is GreetContext with context.Background().
+func (g *Greeter) Greet() error {
+	return g.GreetContext(context.Background())
+}
+
+// GreetContext logs g.Event() at Info level and writes the greeting,
+// followed by a newline, to the writer of g. It writes nothing if ctx is
+// done, returning ctx.Err().
+func (g *Greeter) GreetContext(ctx context.Context) error {
+	s, err := g.Greeting()
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	e := g.Event()
+	l := g.loggerFor(ctx)
+	if cl, ok := l.(contextLogger); ok {
+		cl.InfoContext(ctx, e.Message(), e.Args()...)
+	} else {
+		l.Info(e.Message(), e.Args()...)
+	}
+	_, err = fmt.Fprintln(g.w, s)
+	return err
+}
+
+// loggerFor returns the Logger of g for a greeting with ctx.
+func (g *Greeter) loggerFor(ctx context.Context) Logger {
+	if g.logger != nil {
+		return g.logger
+	}
+	if l := FromContext(ctx); l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// Greeting returns the greeting Greet prints by default, without the
+// trailing newline. Unlike Greet, it has no side effects.
+func Greeting() string {
+	// The default locale and template always render.
+	s, _ := New().Greeting()
+	return s
+}
+
+// WriteGreeting writes the greeting, followed by a newline, to w. It logs
+// nothing.
+func WriteGreeting(w io.Writer) error {
+	_, err := fmt.Fprintln(w, Greeting())
+	return err
+}
+
+// Fprint writes the greeting, followed by a newline, to w. It logs nothing.
+//
+// Deprecated: Use WriteGreeting, which returns the error of w.
+func Fprint(w io.Writer) {
+	_ = WriteGreeting(w)
+}
+
+// Greet is New(opts...).Greet(): by default it logs an event at Info level
+// and prints the greeting to stdout. Use WithoutLogging, or Greeting or
+// WriteGreeting, to print it without logging.
+func Greet(opts ...Option) error {
+	return New(opts...).Greet()
+}
+
+// GreetContext is New(opts...).GreetContext(ctx): it logs to the Logger of
+// ctx unless given one, and prints nothing if ctx is done.
+func GreetContext(ctx context.Context, opts ...Option) error {
+	return New(opts...).GreetContext(ctx)
+}
+
+// HelloWorld is Greet, ignoring its error.
+//
+// Deprecated: Use Greet, which returns the errors of the template and the
+// writer.
+func HelloWorld(opts ...Option) {
+	_ = Greet(opts...)
+}
 
-	fmt.Printf("Hello, World\n")
+// HelloWorldContext is GreetContext, ignoring its error.
+//
+// Deprecated: Use GreetContext, which returns the errors of the template
+// and the writer, and of ctx.
+func HelloWorldContext(ctx context.Context, opts ...Option) {
+	_ = GreetContext(ctx, opts...)
 }
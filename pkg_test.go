@@ -0,0 +1,195 @@
+package testgopkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// recorder is a Logger recording the messages logged to it.
+type recorder struct {
+	msgs []string
+}
+
+func (r *recorder) Info(msg string, args ...any) {
+	r.msgs = append(r.msgs, fmt.Sprint(append([]any{msg}, args...)...))
+}
+
+func TestGreeter(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    string
+		wantErr bool
+	}{
+		{name: "default", want: "Hello, World"},
+		{name: "name", opts: []Option{WithName("Gopher")}, want: "Hello, Gopher"},
+		{name: "locale", opts: []Option{WithLocale("es")}, want: "Hola, Mundo"},
+		{name: "locale and name", opts: []Option{WithLocale("fr"), WithName("Gopher")}, want: "Bonjour, Gopher"},
+		{name: "regional locale", opts: []Option{WithLocale("de-AT")}, want: "Hallo, Welt"},
+		{name: "template", opts: []Option{WithTemplate("Hi {{.Name}}!")}, want: "Hi World!"},
+		{name: "template and locale", opts: []Option{WithLocale("it"), WithTemplate("{{.Name}}?")}, want: "Mondo?"},
+		{name: "unknown locale", opts: []Option{WithLocale("xx")}, wantErr: true},
+		{name: "invalid template", opts: []Option{WithTemplate("{{.Name")}, wantErr: true},
+		{name: "unknown field", opts: []Option{WithTemplate("{{.Nmae}}")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		g := New(append(tt.opts, WithWriter(&out), WithoutLogging())...)
+
+		got, err := g.Greeting()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Greeting error = %v, want error %t", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Greeting = %q, want %q", tt.name, got, tt.want)
+		}
+
+		err = g.Greet()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Greet error = %v, want error %t", tt.name, err, tt.wantErr)
+		}
+		want := tt.want + "\n"
+		if tt.wantErr {
+			want = ""
+		}
+		if out.String() != want {
+			t.Errorf("%s: Greet wrote %q, want %q", tt.name, out.String(), want)
+		}
+	}
+}
+
+func TestGreeting(t *testing.T) {
+	want, err := New().Greeting()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Greeting(); got != want {
+		t.Errorf("Greeting() = %q, want %q", got, want)
+	}
+
+	var out bytes.Buffer
+	if err := WriteGreeting(&out); err != nil || out.String() != want+"\n" {
+		t.Errorf("WriteGreeting wrote %q, %v, want %q", out.String(), err, want+"\n")
+	}
+}
+
+func TestGreeterLogger(t *testing.T) {
+	e := New().Event()
+	want := []string{fmt.Sprint(append([]any{e.Message()}, e.Args()...)...)}
+
+	tests := []struct {
+		name       string
+		withLogger bool     // WithLogger is given a logger
+		opts       []Option // after WithLogger
+		ctx        bool     // the context carries a logger
+		wantOpts   bool     // the logger of WithLogger logs
+		wantCtx    bool     // the logger of the context logs
+	}{
+		{name: "option", withLogger: true, wantOpts: true},
+		{name: "context", ctx: true, wantCtx: true},
+		{name: "option over context", withLogger: true, ctx: true, wantOpts: true},
+		{name: "without logging", withLogger: true, opts: []Option{WithoutLogging()}, ctx: true},
+		{name: "nil logger", opts: []Option{WithLogger(nil)}, ctx: true},
+	}
+	for _, tt := range tests {
+		optsLog, ctxLog := &recorder{}, &recorder{}
+		o := []Option{WithWriter(&bytes.Buffer{})}
+		if tt.withLogger {
+			o = append(o, WithLogger(optsLog))
+		}
+		o = append(o, tt.opts...)
+		ctx := context.Background()
+		if tt.ctx {
+			ctx = NewContext(ctx, ctxLog)
+		}
+
+		if err := New(o...).GreetContext(ctx); err != nil {
+			t.Errorf("%s: GreetContext error: %v", tt.name, err)
+			continue
+		}
+		if got := slices.Equal(optsLog.msgs, want); got != tt.wantOpts {
+			t.Errorf("%s: the logger of WithLogger logged %q", tt.name, optsLog.msgs)
+		}
+		if got := slices.Equal(ctxLog.msgs, want); got != tt.wantCtx {
+			t.Errorf("%s: the logger of the context logged %q", tt.name, ctxLog.msgs)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if l := FromContext(context.Background()); l != nil {
+		t.Errorf("FromContext of an empty context = %v, want nil", l)
+	}
+	r := &recorder{}
+	if l := FromContext(NewContext(context.Background(), r)); l != r {
+		t.Errorf("FromContext = %v, want the logger of NewContext", l)
+	}
+}
+
+func TestWithLogFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format LogFormat
+		json   bool
+	}{
+		{"text", LogText, false},
+		{"json", LogJSON, true},
+		{"unknown", "xml", false},
+		{"output only", "", false},
+	}
+	for _, tt := range tests {
+		var log bytes.Buffer
+		if err := Greet(WithWriter(&bytes.Buffer{}), WithLogFormat(tt.format), WithLogOutput(&log)); err != nil {
+			t.Errorf("%s: Greet error: %v", tt.name, err)
+			continue
+		}
+
+		line := strings.TrimSpace(log.String())
+		if tt.json {
+			var rec map[string]any
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				t.Errorf("%s: log %q is not JSON: %v", tt.name, line, err)
+				continue
+			}
+			if rec["msg"] != New().Event().Message() || rec["animal"] != "walrus" || rec["size"] != 10.0 {
+				t.Errorf("%s: logged %v", tt.name, rec)
+			}
+			continue
+		}
+		if !strings.Contains(line, "level=INFO") || !strings.Contains(line, "animal=walrus size=10") {
+			t.Errorf("%s: logged %q, want a text line with the event", tt.name, line)
+		}
+	}
+}
+
+func TestWithLogFormatLoggerPrecedence(t *testing.T) {
+	var log bytes.Buffer
+	r := &recorder{}
+	if err := Greet(WithWriter(&bytes.Buffer{}), WithLogFormat(LogJSON), WithLogOutput(&log), WithLogger(r)); err != nil {
+		t.Fatal(err)
+	}
+	if log.Len() != 0 || len(r.msgs) != 1 {
+		t.Errorf("logged %q to the output and %q to the logger, want only the logger", log.String(), r.msgs)
+	}
+}
+
+func TestGreetContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var out bytes.Buffer
+	r := &recorder{}
+	err := GreetContext(ctx, WithWriter(&out), WithLogger(r))
+	if err != context.Canceled {
+		t.Errorf("GreetContext error = %v, want context.Canceled", err)
+	}
+	if out.Len() != 0 || len(r.msgs) != 0 {
+		t.Errorf("a canceled GreetContext wrote %q and logged %q, want nothing", out.String(), r.msgs)
+	}
+}
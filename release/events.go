@@ -0,0 +1,35 @@
+package release
+
+import "time"
+
+// EventType is the type of an Event.
+type EventType string
+
+// Types of the events of a release run.
+const (
+	StepStarted     EventType = "step_started"
+	StepFinished    EventType = "step_finished"
+	VersionComputed EventType = "version_computed"
+	TagPushed       EventType = "tag_pushed"
+)
+
+// Results of a step in its StepFinished event.
+const (
+	StepOK      = "ok"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// Event is a point of progress of a release run, passed to the observer of
+// the run as it happens, e.g. to drive a UI or an audit system.
+type Event struct {
+	Type            EventType `json:"type"`
+	Time            time.Time `json:"time"`
+	Step            string    `json:"step,omitempty"`             // step events
+	Result          string    `json:"result,omitempty"`           // StepFinished: StepOK, StepFailed, or StepSkipped
+	Seconds         float64   `json:"seconds,omitempty"`          // StepFinished
+	PreviousVersion string    `json:"previous_version,omitempty"` // VersionComputed
+	NewVersion      string    `json:"new_version,omitempty"`      // VersionComputed
+	Tag             string    `json:"tag,omitempty"`              // VersionComputed, TagPushed
+	Commit          string    `json:"commit,omitempty"`           // TagPushed
+}
@@ -0,0 +1,138 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// git runs git with args in the module directory, returning its output.
+func (r *Releaser) git(ctx context.Context, args ...string) (string, error) {
+	return r.run(ctx, "git", args...)
+}
+
+// run runs name with args in the module directory, returning its output.
+// The error of a failed command includes its stderr.
+func (r *Releaser) run(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = r.opts.Dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return string(out), nil
+}
+
+// updateGoMod changes the module path to the one of the new major version,
+// and the imports of the module's packages with it, in go.mod and the Go
+// files of the module.
+func (r *Releaser) updateGoMod(ctx context.Context, s *State) error {
+	out, err := r.run(ctx, "go", "list", "-m")
+	if err != nil {
+		return err
+	}
+	current := strings.TrimSpace(out)
+	next := ModulePath(current, s.Result.NewVersion.Major)
+	if next == current {
+		return nil
+	}
+	r.log.Info("Updating module path", "from", current, "to", next)
+
+	if _, err := r.run(ctx, "go", "mod", "edit", "-module="+next); err != nil {
+		return err
+	}
+	s.changed = append(s.changed, "go.mod")
+	err = WalkModule(r.opts.Dir, func(path string, _ os.DirEntry) error {
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		updated := ReplaceModulePath(data, current, next)
+		if bytes.Equal(updated, data) {
+			return nil
+		}
+		rel, err := filepath.Rel(r.opts.Dir, path)
+		if err != nil {
+			return err
+		}
+		s.changed = append(s.changed, rel)
+		return os.WriteFile(path, updated, 0o644)
+	})
+	if err != nil {
+		return fmt.Errorf("updating imports: %w", err)
+	}
+	if _, err = r.run(ctx, "go", "mod", "tidy"); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(r.opts.Dir, "go.sum")); err == nil {
+		s.changed = append(s.changed, "go.sum")
+	}
+	return nil
+}
+
+// commit commits the files the release changed, if any.
+func (r *Releaser) commit(ctx context.Context, s *State) error {
+	if len(s.changed) == 0 {
+		r.log.Info("No changes to commit")
+		return nil
+	}
+	if _, err := r.git(ctx, append([]string{"add", "--"}, s.changed...)...); err != nil {
+		return err
+	}
+	if _, err := r.git(ctx, "diff", "--cached", "--quiet"); err == nil {
+		r.log.Info("No changes to commit")
+		return nil
+	}
+	msg := fmt.Sprintf("chore: update module path and related files for %s", s.Result.Tag)
+	if _, err := r.git(ctx, "commit", "-m", msg); err != nil {
+		return err
+	}
+	r.log.Info("Committed changes", "message", msg)
+	return nil
+}
+
+// commitAndPush commits the modified files and pushes the current branch.
+func (r *Releaser) commitAndPush(ctx context.Context, s *State) error {
+	if err := r.commit(ctx, s); err != nil {
+		return err
+	}
+	if _, err := r.git(ctx, "push", r.opts.Remote, "HEAD"); err != nil {
+		return err
+	}
+	r.log.Info("Pushed changes", "remote", r.opts.Remote)
+	return nil
+}
+
+// tag tags HEAD with the new version.
+func (r *Releaser) tag(ctx context.Context, s *State) error {
+	if _, err := r.git(ctx, "tag", s.Result.Tag, "HEAD"); err != nil {
+		return err
+	}
+	out, err := r.git(ctx, "rev-parse", s.Result.Tag+"^{commit}")
+	if err != nil {
+		return err
+	}
+	s.Result.Commit = strings.TrimSpace(out)
+	r.log.Info("Created tag", "tag", s.Result.Tag, "commit", s.Result.Commit)
+	return nil
+}
+
+// pushTag pushes the tag of the new version.
+func (r *Releaser) pushTag(ctx context.Context, s *State) error {
+	if _, err := r.git(ctx, "push", r.opts.Remote, s.Result.Tag); err != nil {
+		return err
+	}
+	s.Result.Pushed = true
+	r.log.Info("Pushed tag", "tag", s.Result.Tag, "remote", r.opts.Remote)
+	r.emit(Event{Type: TagPushed, Tag: s.Result.Tag, Commit: s.Result.Commit})
+	return nil
+}
@@ -0,0 +1,60 @@
+package release
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var majorSuffix = regexp.MustCompile(`/v\d+$`)
+
+// ModulePath returns the path of major version major of module, e.g.
+// example.com/m/v3 for major 3 of example.com/m/v2, and example.com/m for
+// major 1.
+func ModulePath(module string, major int) string {
+	base := majorSuffix.ReplaceAllString(module, "")
+	if major >= 2 {
+		return fmt.Sprintf("%s/v%d", base, major)
+	}
+	return base
+}
+
+// ReplaceModulePath returns data with the quoted paths of module current,
+// such as imports, moved to module next. A path of the module is current
+// followed by a closing quote or "/", unlike the one of a module like
+// current+"-utils".
+func ReplaceModulePath(data []byte, current, next string) []byte {
+	re := regexp.MustCompile(`"` + regexp.QuoteMeta(current) + `(["/])`)
+	return re.ReplaceAll(data, []byte(`"`+strings.ReplaceAll(next, "$", "$$")+`$1`))
+}
+
+// WalkModule calls fn for each regular file of the module in dir. It skips
+// hidden, vendor, and dist directories, and nested modules, which have
+// module paths of their own.
+func WalkModule(dir string, fn func(path string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == dir {
+				return nil
+			}
+			switch name := d.Name(); {
+			case strings.HasPrefix(name, "."), name == "vendor", name == "dist":
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return fn(path, d)
+	})
+}
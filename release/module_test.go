@@ -0,0 +1,80 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestModulePath(t *testing.T) {
+	tests := []struct {
+		module string
+		major  int
+		want   string
+	}{
+		{"example.com/m", 1, "example.com/m"},
+		{"example.com/m", 2, "example.com/m/v2"},
+		{"example.com/m/v2", 3, "example.com/m/v3"},
+		{"example.com/m/v2", 1, "example.com/m"},
+		{"example.com/m/v2", 0, "example.com/m"},
+		{"example.com/v2x", 2, "example.com/v2x/v2"},
+	}
+	for _, tt := range tests {
+		if got := ModulePath(tt.module, tt.major); got != tt.want {
+			t.Errorf("ModulePath(%q, %d) = %q, want %q", tt.module, tt.major, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceModulePath(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"module", `import "example.com/m"`, `import "example.com/m/v2"`},
+		{"package", `import "example.com/m/sub"`, `import "example.com/m/v2/sub"`},
+		{"other module", `import "example.com/m-utils"`, `import "example.com/m-utils"`},
+		{"longer path", `import "example.com/mod"`, `import "example.com/mod"`},
+		{"unquoted", `go get example.com/m`, `go get example.com/m`},
+		{
+			"several",
+			"import (\n\t\"example.com/m\"\n\t\"example.com/m/a\"\n\t\"example.com/ma\"\n)",
+			"import (\n\t\"example.com/m/v2\"\n\t\"example.com/m/v2/a\"\n\t\"example.com/ma\"\n)",
+		},
+	}
+	for _, tt := range tests {
+		if got := string(ReplaceModulePath([]byte(tt.in), "example.com/m", "example.com/m/v2")); got != tt.want {
+			t.Errorf("%s: ReplaceModulePath(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWalkModule(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"go.mod", "a.go", "sub/b.go",
+		".git/config", "vendor/v.go", "dist/d.go", "nested/go.mod", "nested/n.go",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	err := WalkModule(dir, func(path string, _ os.DirEntry) error {
+		rel, err := filepath.Rel(dir, path)
+		got = append(got, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	if want := []string{"a.go", "go.mod", "sub/b.go"}; !slices.Equal(got, want) {
+		t.Errorf("WalkModule visited %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+// Package release releases the next version of a Go module: it computes
+// the version from the git tags, updates the module path for a new major
+// version, and tags and pushes the release, for tools that embed the flow
+// instead of running the release command (cmd/release). The command shares
+// the version and module path logic of the package, but orchestrates the
+// commit, tag, and push itself, to resume interrupted releases and open
+// pull requests, and adds forge releases, artifacts, and gates on top.
+// Embedders add their own steps with Options.Steps.
+//
+//	res, err := release.New(release.Options{Bump: release.Minor}).Run(ctx)
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTagExists is returned for a release whose tag already exists.
+	ErrTagExists = errors.New("tag already exists")
+	// ErrDirtyWorktree is returned for a release from a worktree with
+	// uncommitted changes to tracked files, which the released commit
+	// would not contain.
+	ErrDirtyWorktree = errors.New("worktree has uncommitted changes")
+)
+
+// Options configure a Releaser.
+type Options struct {
+	// Dir is the directory of the module, in a git repository; empty is
+	// the current directory.
+	Dir string
+	// Bump is the version bump of the release.
+	Bump Bump
+	// Prerelease, if set, releases a prerelease with this identifier, e.g.
+	// "rc" for v2.0.0-rc.1.
+	Prerelease string
+	// TagPrefix precedes the "v" of version tags, for modules in a
+	// subdirectory of the repository such as "tools/" for tools/v1.2.3.
+	TagPrefix string
+	// Remote is the git remote the release is pushed to; empty is
+	// "origin".
+	Remote string
+	// NoPush commits and tags locally only.
+	NoPush bool
+	// DryRun only computes the version, making no changes.
+	DryRun bool
+	// Logger receives the progress of the release; nil discards it.
+	Logger *slog.Logger
+	// Observer, if set, is called with each event of the release, in
+	// order.
+	Observer func(Event)
+	// Steps are custom steps inserted into the pipeline at their
+	// positions.
+	Steps []Step
+}
+
+// Result is the outcome of a release.
+type Result struct {
+	PreviousVersion Version
+	NewVersion      Version
+	// Tag is the tag of the new version, with the tag prefix.
+	Tag string
+	// Commit is the released commit; empty for a dry run.
+	Commit string
+	// Steps are the steps completed, in order.
+	Steps []string
+	// Pushed is set once the tag is pushed.
+	Pushed bool
+}
+
+// Releaser runs releases with its options.
+type Releaser struct {
+	opts Options
+	log  *slog.Logger
+}
+
+// New returns a Releaser with opts.
+func New(opts Options) *Releaser {
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+	if opts.Remote == "" {
+		opts.Remote = "origin"
+	}
+	log := opts.Logger
+	if log == nil {
+		log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Releaser{opts: opts, log: log}
+}
+
+// Names of the built-in steps of the pipeline, in order. Only a major
+// release updates the module path and commits it, with commit-and-push or,
+// with NoPush, commit; push-tag is skipped with NoPush too.
+const (
+	StepUpdateGoMod   = "update-go-mod"
+	StepCommit        = "commit"
+	StepCommitAndPush = "commit-and-push"
+	StepTag           = "tag"
+	StepPushTag       = "push-tag"
+)
+
+var builtinSteps = []string{StepUpdateGoMod, StepCommit, StepCommitAndPush, StepTag, StepPushTag}
+
+// Step is a step of the release pipeline.
+type Step struct {
+	// Name names the step in events and in Result.Steps.
+	Name string
+	// At is the position of a custom step: "before:<step>" or
+	// "after:<step>" of a built-in step, e.g. "after:tag". Steps at the
+	// same position run in the order given. A step positioned at a
+	// built-in step the release does not run, such as "after:commit" of a
+	// minor release, does not run either.
+	At string
+	// Run runs the step.
+	Run func(ctx context.Context, s *State) error
+}
+
+// State is the state of a release run, shared by its steps.
+type State struct {
+	// Result is the release so far: the versions and tag are known to all
+	// steps, the commit once the tag step completed.
+	Result *Result
+	// Values carry data from step to step.
+	Values map[string]any
+	// Logger is the logger of the Releaser.
+	Logger *slog.Logger
+
+	changed []string // files changed by the release, to commit
+}
+
+// Run releases the next version. Canceling ctx kills the running git or go
+// command and fails the release. A release failing after its tag was
+// created locally but before it was pushed deletes the tag again.
+func (r *Releaser) Run(ctx context.Context) (*Result, error) {
+	if !r.opts.Bump.IsValid() {
+		return nil, fmt.Errorf("invalid bump %q, must be major, minor, or patch", r.opts.Bump)
+	}
+	steps, err := r.pipeline()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := r.git(ctx, "tag", "-l")
+	if err != nil {
+		return nil, err
+	}
+	tags := strings.Fields(out)
+	res := &Result{
+		PreviousVersion: Latest(tags, r.opts.TagPrefix, true),
+		NewVersion:      Next(tags, r.opts.TagPrefix, r.opts.Bump, r.opts.Prerelease),
+	}
+	res.Tag = r.opts.TagPrefix + res.NewVersion.String()
+	if slices.Contains(tags, res.Tag) {
+		return res, fmt.Errorf("%w: %s", ErrTagExists, res.Tag)
+	}
+	r.log.Info("New version", "from", r.opts.TagPrefix+res.PreviousVersion.String(), "to", res.Tag)
+	r.emit(Event{Type: VersionComputed, PreviousVersion: versionNumber(res.PreviousVersion), NewVersion: versionNumber(res.NewVersion), Tag: res.Tag})
+	if r.opts.DryRun {
+		return res, nil
+	}
+	status, err := r.git(ctx, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return res, err
+	}
+	if status != "" {
+		return res, fmt.Errorf("%w: %s", ErrDirtyWorktree, strings.ReplaceAll(strings.TrimSpace(status), "\n", ", "))
+	}
+
+	state := &State{Result: res, Values: make(map[string]any), Logger: r.log}
+	tagged := false
+	for _, s := range steps {
+		start := time.Now()
+		r.emit(Event{Type: StepStarted, Step: s.Name})
+		err := s.Run(ctx, state)
+		result := StepOK
+		if err != nil {
+			result = StepFailed
+		}
+		r.emit(Event{Type: StepFinished, Step: s.Name, Result: result, Seconds: time.Since(start).Seconds()})
+		if err != nil {
+			if tagged && !res.Pushed {
+				// The tag is deleted even if ctx was canceled.
+				if _, derr := r.git(context.WithoutCancel(ctx), "tag", "-d", res.Tag); derr != nil {
+					r.log.Error("Failed to delete the tag", "tag", res.Tag, "err", derr)
+				}
+			}
+			return res, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		res.Steps = append(res.Steps, s.Name)
+		tagged = tagged || s.Name == StepTag
+	}
+	return res, nil
+}
+
+// pipeline returns the steps of the release, in order: the built-in ones,
+// and the custom ones of the options at their positions.
+func (r *Releaser) pipeline() ([]Step, error) {
+	var builtin []Step
+	if r.opts.Bump == Major {
+		builtin = append(builtin, Step{Name: StepUpdateGoMod, Run: r.updateGoMod})
+		if r.opts.NoPush {
+			builtin = append(builtin, Step{Name: StepCommit, Run: r.commit})
+		} else {
+			builtin = append(builtin, Step{Name: StepCommitAndPush, Run: r.commitAndPush})
+		}
+	}
+	builtin = append(builtin, Step{Name: StepTag, Run: r.tag})
+	if !r.opts.NoPush {
+		builtin = append(builtin, Step{Name: StepPushTag, Run: r.pushTag})
+	}
+
+	custom := make(map[string][]Step)
+	for _, s := range r.opts.Steps {
+		where, anchor, _ := strings.Cut(s.At, ":")
+		switch {
+		case s.Name == "" || s.Run == nil:
+			return nil, fmt.Errorf("custom step %q: a name and a run function are required", s.Name)
+		case slices.Contains(builtinSteps, s.Name):
+			return nil, fmt.Errorf("custom step %q: the name of a built-in step", s.Name)
+		case where != "before" && where != "after" || !slices.Contains(builtinSteps, anchor):
+			return nil, fmt.Errorf("custom step %q: invalid position %q, must be before:<step> or after:<step> of a built-in step (%s)", s.Name, s.At, strings.Join(builtinSteps, ", "))
+		}
+		custom[s.At] = append(custom[s.At], s)
+	}
+
+	var steps []Step
+	for _, b := range builtin {
+		steps = append(steps, custom["before:"+b.Name]...)
+		steps = append(steps, b)
+		steps = append(steps, custom["after:"+b.Name]...)
+	}
+	return steps, nil
+}
+
+// emit passes e, stamped with the current time, to the observer.
+func (r *Releaser) emit(e Event) {
+	if r.opts.Observer != nil {
+		e.Time = time.Now().UTC()
+		r.opts.Observer(e)
+	}
+}
+
+// versionNumber returns v without the "v", e.g. "1.2.3".
+func versionNumber(v Version) string {
+	return v.String()[1:]
+}
@@ -0,0 +1,202 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	noop := func(context.Context, *State) error { return nil }
+	tests := []struct {
+		name  string
+		opts  Options
+		want  []string
+		error bool
+	}{
+		{
+			name: "patch",
+			opts: Options{Bump: Patch},
+			want: []string{StepTag, StepPushTag},
+		},
+		{
+			name: "patch without push",
+			opts: Options{Bump: Patch, NoPush: true},
+			want: []string{StepTag},
+		},
+		{
+			name: "major",
+			opts: Options{Bump: Major},
+			want: []string{StepUpdateGoMod, StepCommitAndPush, StepTag, StepPushTag},
+		},
+		{
+			name: "major without push",
+			opts: Options{Bump: Major, NoPush: true},
+			want: []string{StepUpdateGoMod, StepCommit, StepTag},
+		},
+		{
+			name: "custom steps",
+			opts: Options{Bump: Minor, Steps: []Step{
+				{Name: "notify", At: "after:push-tag", Run: noop},
+				{Name: "test", At: "before:tag", Run: noop},
+				{Name: "build", At: "after:tag", Run: noop},
+				{Name: "sign", At: "after:tag", Run: noop},
+			}},
+			want: []string{"test", StepTag, "build", "sign", StepPushTag, "notify"},
+		},
+		{
+			name: "custom step at a skipped step",
+			opts: Options{Bump: Minor, Steps: []Step{
+				{Name: "review", At: "after:update-go-mod", Run: noop},
+			}},
+			want: []string{StepTag, StepPushTag},
+		},
+		{
+			name:  "unknown position",
+			opts:  Options{Bump: Minor, Steps: []Step{{Name: "x", At: "after:build", Run: noop}}},
+			error: true,
+		},
+		{
+			name:  "invalid position",
+			opts:  Options{Bump: Minor, Steps: []Step{{Name: "x", At: "tag", Run: noop}}},
+			error: true,
+		},
+		{
+			name:  "built-in name",
+			opts:  Options{Bump: Minor, Steps: []Step{{Name: StepTag, At: "after:tag", Run: noop}}},
+			error: true,
+		},
+		{
+			name:  "no run function",
+			opts:  Options{Bump: Minor, Steps: []Step{{Name: "x", At: "after:tag"}}},
+			error: true,
+		},
+	}
+	for _, tt := range tests {
+		steps, err := New(tt.opts).pipeline()
+		if (err != nil) != tt.error {
+			t.Errorf("%s: pipeline() error = %v, want error %t", tt.name, err, tt.error)
+			continue
+		}
+		var got []string
+		for _, s := range steps {
+			got = append(got, s.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: pipeline() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// newRepo returns a git repository of module example.com/m with a commit
+// tagged with tags.
+func newRepo(t *testing.T, tags ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "init", "-q")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-q", "-m", "initial")
+	for _, tag := range tags {
+		git(t, dir, "tag", tag)
+	}
+	return dir
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestRun(t *testing.T) {
+	dir := newRepo(t, "v1.0.0")
+	var events []string
+	res, err := New(Options{
+		Dir:    dir,
+		Bump:   Minor,
+		NoPush: true,
+		Observer: func(e Event) {
+			events = append(events, string(e.Type)+" "+e.Step)
+		},
+		Steps: []Step{{Name: "check", At: "after:tag", Run: func(_ context.Context, s *State) error {
+			if s.Result.Commit == "" {
+				return errors.New("the commit is unknown after the tag step")
+			}
+			return nil
+		}}},
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Tag != "v1.1.0" || res.PreviousVersion.String() != "v1.0.0" {
+		t.Errorf("released %s after %s, want v1.1.0 after v1.0.0", res.Tag, res.PreviousVersion)
+	}
+	if want := []string{StepTag, "check"}; !slices.Equal(res.Steps, want) {
+		t.Errorf("Steps = %q, want %q", res.Steps, want)
+	}
+	if got := git(t, dir, "rev-parse", "v1.1.0^{commit}"); got != res.Commit {
+		t.Errorf("v1.1.0 is at %s, want %s", got, res.Commit)
+	}
+	want := []string{"version_computed ", "step_started tag", "step_finished tag", "step_started check", "step_finished check"}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+}
+
+func TestRunDeletesTagOnFailure(t *testing.T) {
+	dir := newRepo(t, "v1.0.0")
+	_, err := New(Options{
+		Dir:    dir,
+		Bump:   Patch,
+		NoPush: true,
+		Steps: []Step{{Name: "fail", At: "after:tag", Run: func(context.Context, *State) error {
+			return errors.New("failed")
+		}}},
+	}).Run(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "fail: ") {
+		t.Fatalf("Run error = %v, want the failure of the fail step", err)
+	}
+	if tags := git(t, dir, "tag", "-l"); tags != "v1.0.0" {
+		t.Errorf("tags after the failed release = %q, want only v1.0.0", tags)
+	}
+}
+
+func TestRunRefuses(t *testing.T) {
+	dir := newRepo(t, "v1.0.0")
+	if _, err := New(Options{Dir: dir, Bump: "huge"}).Run(context.Background()); err == nil {
+		t.Error("Run with an invalid bump succeeded")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(Options{Dir: dir, Bump: Patch, NoPush: true}).Run(context.Background()); !errors.Is(err, ErrDirtyWorktree) {
+		t.Errorf("Run in a dirty worktree error = %v, want ErrDirtyWorktree", err)
+	}
+	// A dry run changes nothing, so the worktree may be dirty.
+	if res, err := New(Options{Dir: dir, Bump: Patch, DryRun: true}).Run(context.Background()); err != nil || res.Tag != "v1.0.1" {
+		t.Errorf("dry run = %v, %v, want v1.0.1", res, err)
+	}
+}
@@ -0,0 +1,162 @@
+package release
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Bump is the kind of version bump of a release.
+type Bump string
+
+// Version bumps.
+const (
+	Major Bump = "major"
+	Minor Bump = "minor"
+	Patch Bump = "patch"
+)
+
+// IsValid reports whether b is one of the version bumps.
+func (b Bump) IsValid() bool {
+	return b == Major || b == Minor || b == Patch
+}
+
+// Version is a semantic version.
+type Version struct {
+	Major, Minor, Patch int
+	// Pre is the prerelease suffix without the leading "-", e.g. "rc.1".
+	Pre string
+}
+
+// String returns v as "v1.2.3" or "v1.2.3-rc.1", without a tag prefix.
+func (v Version) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Less orders versions by SemVer precedence: a prerelease sorts before the
+// release it precedes.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	if v.Patch != o.Patch {
+		return v.Patch < o.Patch
+	}
+	switch {
+	case v.Pre == o.Pre, v.Pre == "":
+		return false
+	case o.Pre == "":
+		return true
+	default:
+		return comparePrerelease(v.Pre, o.Pre) < 0
+	}
+}
+
+// comparePrerelease compares dot-separated prerelease identifiers:
+// numeric identifiers numerically and below alphanumeric ones, which
+// compare lexically; a shorter list of otherwise equal identifiers sorts
+// first.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(as) - len(bs)
+}
+
+var versionRE = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// ParseVersion parses tag, a version prefixed with prefix, such as
+// "tools/v1.2.3" for prefix "tools/". The "v" is optional, and build
+// metadata, as in "v1.2.3+build.5", is ignored.
+func ParseVersion(tag, prefix string) (Version, error) {
+	s, ok := strings.CutPrefix(tag, prefix)
+	if !ok {
+		return Version{}, fmt.Errorf("invalid version %q: expected prefix %q", tag, prefix)
+	}
+	m := versionRE.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("invalid version %q", tag)
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+	return Version{Major: major, Minor: minor, Patch: patch, Pre: m[4]}, nil
+}
+
+// Latest returns the highest version among tags with prefix, ignoring
+// other tags, and prereleases unless withPre is set.
+func Latest(tags []string, prefix string, withPre bool) Version {
+	var latest Version
+	for _, tag := range tags {
+		v, err := ParseVersion(tag, prefix)
+		if err == nil && (withPre || v.Pre == "") && latest.Less(v) {
+			latest = v
+		}
+	}
+	return latest
+}
+
+// Next computes the version to release after the versions of tags with
+// prefix. Bumps are relative to the latest stable version, so a series of
+// prereleases (v2.0.0-rc.1, v2.0.0-rc.2, ...) all target the same release,
+// and a bump without prerelease finalizes it. The prerelease number
+// continues from the highest existing tag in the series.
+func Next(tags []string, prefix string, bump Bump, prerelease string) Version {
+	current := Latest(tags, prefix, false)
+	var next Version
+	switch bump {
+	case Major:
+		next = Version{Major: current.Major + 1}
+	case Minor:
+		next = Version{Major: current.Major, Minor: current.Minor + 1}
+	default:
+		next = Version{Major: current.Major, Minor: current.Minor, Patch: current.Patch + 1}
+	}
+	if prerelease == "" {
+		return next
+	}
+
+	n := 0
+	for _, tag := range tags {
+		v, err := ParseVersion(tag, prefix)
+		if err != nil || v.Major != next.Major || v.Minor != next.Minor || v.Patch != next.Patch {
+			continue
+		}
+		suffix, ok := strings.CutPrefix(v.Pre, prerelease+".")
+		if !ok {
+			continue
+		}
+		if i, err := strconv.Atoi(suffix); err == nil && i > n {
+			n = i
+		}
+	}
+	next.Pre = fmt.Sprintf("%s.%d", prerelease, n+1)
+	return next
+}
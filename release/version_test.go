@@ -0,0 +1,128 @@
+package release
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag, prefix string
+		want        Version
+		wantErr     bool
+	}{
+		{tag: "v1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{tag: "1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{tag: "v2.0.0-rc.1", want: Version{Major: 2, Pre: "rc.1"}},
+		{tag: "v1.2.3+build.5", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{tag: "v1.2.3-beta+exp.sha", want: Version{Major: 1, Minor: 2, Patch: 3, Pre: "beta"}},
+		{tag: "tools/v0.4.1", prefix: "tools/", want: Version{Minor: 4, Patch: 1}},
+		{tag: "v0.4.1", prefix: "tools/", wantErr: true},
+		{tag: "tools/v0.4.1", wantErr: true},
+		{tag: "v1.2", wantErr: true},
+		{tag: "v1.2.3.4", wantErr: true},
+		{tag: "v1.2.3-", wantErr: true},
+		{tag: "release-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.tag, tt.prefix)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q, %q) error = %v, want error %t", tt.tag, tt.prefix, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q, %q) = %+v, want %+v", tt.tag, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestComparePrerelease(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // sign
+	}{
+		{"rc.1", "rc.1", 0},
+		{"rc.1", "rc.2", -1},
+		{"rc.2", "rc.10", -1},
+		{"alpha", "beta", -1},
+		{"alpha.1", "alpha", 1},
+		{"1", "alpha", -1},
+		{"alpha", "1", 1},
+		{"alpha.beta", "alpha.1", 1},
+		{"beta.11", "beta.2", 1},
+	}
+	for _, tt := range tests {
+		got := comparePrerelease(tt.a, tt.b)
+		if sign(got) != tt.want {
+			t.Errorf("comparePrerelease(%q, %q) = %d, want sign %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func TestVersionLess(t *testing.T) {
+	// In SemVer precedence order.
+	ordered := []string{"v0.9.9", "v1.0.0-alpha", "v1.0.0-alpha.1", "v1.0.0-beta", "v1.0.0-rc.1", "v1.0.0", "v1.0.1", "v1.1.0", "v2.0.0"}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := ParseVersion(ordered[i], "")
+			b, _ := ParseVersion(ordered[j], "")
+			if got := a.Less(b); got != (i < j) {
+				t.Errorf("%s.Less(%s) = %t, want %t", ordered[i], ordered[j], got, i < j)
+			}
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.1.0", "v1.2.0-rc.1", "tools/v3.0.0", "nightly", "v1.0.1"}
+	if got, want := Latest(tags, "", true), (Version{Major: 1, Minor: 2, Pre: "rc.1"}); got != want {
+		t.Errorf("Latest(withPre) = %v, want %v", got, want)
+	}
+	if got, want := Latest(tags, "", false), (Version{Major: 1, Minor: 1}); got != want {
+		t.Errorf("Latest = %v, want %v", got, want)
+	}
+	if got, want := Latest(tags, "tools/", false), (Version{Major: 3}); got != want {
+		t.Errorf("Latest(tools/) = %v, want %v", got, want)
+	}
+	if got := Latest(nil, "", true); got != (Version{}) {
+		t.Errorf("Latest(nil) = %v, want the zero version", got)
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		tags       []string
+		prefix     string
+		bump       Bump
+		prerelease string
+		want       string
+	}{
+		{nil, "", Patch, "", "v0.0.1"},
+		{nil, "", Minor, "", "v0.1.0"},
+		{nil, "", Major, "", "v1.0.0"},
+		{[]string{"v1.2.3"}, "", Patch, "", "v1.2.4"},
+		{[]string{"v1.2.3"}, "", Minor, "", "v1.3.0"},
+		{[]string{"v1.2.3"}, "", Major, "", "v2.0.0"},
+		{[]string{"v1.2.3"}, "", Major, "rc", "v2.0.0-rc.1"},
+		// Prereleases continue their series, and a bump without one
+		// finalizes it.
+		{[]string{"v1.2.3", "v2.0.0-rc.1", "v2.0.0-rc.2"}, "", Major, "rc", "v2.0.0-rc.3"},
+		{[]string{"v1.2.3", "v2.0.0-rc.9", "v2.0.0-rc.10"}, "", Major, "rc", "v2.0.0-rc.11"},
+		{[]string{"v1.2.3", "v2.0.0-rc.2"}, "", Major, "beta", "v2.0.0-beta.1"},
+		{[]string{"v1.2.3", "v2.0.0-rc.2"}, "", Major, "", "v2.0.0"},
+		// Only tags with the prefix count.
+		{[]string{"v5.0.0", "tools/v0.3.0"}, "tools/", Minor, "", "v0.4.0"},
+	}
+	for _, tt := range tests {
+		if got := Next(tt.tags, tt.prefix, tt.bump, tt.prerelease).String(); got != tt.want {
+			t.Errorf("Next(%q, %q, %s, %q) = %s, want %s", tt.tags, tt.prefix, tt.bump, tt.prerelease, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,14 @@ import (
 	"github.com/raducristianpopa/test-go-pkg/v4"
 )
 
+// Run is RunE, ignoring its error.
+//
+// Deprecated: Use RunE, which returns the error of the greeting.
 func Run() {
-	testgopkg.HelloWorld()
+	_ = RunE()
+}
+
+// RunE greets, returning the errors of the template and the writer.
+func RunE() error {
+	return testgopkg.Greet()
 }